@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// ApiController serves json api at /api/v1
+type ApiController struct {
+	log             ports.Logger
+	render          infra.Render
+	repos           domain.Repositories
+	artifactStorage ports.ArtifactStorage
+	limiter         *infra.Limiter
+}
+
+func NewApiController(log ports.Logger, render infra.Render, repos domain.Repositories, artifactStorage ports.ArtifactStorage, limiter *infra.Limiter) *ApiController {
+	log = log.With(slog.String("entity", "ApiController"))
+	c := &ApiController{
+		log:             log,
+		render:          render,
+		repos:           repos,
+		artifactStorage: artifactStorage,
+		limiter:         limiter,
+	}
+	return c
+}
+
+const apiDefaultPerPage = 20
+const apiMaxPerPage = 100
+
+// Repos returns list of all repos readable by request principal
+func (c *ApiController) Repos(w http.ResponseWriter, r *http.Request) {
+	repos, err := c.repos.Repo().FindAll(ports.WithRelationship(true))
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+	repos = helperReadableRepos(r, repos)
+
+	c.render.JSON(w, http.StatusOK, viewmodels.NewApiRepos(viewmodels.NewRepos(repos)))
+}
+
+// Repo returns repo details
+func (c *ApiController) Repo(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	repo, ok := c.findRepo(w, repoID, ports.WithRelationship(true))
+	if !ok {
+		return
+	}
+
+	c.render.JSON(w, http.StatusOK, viewmodels.NewApiRepo(viewmodels.NewRepo(repo)))
+}
+
+// Artifacts returns paginated list of repo artifacts, newest first.
+// The query parameters page, per_page, state and meta.KEY are supported.
+func (c *ApiController) Artifacts(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	if _, ok := c.findRepo(w, repoID); !ok {
+		return
+	}
+
+	artifacts, err := c.repos.Artifact().FindAll(ports.WithRepoID(repoID))
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+	artifacts, err = helperArtifactFilter(r, artifacts)
+	if err != nil { // 400
+		c.renderError(w, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+
+	perPage := apiDefaultPerPage
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		perPage, err = strconv.Atoi(v)
+		if err != nil || perPage < 1 || perPage > apiMaxPerPage { // 400
+			c.renderErrorMessage(w, http.StatusBadRequest, "bad_request", "per_page must be in range 1.."+strconv.Itoa(apiMaxPerPage))
+			return
+		}
+	}
+	total := len(artifacts)
+	artifacts, page := helperPagination(r, artifacts, perPage)
+
+	data := viewmodels.ApiPage[*viewmodels.ApiArtifact]{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+		Items:   viewmodels.NewApiArtifacts(viewmodels.NewArtifacts(artifacts)),
+	}
+	c.render.JSON(w, http.StatusOK, data)
+}
+
+// Artifact returns artifact details with files, meta and components
+func (c *ApiController) Artifact(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	artifactID := chi.URLParam(r, "artifactID")
+	c.findArtifactAndRender(w, repoID, artifactID)
+}
+
+// Latest returns details of the newest good artifact of the repo.
+// The meta.KEY=VALUE query parameters narrow the artifacts.
+func (c *ApiController) Latest(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	if _, ok := c.findRepo(w, repoID); !ok {
+		return
+	}
+
+	artifacts, err := c.repos.Artifact().FindAll(ports.WithRepoID(repoID))
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+	artifact, err := helperLatestArtifact(r, artifacts)
+	if err != nil { // 400
+		c.renderError(w, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	if artifact == nil { // 404
+		c.renderErrorMessage(w, http.StatusNotFound, "artifact_not_found", "no good artifact found in repo "+repoID)
+		return
+	}
+
+	// The latest artifact changes over time, so the response must not be cached
+	w.Header().Set("Cache-Control", "no-cache")
+	c.findArtifactAndRender(w, repoID, artifact.ArtifactID)
+}
+
+// DownloadFile returns artifact file content
+func (c *ApiController) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	artifactID := chi.URLParam(r, "artifactID")
+	artifact, ok := c.findArtifact(w, repoID, artifactID)
+	if !ok {
+		return
+	}
+	if artifact.State.IsBroken() { // 422
+		c.renderErrorMessage(w, http.StatusUnprocessableEntity, "artifact_broken", "artifact "+artifactID+" is broken")
+		return
+	}
+
+	filename := chi.URLParam(r, "*")
+	modelFile := helperFindFile(artifact, filename)
+	if modelFile == nil { // 404
+		c.renderErrorMessage(w, http.StatusNotFound, "file_not_found", "file "+filename+" not found")
+		return
+	}
+	if modelFile.State.IsBroken() { // 422
+		c.renderErrorMessage(w, http.StatusUnprocessableEntity, "file_broken", "file "+filename+" is broken")
+		return
+	}
+
+	w, release := helperLimitDownload(w, r, c.render, c.limiter, repoID, false)
+	if w == nil { // 429
+		return
+	}
+	defer release()
+
+	if op, err := helperServeFile(w, r, c.artifactStorage, artifact, filename); err != nil {
+		c.log.Error("file error", slog.Any("repoID", repoID), slog.Any("artifactID", artifactID), slog.Any("op", op), slog.Any("filename", filename), slog.Any("err", err))
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+	}
+}
+
+// Manifest returns checksums and sizes of artifact files (see helperManifest)
+func (c *ApiController) Manifest(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	artifactID := chi.URLParam(r, "artifactID")
+	artifact, ok := c.findArtifact(w, repoID, artifactID)
+	if !ok {
+		return
+	}
+
+	manifest, err := helperManifest(artifact, func(name string) (fs.File, error) {
+		return c.artifactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, name)
+	})
+	if err != nil { // 500
+		c.log.Error("manifest error", slog.Any("repoID", repoID), slog.Any("artifactID", artifactID), slog.Any("err", err))
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+	c.render.JSON(w, http.StatusOK, viewmodels.NewApiManifest(repoID, artifactID, manifest))
+}
+
+// NotFound is the 404 handler for unknown api routes
+func (c *ApiController) NotFound(w http.ResponseWriter, r *http.Request) {
+	c.renderErrorMessage(w, http.StatusNotFound, "not_found", "no such endpoint "+r.URL.Path)
+}
+
+func (c *ApiController) findRepo(w http.ResponseWriter, repoID models.RepoID, flags ...interface{}) (*models.Repo, bool) {
+	repo, err := c.repos.Repo().FindByID(repoID, flags...)
+	if err == ports.ErrRecordNotFound { // 404
+		c.renderErrorMessage(w, http.StatusNotFound, "repo_not_found", "repo "+repoID+" not found")
+		return nil, false
+	}
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return nil, false
+	}
+	return repo, true
+}
+
+func (c *ApiController) findArtifact(w http.ResponseWriter, repoID models.RepoID, artifactID models.ArtifactID) (*models.Artifact, bool) {
+	artifact, err := c.repos.Artifact().FindByID(repoID, artifactID, ports.WithRelationship(true))
+	if err == ports.ErrRecordNotFound { // 404
+		c.renderErrorMessage(w, http.StatusNotFound, "artifact_not_found", "artifact "+artifactID+" not found in repo "+repoID)
+		return nil, false
+	}
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return nil, false
+	}
+	return artifact, true
+}
+
+func (c *ApiController) findArtifactAndRender(w http.ResponseWriter, repoID models.RepoID, artifactID models.ArtifactID) {
+	artifact, ok := c.findArtifact(w, repoID, artifactID)
+	if !ok {
+		return
+	}
+
+	c.render.JSON(w, http.StatusOK, viewmodels.NewApiArtifact(viewmodels.NewArtifact(artifact)))
+}
+
+func (c *ApiController) renderError(w http.ResponseWriter, status int, code string, err error) {
+	c.renderErrorMessage(w, status, code, err.Error())
+}
+
+func (c *ApiController) renderErrorMessage(w http.ResponseWriter, status int, code string, message string) {
+	c.render.JSON(w, status, viewmodels.NewApiError(code, message))
+}
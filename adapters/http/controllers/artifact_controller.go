@@ -1,15 +1,20 @@
 package controllers
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
+	"io/fs"
 	"log/slog"
 	"mime"
 	"net/http"
+	"net/url"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
 	"github.com/cloudcopper/swamp/domain"
@@ -24,15 +29,28 @@ type ArtifactController struct {
 	render             infra.Render
 	artifactRepository domain.ArtifactRepository
 	aritfactStorage    ports.ArtifactStorage
+	limiter            *infra.Limiter
+	archiveCache       *infra.ArchiveCache
+	archiveBrowser     *infra.ArchiveBrowser
+	filePreviewer      *infra.FilePreviewer
 }
 
-func NewArtifactController(log ports.Logger, render infra.Render, artifactRepository domain.ArtifactRepository, aritfactStorage ports.ArtifactStorage) *ArtifactController {
+// The archiveChecksumSuffix is the url suffix of archive checksum
+const archiveChecksumSuffix = ".sha256"
+
+// NewArtifactController creates artifact controller.
+// The archiveCache is optional, archives are generated on every download if nil.
+func NewArtifactController(log ports.Logger, render infra.Render, artifactRepository domain.ArtifactRepository, aritfactStorage ports.ArtifactStorage, limiter *infra.Limiter, archiveCache *infra.ArchiveCache, archiveBrowser *infra.ArchiveBrowser, filePreviewer *infra.FilePreviewer) *ArtifactController {
 	log = log.With(slog.String("entity", "ArtifactController"))
 	s := &ArtifactController{
 		log:                log,
 		render:             render,
 		artifactRepository: artifactRepository,
 		aritfactStorage:    aritfactStorage,
+		limiter:            limiter,
+		archiveCache:       archiveCache,
+		archiveBrowser:     archiveBrowser,
+		filePreviewer:      filePreviewer,
 	}
 	return s
 }
@@ -41,13 +59,13 @@ func (c *ArtifactController) Get(w http.ResponseWriter, r *http.Request) {
 	repoID := chi.URLParam(r, "repoID")
 	artifactID := chi.URLParam(r, "artifactID")
 	// WARN Reroute - due to problem in go-chi
-	// see also warning in app.go
-	if strings.HasSuffix(artifactID, ".tar.gz") {
-		c.DownloadGzip(w, r)
-		return
-	}
-	if strings.HasSuffix(artifactID, ".zip") {
-		c.DownloadZip(w, r)
+	// see also warning in routes.go
+	if format, _ := helperArchiveFormat(strings.TrimSuffix(artifactID, archiveChecksumSuffix)); format != nil {
+		if strings.HasSuffix(artifactID, archiveChecksumSuffix) {
+			c.ArchiveChecksum(w, r)
+			return
+		}
+		c.DownloadArchive(w, r)
 		return
 	}
 
@@ -65,132 +83,190 @@ func (c *ArtifactController) Get(w http.ResponseWriter, r *http.Request) {
 	c.render.HTML(w, http.StatusOK, "artifact", data)
 }
 
-func (c *ArtifactController) DownloadZip(w http.ResponseWriter, r *http.Request) {
-	repoID := chi.URLParam(r, "repoID")
-	artifactID, _ := strings.CutSuffix(chi.URLParam(r, "artifactID"), ".zip")
-	artifact, err := c.artifactRepository.FindByID(repoID, artifactID, ports.WithRelationship(true))
-	if err == ports.ErrRecordNotFound { // 404
-		c.renderArtifactNotFound(w, repoID, artifactID, err)
+// DownloadArchive downloads artifact files as archive given by suffix (see archiveFormats).
+// The files query parameters (glob patterns) select files to download.
+// The archives of all files are cached (see infra.ArchiveCache) while downloaded,
+// so served with ETag and range support once cached.
+func (c *ArtifactController) DownloadArchive(w http.ResponseWriter, r *http.Request) {
+	artifact, format, files, ok := c.findArchive(w, r, "")
+	if !ok {
 		return
 	}
-	if err != nil { // 500
-		c.renderServerError(w, repoID, artifactID, err)
+	name := artifact.ArtifactID + format.suffix
+	key, cached := helperArchiveKey(artifact, format), c.archiveCache != nil && !r.URL.Query().Has("files")
+
+	w, release := helperLimitDownload(w, r, c.render, c.limiter, artifact.RepoID, !cached || !c.archiveCache.Has(key))
+	if w == nil { // 429
 		return
 	}
-	if artifact.State.IsBroken() { // 422
-		c.render.HTML(w, http.StatusUnprocessableEntity, "artifact-broken", artifact)
+	defer release()
+
+	// Set headers for archive file
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", helperAttachment(name))
+
+	generate := c.generateArchive(artifact, format, files)
+	if !cached {
+		// Write archive directly to the response writer
+		if err := generate(w); err != nil {
+			c.renderArchiveError(w, artifact, err)
+		}
 		return
 	}
-	files := artifact.Files
-
-	// Set headers for zip file
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", "attachment; filename="+artifact.ArtifactID+".zip")
-
-	// Create zip writer directly on the response writer
-	zipWriter := zip.NewWriter(w)
-	defer zipWriter.Close()
-
-	// Loop over the files and add each one to the zip
-	for _, modelFile := range files {
-		if !func() (cont bool) {
-			fileName := modelFile.Name
-			filePath := filepath.Join(artifact.Storage, fileName)
-			file, err := c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, fileName)
-			if err != nil {
-				c.renderFileError(w, artifact, "open file", filePath, err)
-				return
-			}
-			defer file.Close()
-
-			// Create a file entry in the zip archive
-			fileWriter, err := zipWriter.Create(fileName)
-			if err != nil {
-				c.renderFileError(w, artifact, "create file in zip", filePath, err)
-				return
-			}
-			// Copy the file content into the zip archive
-			if _, err := io.Copy(fileWriter, file); err != nil {
-				c.renderFileError(w, artifact, "write file to zip", filePath, err)
-				return
-			}
-
-			return true
-		}() {
+
+	file, sum, ok := c.archiveCache.Get(key)
+	if !ok {
+		// Write archive to the response writer, while caching it
+		if err := c.archiveCache.Write(key, w, generate); err != nil {
+			c.renderArchiveError(w, artifact, err)
+		}
+		return
+	}
+	defer file.Close()
+	// The ServeContent handles Range, If-Range, If-None-Match, If-Modified-Since and HEAD
+	w.Header().Set("ETag", `"`+sum+`"`)
+	http.ServeContent(w, r, name, time.Unix(artifact.CreatedAt, 0), file)
+}
+
+// ArchiveChecksum returns sha256 of archive (see DownloadArchive) in sha256sum format
+func (c *ArtifactController) ArchiveChecksum(w http.ResponseWriter, r *http.Request) {
+	artifact, format, files, ok := c.findArchive(w, r, archiveChecksumSuffix)
+	if !ok {
+		return
+	}
+	name := artifact.ArtifactID + format.suffix
+	key, cached := helperArchiveKey(artifact, format), c.archiveCache != nil && !r.URL.Query().Has("files")
+
+	w, release := helperLimitDownload(w, r, c.render, c.limiter, artifact.RepoID, !cached || !c.archiveCache.Has(key))
+	if w == nil { // 429
+		return
+	}
+	defer release()
+
+	generate := c.generateArchive(artifact, format, files)
+	sum := ""
+	if cached {
+		file, s, err := c.archiveCache.Open(key, generate)
+		if err != nil {
+			c.renderArchiveError(w, artifact, err)
+			return
+		}
+		file.Close()
+		sum = s
+	} else {
+		hash := sha256.New()
+		if err := generate(hash); err != nil {
+			c.renderArchiveError(w, artifact, err)
 			return
 		}
+		sum = hex.EncodeToString(hash.Sum(nil))
 	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(sum + "  " + name + "\n"))
 }
 
-func (c *ArtifactController) DownloadGzip(w http.ResponseWriter, r *http.Request) {
+// Manifest returns checksums of artifact files in sha256sum format (see helperManifest)
+func (c *ArtifactController) Manifest(w http.ResponseWriter, r *http.Request) {
+	artifact, manifest, ok := c.findManifest(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := helperWriteManifest(w, manifest); err != nil {
+		c.log.Warn("unable write manifest", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("err", err))
+	}
+}
+
+// VerifyScript returns POSIX shell script verifying downloaded artifact files
+func (c *ArtifactController) VerifyScript(w http.ResponseWriter, r *http.Request) {
+	artifact, manifest, ok := c.findManifest(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-shellscript; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=verify.sh")
+	if err := helperWriteVerifyScript(w, artifact, manifest); err != nil {
+		c.log.Warn("unable write verify script", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("err", err))
+	}
+}
+
+// The findManifest returns artifact and its manifest.
+// It renders error and returns false, if there is no such artifact.
+// The manifest of broken artifact is returned too, as it helps to find broken files.
+func (c *ArtifactController) findManifest(w http.ResponseWriter, r *http.Request) (*models.Artifact, models.ManifestFiles, bool) {
 	repoID := chi.URLParam(r, "repoID")
-	artifactID, _ := strings.CutSuffix(chi.URLParam(r, "artifactID"), ".tar.gz")
+	artifactID := chi.URLParam(r, "artifactID")
 	artifact, err := c.artifactRepository.FindByID(repoID, artifactID, ports.WithRelationship(true))
 	if err == ports.ErrRecordNotFound { // 404
 		c.renderArtifactNotFound(w, repoID, artifactID, err)
-		return
+		return nil, nil, false
 	}
 	if err != nil { // 500
 		c.renderServerError(w, repoID, artifactID, err)
-		return
+		return nil, nil, false
+	}
+	manifest, err := helperManifest(artifact, c.openFile(artifact))
+	if err != nil { // 500
+		c.renderFileError(w, artifact, "read manifest", artifact.Storage, err)
+		return nil, nil, false
+	}
+	return artifact, manifest, true
+}
+
+// The findArchive returns artifact, archive format and files selected by request.
+// The suffix is the url suffix after archive format (e.g. .sha256).
+// It renders error and returns false, if there is no such archive.
+func (c *ArtifactController) findArchive(w http.ResponseWriter, r *http.Request, suffix string) (*models.Artifact, *archiveFormat, models.ArtifactFiles, bool) {
+	repoID := chi.URLParam(r, "repoID")
+	// The artifactID param has no suffix, if routed by suffix route,
+	// so the format is detected by path
+	format, _ := helperArchiveFormat(strings.TrimSuffix(r.URL.Path, suffix))
+	artifactID, _ := strings.CutSuffix(chi.URLParam(r, "artifactID"), suffix)
+	if format == nil { // 404
+		c.renderArtifactNotFound(w, repoID, artifactID, ports.ErrRecordNotFound)
+		return nil, nil, nil, false
+	}
+	artifactID, _ = strings.CutSuffix(artifactID, format.suffix)
+	artifact, err := c.artifactRepository.FindByID(repoID, artifactID, ports.WithRelationship(true))
+	if err == ports.ErrRecordNotFound { // 404
+		c.renderArtifactNotFound(w, repoID, artifactID, err)
+		return nil, nil, nil, false
+	}
+	if err != nil { // 500
+		c.renderServerError(w, repoID, artifactID, err)
+		return nil, nil, nil, false
 	}
 	if artifact.State.IsBroken() { // 422
 		c.render.HTML(w, http.StatusUnprocessableEntity, "artifact-broken", artifact)
-		return
+		return nil, nil, nil, false
 	}
-	files := artifact.Files
-
-	// Set headers for tar.gz file
-	w.Header().Set("Content-Type", "application/gzip")
-	w.Header().Set("Content-Disposition", "attachment; filename="+artifact.ArtifactID+".tar.gz")
-
-	// Create gzip writer directly on the response writer
-	gzipWriter := gzip.NewWriter(w)
-	defer gzipWriter.Close()
-	// Create tar writer inside the gzip writer
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-
-	// Loop over the files and add each one to the tar archive
-	for _, modelFile := range files {
-		if !func() (cont bool) {
-			fileName := modelFile.Name
-			filePath := filepath.Join(artifact.Storage, fileName)
-			file, err := c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, fileName)
-			if err != nil {
-				c.renderFileError(w, artifact, "open file", filePath, err)
-				return
-			}
-			defer file.Close()
-
-			// Get file information
-			fileInfo, err := file.Stat()
-			if err != nil {
-				c.renderFileError(w, artifact, "stat file", filePath, err)
-				return
-			}
-
-			// Create tar header based on the file info
-			header := &tar.Header{
-				Name: fileName, // TODO Check with nested subdirectories
-				Size: fileInfo.Size(),
-				Mode: int64(fileInfo.Mode()),
-			}
-			if err := tarWriter.WriteHeader(header); err != nil {
-				c.renderFileError(w, artifact, "write tar header", filePath, err)
-				return
-			}
-
-			// Copy the file content into the tar archive
-			if _, err := io.Copy(tarWriter, file); err != nil {
-				c.renderFileError(w, artifact, "write file to tar", filePath, err)
-				return
-			}
-
-			return true
-		}() {
-			return
+	patterns := r.URL.Query()["files"]
+	files := helperSelectFiles(artifact.Files, patterns)
+	if len(files) == 0 { // 404
+		c.renderFileNotFound(w, artifact, strings.Join(patterns, ","))
+		return nil, nil, nil, false
+	}
+	return artifact, format, files, true
+}
+
+// The generateArchive returns function writing reproducible archive of the artifact files and their manifest.
+// The archive entries have artifact creation time.
+func (c *ArtifactController) generateArchive(artifact *models.Artifact, format *archiveFormat, files models.ArtifactFiles) func(w io.Writer) error {
+	open := c.openFile(artifact)
+	return func(w io.Writer) error {
+		manifest, err := helperManifest(artifact, open)
+		if err != nil {
+			return &archiveError{"read manifest", "", err}
 		}
+		return helperWriteArchive(w, format, files, helperSelectManifest(manifest, files), time.Unix(artifact.CreatedAt, 0), open)
+	}
+}
+
+// The openFile returns function opening files of the artifact
+func (c *ArtifactController) openFile(artifact *models.Artifact) func(name string) (fs.File, error) {
+	return func(name string) (fs.File, error) {
+		return c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, name)
 	}
 }
 
@@ -212,50 +288,166 @@ func (c *ArtifactController) DownloadSingleFile(w http.ResponseWriter, r *http.R
 	}
 
 	filename := chi.URLParam(r, "*")
-	files := artifact.Files
-	for _, modelFile := range files {
-		if modelFile.Name != filename {
-			continue
-		}
+	modelFile := helperFindFile(artifact, filename)
+	if modelFile == nil {
+		c.renderFileNotFound(w, artifact, filename)
+		return
+	}
+	if modelFile.State.IsBroken() {
+		c.render.HTML(w, http.StatusUnprocessableEntity, "file-broken", modelFile)
+		return
+	}
 
-		if modelFile.State.IsBroken() {
-			c.render.HTML(w, http.StatusUnprocessableEntity, "file-broken", modelFile)
+	// Preview file or browse inside archive file
+	if query := r.URL.Query(); query.Has("preview") {
+		// The preview reads the file same way as download
+		w, release := helperLimitDownload(w, r, c.render, c.limiter, repoID, false)
+		if w == nil { // 429
+			return
+		}
+		defer release()
+		c.preview(w, artifact, modelFile)
+		return
+	} else if query.Has("entries") {
+		// The listing scans archive same way as extracting entry
+		w, release := helperLimitDownload(w, r, c.render, c.limiter, repoID, true)
+		if w == nil { // 429
 			return
 		}
+		defer release()
+		c.archiveEntries(w, artifact, modelFile)
+		return
+	} else if query.Has("entry") {
+		c.downloadArchiveEntry(w, r, artifact, modelFile, query.Get("entry"))
+		return
+	}
+
+	w, release := helperLimitDownload(w, r, c.render, c.limiter, repoID, false)
+	if w == nil { // 429
+		return
+	}
+	defer release()
 
-		func() {
-			// Open file
-			filePath := filepath.Join(artifact.Storage, filename)
-			file, err := c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, filename)
-			if err != nil {
-				c.renderFileError(w, artifact, "open file", filePath, err)
-				return
-			}
-			defer file.Close()
-
-			// Detect proper mime
-			ext := filepath.Ext(filename)
-			mimeType := mime.TypeByExtension(ext)
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
-			}
-
-			// Set headers for tar.gz file
-			w.Header().Set("Content-Type", mimeType)
-			w.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(filename))
-
-			// Use io.Copy to stream the file to the response
-			_, err = io.Copy(w, file)
-			if err != nil {
-				c.log.Error("write file failed", slog.Any("repoID", repoID), slog.Any("artifactID", artifactID), slog.Any("filename", filename), slog.Any("err", err))
-				c.renderFileError(w, artifact, "open file", filePath, err)
-			}
-		}()
+	if op, err := helperServeFile(w, r, c.aritfactStorage, artifact, filename); err != nil {
+		c.renderFileError(w, artifact, op, filepath.Join(artifact.Storage, filename), err)
+	}
+}
 
+// The preview shows the file in browser (see infra.FilePreviewer)
+func (c *ArtifactController) preview(w http.ResponseWriter, artifact *models.Artifact, modelFile *models.ArtifactFile) {
+	file, err := c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, modelFile.Name)
+	if err != nil { // 500
+		c.renderFileError(w, artifact, "open file", filepath.Join(artifact.Storage, modelFile.Name), err)
 		return
 	}
+	defer file.Close()
 
-	c.renderFileNotFound(w, artifact, filename)
+	preview, err := c.filePreviewer.Preview(file, modelFile.Name, modelFile.Size, modelFile.MimeType)
+	if err != nil { // 500
+		c.renderFileError(w, artifact, "read file", filepath.Join(artifact.Storage, modelFile.Name), err)
+		return
+	}
+	type Data struct {
+		Artifact *models.Artifact
+		File     *models.ArtifactFile
+		Preview  *infra.FilePreview
+	}
+	c.render.HTML(w, http.StatusOK, "preview", Data{artifact, modelFile, preview})
+}
+
+// The archiveEntries lists entries of archive file (see infra.ArchiveBrowser)
+func (c *ArtifactController) archiveEntries(w http.ResponseWriter, artifact *models.Artifact, modelFile *models.ArtifactFile) {
+	file, err := c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, modelFile.Name)
+	if err != nil { // 500
+		c.renderFileError(w, artifact, "open file", filepath.Join(artifact.Storage, modelFile.Name), err)
+		return
+	}
+	defer file.Close()
+
+	entries, truncated, err := c.archiveBrowser.List(file, modelFile.Name)
+	if err != nil {
+		c.renderArchiveEntryError(w, artifact, modelFile.Name, "", err)
+		return
+	}
+	type Data struct {
+		Artifact  *models.Artifact
+		File      *models.ArtifactFile
+		Entries   []*ports.ArchiveEntry
+		Truncated bool
+	}
+	c.render.HTML(w, http.StatusOK, "archive", Data{artifact, modelFile, entries, truncated})
+}
+
+// The downloadArchiveEntry downloads single entry of archive file streamed out of archive (see infra.ArchiveBrowser)
+func (c *ArtifactController) downloadArchiveEntry(w http.ResponseWriter, r *http.Request, artifact *models.Artifact, modelFile *models.ArtifactFile, entryName string) {
+	w, release := helperLimitDownload(w, r, c.render, c.limiter, artifact.RepoID, true)
+	if w == nil { // 429
+		return
+	}
+	defer release()
+
+	file, err := c.aritfactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, modelFile.Name)
+	if err != nil { // 500
+		c.renderFileError(w, artifact, "open file", filepath.Join(artifact.Storage, modelFile.Name), err)
+		return
+	}
+	defer file.Close()
+
+	written := false
+	err = c.archiveBrowser.Extract(file, modelFile.Name, entryName, func(entry *ports.ArchiveEntry, content io.Reader) error {
+		mimeType := mime.TypeByExtension(path.Ext(entry.Name))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Content-Disposition", helperAttachment(path.Base(entry.Name)))
+		w.Header().Set("Content-Length", strconv.FormatInt(int64(entry.Size), 10))
+		written = true
+		_, err := io.Copy(w, content)
+		return err
+	})
+	if err != nil && written {
+		// The response is started already
+		c.log.Warn("archive entry error", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("filename", modelFile.Name), slog.Any("entry", entryName), slog.Any("err", err))
+		return
+	}
+	if err != nil {
+		c.renderArchiveEntryError(w, artifact, modelFile.Name, entryName, err)
+	}
+}
+
+// Latest redirects to the newest good artifact of the repo.
+// The meta.KEY=VALUE query parameters narrow the artifacts.
+func (c *ArtifactController) Latest(w http.ResponseWriter, r *http.Request) {
+	c.redirectLatest(w, r, "")
+}
+
+// LatestFile redirects to the file of the newest good artifact of the repo.
+// The meta.KEY=VALUE query parameters narrow the artifacts.
+func (c *ArtifactController) LatestFile(w http.ResponseWriter, r *http.Request) {
+	c.redirectLatest(w, r, "/file/"+chi.URLParam(r, "*"))
+}
+
+func (c *ArtifactController) redirectLatest(w http.ResponseWriter, r *http.Request, suffix string) {
+	repoID := chi.URLParam(r, "repoID")
+	artifacts, err := c.artifactRepository.FindAll(ports.WithRepoID(repoID))
+	if err != nil { // 500
+		c.renderServerError(w, repoID, "latest", err)
+		return
+	}
+	artifact, err := helperLatestArtifact(r, artifacts)
+	if err == nil && artifact == nil {
+		err = ports.ErrRecordNotFound
+	}
+	if err != nil { // 404
+		c.renderArtifactNotFound(w, repoID, "latest", err)
+		return
+	}
+
+	// The latest artifact changes over time, so the redirect must not be cached
+	u := url.URL{Path: "/repo/" + repoID + "/artifact/" + artifact.ArtifactID + suffix}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.Redirect(w, r, u.String(), http.StatusFound)
 }
 
 func (c *ArtifactController) renderFileNotFound(w http.ResponseWriter, artifact *models.Artifact, filename string) {
@@ -284,6 +476,38 @@ func (c *ArtifactController) renderServerError(w http.ResponseWriter, repoID mod
 	c.render.HTML(w, http.StatusInternalServerError, "errors/artifact-server-error", Data{repoID, artifactID, err})
 }
 
+func (c *ArtifactController) renderArchiveError(w http.ResponseWriter, artifact *models.Artifact, err error) {
+	var archiveErr *archiveError
+	if errors.As(err, &archiveErr) {
+		c.renderFileError(w, artifact, archiveErr.op, filepath.Join(artifact.Storage, archiveErr.filename), archiveErr.err)
+		return
+	}
+	c.renderFileError(w, artifact, "cache archive", artifact.ArtifactID, err)
+}
+
+func (c *ArtifactController) renderArchiveEntryError(w http.ResponseWriter, artifact *models.Artifact, filename string, entry string, err error) {
+	status, title := http.StatusInternalServerError, "Archive error"
+	switch {
+	case errors.Is(err, ports.ErrWrongArchiveFormat): // 415
+		status, title = http.StatusUnsupportedMediaType, "Unsupported archive"
+	case errors.Is(err, ports.ErrArchiveEntryNotFound): // 404
+		status, title = http.StatusNotFound, "Entry not found"
+	case errors.Is(err, ports.ErrArchiveEntryTooLarge): // 422
+		status, title = http.StatusUnprocessableEntity, "Entry too large"
+	default:
+		c.log.Error("archive error", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("filename", filename), slog.Any("entry", entry), slog.Any("err", err))
+	}
+	type Data struct {
+		Artifact *models.Artifact
+		Filename string
+		Entry    string
+		Status   int
+		Title    string
+		Error    error
+	}
+	c.render.HTML(w, status, "errors/archive-entry-error", Data{artifact, filename, entry, status, title, err})
+}
+
 func (c *ArtifactController) renderFileError(w http.ResponseWriter, artifact *models.Artifact, op, filename string, err error) {
 	c.log.Error("file error", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("op", op), slog.Any("filename", filename), slog.Any("err", err))
 	type Data struct {
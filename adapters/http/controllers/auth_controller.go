@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The principal name of admin token
+const adminTokenPrincipal = "admin-token"
+
+// The api token last used time is updated not often than this
+const apiTokenTouchInterval = 60 // seconds
+
+// The verified basic auth passwords are cached for a while,
+// as bcrypt is slow by design and browsers send credentials with each request
+const authCacheTTL = 5 * time.Minute
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+type authContextKey struct{}
+
+type authContext struct {
+	principal *models.Principal
+	enabled   bool
+}
+
+func contextWithAuth(ctx context.Context, principal *models.Principal, enabled bool) context.Context {
+	return context.WithValue(ctx, authContextKey{}, &authContext{principal, enabled})
+}
+
+type repoContextKey struct{}
+
+func contextWithRepo(ctx context.Context, repo *models.Repo) context.Context {
+	return context.WithValue(ctx, repoContextKey{}, repo)
+}
+
+// AuthController authenticates requests by basic auth (users) or bearer token (tokens, api tokens and admin token)
+// and guards routes by repo permissions.
+// The authentication is enabled by auth config, while admin token alone enables management only.
+type AuthController struct {
+	log            ports.Logger
+	render         infra.Render
+	repoRepository domain.RepoRepository
+	tokens         domain.ApiTokenRepository
+	auth           *models.Auth
+	adminToken     string
+	mutex          sync.Mutex
+	cache          map[[sha256.Size]byte]time.Time
+}
+
+func NewAuthController(log ports.Logger, render infra.Render, repoRepository domain.RepoRepository, tokens domain.ApiTokenRepository, auth *models.Auth, adminToken string) *AuthController {
+	log = log.With(slog.String("entity", "AuthController"))
+	c := &AuthController{
+		log:            log,
+		render:         render,
+		repoRepository: repoRepository,
+		tokens:         tokens,
+		auth:           auth,
+		adminToken:     adminToken,
+		cache:          map[[sha256.Size]byte]time.Time{},
+	}
+	return c
+}
+
+// Authenticate is the middleware adding request principal to the request context.
+// The requests without credentials are anonymous.
+// The requests with invalid credentials are rejected, except static files.
+func (c *AuthController) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, err := c.authenticate(r)
+		if err != nil { // 401
+			c.log.Warn("authentication failed", slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("remote", r.RemoteAddr), slog.Any("err", err))
+			c.renderUnauthorized(w, r, err.Error())
+			return
+		}
+		ctx := contextWithAuth(r.Context(), principal, c.auth != nil)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireRepo returns the middleware allowing requests with given permission to the {repoID} repo.
+// The unknown repo is passed to the handler to render not found.
+// The repo not readable by authenticated principal is reported not found as well.
+// The allowed repo is added to the request context (see helperRepo).
+func (c *AuthController) RequireRepo(perm vo.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repoID := chi.URLParam(r, "repoID")
+			repo, err := c.repoRepository.FindByID(repoID)
+			if errors.Is(err, ports.ErrRecordNotFound) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil { // 500, as permission can not be checked
+				c.renderRepoServerError(w, r, repoID, err)
+				return
+			}
+			principal := helperPrincipal(r)
+			switch {
+			case principal.Can(repo, perm):
+				next.ServeHTTP(w, r.WithContext(contextWithRepo(r.Context(), repo)))
+			case principal.IsAnonymous(): // 401
+				c.renderUnauthorized(w, r, "")
+			case !principal.Can(repo, vo.PermissionRead): // 404
+				c.renderRepoNotFound(w, r, repoID)
+			default: // 403
+				c.renderForbidden(w, r, principal, perm.String()+" permission to repo "+repoID+" required")
+			}
+		})
+	}
+}
+
+// RequireAdmin is the middleware allowing requests of admins only
+func (c *AuthController) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := helperPrincipal(r)
+		switch {
+		case principal.Admin:
+			next.ServeHTTP(w, r)
+		case principal.IsAnonymous(): // 401
+			c.renderUnauthorized(w, r, "")
+		default: // 403
+			c.renderForbidden(w, r, principal, "admin required")
+		}
+	})
+}
+
+// RequireAdminIfEnabled is the middleware allowing requests of admins only, if authentication enabled.
+// It guards the pages revealing details of all repos (e.g. metrics).
+func (c *AuthController) RequireAdminIfEnabled(next http.Handler) http.Handler {
+	admin := c.RequireAdmin(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !helperAuthEnabled(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		admin.ServeHTTP(w, r)
+	})
+}
+
+// Login asks browser for credentials and redirects to front page, once authenticated
+func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
+	if helperPrincipal(r).IsAnonymous() { // 401
+		c.renderUnauthorized(w, r, "")
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// The authenticate returns principal of the request credentials
+func (c *AuthController) authenticate(r *http.Request) (*models.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return models.Anonymous, nil
+	}
+
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		if c.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.adminToken)) == 1 {
+			return &models.Principal{Name: adminTokenPrincipal, Admin: true}, nil
+		}
+		if c.auth != nil {
+			for name, t := range c.auth.Tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+					return &models.Principal{Name: name, Admin: t.Admin}, nil
+				}
+			}
+		}
+		if strings.HasPrefix(token, models.ApiTokenPrefix) && c.tokens != nil {
+			return c.authenticateApiToken(token)
+		}
+		return nil, errInvalidCredentials
+	}
+
+	if name, password, ok := r.BasicAuth(); ok {
+		// The admin token is accepted as password of any user for browsers visiting admin pages
+		if c.adminToken != "" && subtle.ConstantTimeCompare([]byte(password), []byte(c.adminToken)) == 1 {
+			return &models.Principal{Name: adminTokenPrincipal, Admin: true}, nil
+		}
+		if c.auth != nil {
+			if user, ok := c.auth.Users[name]; ok && c.verifyPassword(name, user.Password, password) {
+				return &models.Principal{Name: name, Admin: user.Admin}, nil
+			}
+		}
+		return nil, errInvalidCredentials
+	}
+
+	return nil, errors.New("unsupported authorization scheme")
+}
+
+// The authenticateApiToken returns principal of valid api token.
+// It also updates token last used time.
+func (c *AuthController) authenticateApiToken(secret string) (*models.Principal, error) {
+	token, err := c.tokens.FindByHash(models.HashApiToken(secret))
+	if err != nil {
+		if !errors.Is(err, ports.ErrRecordNotFound) {
+			c.log.Error("unable to find api token", slog.Any("err", err))
+		}
+		return nil, errInvalidCredentials
+	}
+	now := time.Now().UTC().Unix()
+	if !token.IsValid(now) {
+		return nil, errors.New("api token is revoked or expired")
+	}
+	if now-token.LastUsedAt >= apiTokenTouchInterval {
+		if err := c.tokens.Touch(token.TokenID, now); err != nil {
+			c.log.Warn("unable to update api token last used time", slog.String("tokenID", token.TokenID), slog.Any("err", err))
+		}
+	}
+	return &models.Principal{Name: token.Name, Token: token}, nil
+}
+
+// The verifyPassword compares password with bcrypt hash.
+// The successful result is cached for authCacheTTL.
+func (c *AuthController) verifyPassword(name, hash, password string) bool {
+	key := sha256.Sum256([]byte(name + "\x00" + hash + "\x00" + password))
+	now := time.Now()
+
+	c.mutex.Lock()
+	expiredAt, ok := c.cache[key]
+	c.mutex.Unlock()
+	if ok && now.Before(expiredAt) {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for k, v := range c.cache {
+		if now.After(v) {
+			delete(c.cache, k)
+		}
+	}
+	c.cache[key] = now.Add(authCacheTTL)
+	return true
+}
+
+func (c *AuthController) renderUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="swamp", charset="UTF-8"`)
+	w.Header().Add("WWW-Authenticate", `Bearer realm="swamp"`)
+	if message == "" {
+		message = "authentication required"
+	}
+	if helperIsApiRequest(r) {
+		c.render.JSON(w, http.StatusUnauthorized, viewmodels.NewApiError("unauthorized", message))
+		return
+	}
+	type Data struct {
+		Error string
+	}
+	c.render.HTML(w, http.StatusUnauthorized, "errors/401", Data{message})
+}
+
+func (c *AuthController) renderForbidden(w http.ResponseWriter, r *http.Request, principal *models.Principal, message string) {
+	c.log.Warn("forbidden request", slog.String("principal", principal.Name), slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("err", message))
+	if helperIsApiRequest(r) {
+		c.render.JSON(w, http.StatusForbidden, viewmodels.NewApiError("forbidden", message))
+		return
+	}
+	type Data struct {
+		Name  string
+		Error string
+	}
+	c.render.HTML(w, http.StatusForbidden, "errors/403", Data{principal.Name, message})
+}
+
+func (c *AuthController) renderRepoServerError(w http.ResponseWriter, r *http.Request, repoID models.RepoID, err error) {
+	c.log.Error("unable to find repo", slog.Any("repoID", repoID), slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.Any("err", err))
+	if helperIsApiRequest(r) {
+		c.render.JSON(w, http.StatusInternalServerError, viewmodels.NewApiError("server_error", "unable to find repo "+repoID))
+		return
+	}
+	type Data struct {
+		RepoID models.RepoID
+		Error  error
+	}
+	c.render.HTML(w, http.StatusInternalServerError, "errors/repo-server-error", Data{repoID, nil})
+}
+
+func (c *AuthController) renderRepoNotFound(w http.ResponseWriter, r *http.Request, repoID models.RepoID) {
+	if helperIsApiRequest(r) {
+		c.render.JSON(w, http.StatusNotFound, viewmodels.NewApiError("repo_not_found", "repo "+repoID+" not found"))
+		return
+	}
+	type Data struct {
+		RepoID models.RepoID
+		Error  error
+	}
+	c.render.HTML(w, http.StatusNotFound, "errors/repo-not-found", Data{repoID, ports.ErrRecordNotFound})
+}
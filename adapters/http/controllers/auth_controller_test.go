@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testRepos is the repo repository of given repos.
+// The lookup of broken repo fails.
+type testRepos struct {
+	domain.RepoRepository
+	repos  []*models.Repo
+	broken models.RepoID
+}
+
+func (t *testRepos) FindByID(id models.RepoID, flags ...interface{}) (*models.Repo, error) {
+	if id != "" && id == t.broken {
+		return nil, errors.New("database is locked")
+	}
+	for _, repo := range t.repos {
+		if repo.RepoID == id {
+			return repo, nil
+		}
+	}
+	return nil, ports.ErrRecordNotFound
+}
+
+// testTokens is the api token repository of given tokens
+type testTokens struct {
+	domain.ApiTokenRepository
+	tokens []*models.ApiToken
+}
+
+func (t *testTokens) FindByHash(hash string) (*models.ApiToken, error) {
+	for _, token := range t.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+	return nil, ports.ErrRecordNotFound
+}
+
+func (t *testTokens) Touch(id models.ApiTokenID, now int64) error {
+	for _, token := range t.tokens {
+		if token.TokenID == id {
+			token.LastUsedAt = now
+		}
+	}
+	return nil
+}
+
+func TestAuthController(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	auth := &models.Auth{
+		Users: map[string]*models.AuthUser{
+			"alice": {Password: string(hash)},
+		},
+		Tokens: map[string]*models.AuthToken{
+			"ci": {Token: "ci-token"},
+		},
+	}
+	repos := &testRepos{repos: []*models.Repo{
+		{RepoID: "public", Access: models.Access{models.PrincipalAnonymous: vo.PermissionRead}},
+		{RepoID: "internal", Access: models.Access{models.PrincipalAuthenticated: vo.PermissionRead, "ci": vo.PermissionWrite}},
+		{RepoID: "secret", Access: models.Access{"alice": vo.PermissionAdmin}},
+	}, broken: "broken"}
+	now := time.Now().Unix()
+	tokens := &testTokens{}
+	newToken := func(name string, repoID models.RepoID, scope vo.Permission, expiresAt int64) string {
+		token, secret, err := models.NewApiToken(name, repoID, scope, now-60, expiresAt)
+		require.NoError(t, err)
+		tokens.tokens = append(tokens.tokens, token)
+		return secret
+	}
+	uploader := newToken("uploader", "secret", vo.PermissionWrite, 0)
+	reader := newToken("reader", "", vo.PermissionRead, now+60)
+	expired := newToken("expired", "", vo.PermissionRead, now-30)
+	revoked := newToken("revoked", "", vo.PermissionRead, 0)
+	tokens.tokens[3].RevokedAt = now
+	c := NewAuthController(slog.Default(), infra.NewRender(fstest.MapFS{}, ""), repos, tokens, auth, "admin-token")
+
+	router := chi.NewRouter()
+	router.Use(c.Authenticate)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(helperPrincipal(r).Name)) }
+	router.With(c.RequireRepo(vo.PermissionRead)).Get("/api/v1/repos/{repoID}", ok)
+	router.With(c.RequireRepo(vo.PermissionWrite)).Post("/api/v1/repos/{repoID}", ok)
+	router.With(c.RequireAdmin).Get("/api/admin", ok)
+	router.With(c.RequireAdminIfEnabled).Get("/api/metrics", ok)
+	router.Get("/static/*", ok)
+
+	testCases := []struct {
+		desc      string
+		method    string
+		path      string
+		basic     []string
+		bearer    string
+		status    int
+		principal string
+	}{
+		{"anonymous public", "GET", "/api/v1/repos/public", nil, "", 200, "anonymous"},
+		{"anonymous internal", "GET", "/api/v1/repos/internal", nil, "", 401, ""},
+		{"user internal", "GET", "/api/v1/repos/internal", []string{"alice", "alice-password"}, "", 200, "alice"},
+		{"user secret", "GET", "/api/v1/repos/secret", []string{"alice", "alice-password"}, "", 200, "alice"},
+		{"user wrong password", "GET", "/api/v1/repos/public", []string{"alice", "wrong"}, "", 401, ""},
+		{"unknown user", "GET", "/api/v1/repos/public", []string{"bob", "alice-password"}, "", 401, ""},
+		{"token secret hidden", "GET", "/api/v1/repos/secret", nil, "ci-token", 404, ""},
+		{"token write", "POST", "/api/v1/repos/internal", nil, "ci-token", 200, "ci"},
+		{"user no write", "POST", "/api/v1/repos/internal", []string{"alice", "alice-password"}, "", 403, ""},
+		{"wrong token", "GET", "/api/v1/repos/public", nil, "wrong", 401, ""},
+		{"unknown repo", "GET", "/api/v1/repos/unknown", nil, "", 200, "anonymous"},
+		{"repo lookup failed", "GET", "/api/v1/repos/broken", nil, "", 500, ""},
+		{"admin repo lookup failed", "GET", "/api/v1/repos/broken", nil, "admin-token", 500, ""},
+		{"admin token", "POST", "/api/v1/repos/secret", nil, "admin-token", 200, adminTokenPrincipal},
+		{"admin token as password", "GET", "/api/admin", []string{"", "admin-token"}, "", 200, adminTokenPrincipal},
+		{"not admin", "GET", "/api/admin", nil, "ci-token", 403, ""},
+		{"anonymous admin", "GET", "/api/admin", nil, "", 401, ""},
+		{"api token", "POST", "/api/v1/repos/secret", nil, uploader, 200, "uploader"},
+		{"api token other repo", "GET", "/api/v1/repos/internal", nil, uploader, 404, ""},
+		{"api token all repos", "GET", "/api/v1/repos/secret", nil, reader, 200, "reader"},
+		{"api token read only", "POST", "/api/v1/repos/internal", nil, reader, 403, ""},
+		{"api token not admin", "GET", "/api/admin", nil, uploader, 403, ""},
+		{"api token expired", "GET", "/api/v1/repos/public", nil, expired, 401, ""},
+		{"api token revoked", "GET", "/api/v1/repos/public", nil, revoked, 401, ""},
+		{"api token unknown", "GET", "/api/v1/repos/public", nil, models.ApiTokenPrefix + "unknown", 401, ""},
+		{"anonymous metrics", "GET", "/api/metrics", nil, "", 401, ""},
+		{"not admin metrics", "GET", "/api/metrics", []string{"alice", "alice-password"}, "", 403, ""},
+		{"admin metrics", "GET", "/api/metrics", nil, "admin-token", 200, adminTokenPrincipal},
+		{"static with wrong token", "GET", "/static/x.css", nil, "wrong", 200, "anonymous"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert := require.New(t)
+			r := httptest.NewRequest(tC.method, tC.path, nil)
+			if tC.basic != nil {
+				r.SetBasicAuth(tC.basic[0], tC.basic[1])
+			}
+			if tC.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tC.bearer)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			assert.Equal(tC.status, w.Code, w.Body.String())
+			if tC.status == http.StatusOK {
+				assert.Equal(tC.principal, w.Body.String())
+			}
+			if tC.status == http.StatusUnauthorized {
+				assert.NotEmpty(w.Header().Values("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestHelperReadableRepos(t *testing.T) {
+	assert := require.New(t)
+	repos := []*models.Repo{
+		{RepoID: "public"}, // no access - public
+		{RepoID: "internal", Access: models.Access{models.PrincipalAuthenticated: vo.PermissionRead}},
+		{RepoID: "secret", Access: models.Access{"alice": vo.PermissionRead}},
+	}
+	ids := func(repos []*models.Repo) []models.RepoID {
+		ids := []models.RepoID{}
+		for _, repo := range repos {
+			ids = append(ids, repo.RepoID)
+		}
+		return ids
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Equal([]models.RepoID{"public"}, ids(helperReadableRepos(r, repos)))
+
+	withPrincipal := func(p *models.Principal) *http.Request {
+		return r.WithContext(contextWithAuth(r.Context(), p, true))
+	}
+	assert.Equal([]models.RepoID{"public", "internal"}, ids(helperReadableRepos(withPrincipal(&models.Principal{Name: "bob"}), repos)))
+	assert.Equal([]models.RepoID{"public", "internal", "secret"}, ids(helperReadableRepos(withPrincipal(&models.Principal{Name: "alice"}), repos)))
+	assert.Equal([]models.RepoID{"public", "internal", "secret"}, ids(helperReadableRepos(withPrincipal(&models.Principal{Name: "root", Admin: true}), repos)))
+}
+
+func TestAuthControllerApiTokenLastUsed(t *testing.T) {
+	assert := require.New(t)
+	token, secret, err := models.NewApiToken("ci", "", vo.PermissionRead, time.Now().Unix(), 0)
+	assert.NoError(err)
+	tokens := &testTokens{tokens: []*models.ApiToken{token}}
+	c := NewAuthController(slog.Default(), infra.NewRender(fstest.MapFS{}, ""), &testRepos{}, tokens, nil, "")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+secret)
+	principal, err := c.authenticate(r)
+	assert.NoError(err)
+	assert.Equal(token, principal.Token)
+	assert.NotZero(token.LastUsedAt)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// The badgeMaxAge is how long badges are cached by clients
+const badgeMaxAge = "max-age=300"
+
+// BadgeController serves svg status badges of repo for READMEs:
+//   - version - the meta (VERSION by default, see key query parameter) or id of the latest good artifact
+//   - age - the age of the latest good artifact
+//   - status - the state of the newest artifact
+//
+// The artifacts are filtered by query parameters (see helperArtifactFilter)
+// and the badge label is set by label query parameter.
+type BadgeController struct {
+	log                ports.Logger
+	render             infra.Render
+	artifactRepository domain.ArtifactRepository
+}
+
+func NewBadgeController(log ports.Logger, render infra.Render, artifactRepository domain.ArtifactRepository) *BadgeController {
+	log = log.With(slog.String("entity", "BadgeController"))
+	c := &BadgeController{
+		log:                log,
+		render:             render,
+		artifactRepository: artifactRepository,
+	}
+	return c
+}
+
+func (c *BadgeController) Get(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	kind := chi.URLParam(r, "kind")
+	artifacts, err := c.artifactRepository.FindAll(ports.WithRepoID(repoID))
+	if err != nil { // 500
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	artifacts, err = helperArtifactFilter(r, artifacts)
+	if err != nil { // 400
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	latest, _ := helperLatestArtifact(r, artifacts)
+
+	label, message, color := kind, "none", badgeGrey
+	switch kind {
+	case "version":
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			key = "VERSION"
+		}
+		if latest != nil {
+			message, color = latest.ArtifactID, badgeGreen
+			if value := badgeMeta(latest, key); value != "" {
+				message = value
+			}
+		}
+	case "age":
+		label = "latest"
+		if latest != nil {
+			message, color = helperBadgeAge(time.Unix(latest.CreatedAt, 0), time.Now())
+		}
+	case "status":
+		if len(artifacts) != 0 {
+			message, color = viewmodels.ApiState(artifacts[0].State), badgeGreen
+			if artifacts[0].State.IsBroken() {
+				color = badgeRed
+			} else if artifacts[0].State.IsExpired() {
+				color = badgeGrey
+			}
+		}
+	default: // 404
+		http.Error(w, "unknown badge "+kind, http.StatusNotFound)
+		return
+	}
+	if l := r.URL.Query().Get("label"); l != "" {
+		label = l
+	}
+
+	buf := &bytes.Buffer{}
+	if err := helperWriteBadge(buf, label, message, color); err != nil { // 500
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", badgeMaxAge)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	// The ServeContent handles If-None-Match and HEAD
+	http.ServeContent(w, r, kind+".svg", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+func badgeMeta(artifact *models.Artifact, key string) string {
+	for _, m := range artifact.Meta {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return ""
+}
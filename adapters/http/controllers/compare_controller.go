@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// CompareController compares artifact with other artifact of same or other repo.
+// The other artifact is given by query parameters with (artifact id) and repo (repo id, same repo by default).
+// The previous artifact of the repo is compared by default.
+type CompareController struct {
+	log             ports.Logger
+	render          infra.Render
+	repos           domain.Repositories
+	artifactStorage ports.ArtifactStorage
+}
+
+func NewCompareController(log ports.Logger, render infra.Render, repos domain.Repositories, artifactStorage ports.ArtifactStorage) *CompareController {
+	log = log.With(slog.String("entity", "CompareController"))
+	c := &CompareController{
+		log:             log,
+		render:          render,
+		repos:           repos,
+		artifactStorage: artifactStorage,
+	}
+	return c
+}
+
+// Page shows difference of artifact from other artifact
+func (c *CompareController) Page(w http.ResponseWriter, r *http.Request) {
+	diff, cerr := c.compare(r)
+	if cerr != nil {
+		type Data struct {
+			RepoID     models.RepoID
+			ArtifactID models.ArtifactID
+			Error      error
+		}
+		data := Data{cerr.repoID, cerr.artifactID, cerr.err}
+		if cerr.status == http.StatusNotFound { // 404
+			c.render.HTML(w, cerr.status, "errors/artifact-not-found", data)
+			return
+		}
+		c.render.HTML(w, cerr.status, "errors/artifact-server-error", data)
+		return
+	}
+	c.render.HTML(w, http.StatusOK, "compare", viewmodels.NewArtifactDiff(diff))
+}
+
+// Api returns difference of artifact from other artifact
+func (c *CompareController) Api(w http.ResponseWriter, r *http.Request) {
+	diff, cerr := c.compare(r)
+	if cerr != nil {
+		code := "server_error"
+		if cerr.status == http.StatusNotFound { // 404
+			code = "artifact_not_found"
+		}
+		c.render.JSON(w, cerr.status, viewmodels.NewApiError(code, cerr.err.Error()))
+		return
+	}
+	c.render.JSON(w, http.StatusOK, viewmodels.NewApiArtifactDiff(diff))
+}
+
+// compareError is the failure of comparison with http status
+type compareError struct {
+	status     int
+	repoID     models.RepoID
+	artifactID models.ArtifactID
+	err        error
+}
+
+func (c *CompareController) compare(r *http.Request) (*models.ArtifactDiff, *compareError) {
+	repoID := chi.URLParam(r, "repoID")
+	artifactID := chi.URLParam(r, "artifactID")
+	to, cerr := c.findArtifact(repoID, artifactID)
+	if cerr != nil {
+		return nil, cerr
+	}
+
+	fromRepoID := r.URL.Query().Get("repo")
+	if fromRepoID == "" {
+		fromRepoID = repoID
+	}
+	if fromRepoID != repoID {
+		// The other repo is not guarded by route, so hidden if not readable
+		repo, err := c.repos.Repo().FindByID(fromRepoID)
+		if err == nil && !helperPrincipal(r).Can(repo, vo.PermissionRead) {
+			err = ports.ErrRecordNotFound
+		}
+		if err != nil {
+			return nil, c.findError(fromRepoID, "", fmt.Errorf("repo %v: %w", fromRepoID, err))
+		}
+	}
+
+	fromArtifactID := r.URL.Query().Get("with")
+	if fromArtifactID == "" {
+		previous, cerr := c.findPrevious(to, fromRepoID)
+		if cerr != nil {
+			return nil, cerr
+		}
+		fromArtifactID = previous.ArtifactID
+	}
+	from, cerr := c.findArtifact(fromRepoID, fromArtifactID)
+	if cerr != nil {
+		return nil, cerr
+	}
+
+	return models.NewArtifactDiff(from, to, c.manifest(from), c.manifest(to)), nil
+}
+
+func (c *CompareController) findArtifact(repoID models.RepoID, artifactID models.ArtifactID) (*models.Artifact, *compareError) {
+	artifact, err := c.repos.Artifact().FindByID(repoID, artifactID, ports.WithRelationship(true))
+	if err != nil {
+		return nil, c.findError(repoID, artifactID, fmt.Errorf("artifact %v: %w", artifactID, err))
+	}
+	return artifact, nil
+}
+
+// The findPrevious returns the newest artifact of repo created before given artifact
+func (c *CompareController) findPrevious(artifact *models.Artifact, repoID models.RepoID) (*models.Artifact, *compareError) {
+	artifacts, err := c.repos.Artifact().FindAll(ports.WithRepoID(repoID))
+	if err != nil {
+		return nil, c.findError(repoID, "", err)
+	}
+	for _, a := range artifacts {
+		if a.CreatedAt < artifact.CreatedAt || (a.CreatedAt == artifact.CreatedAt && a.ArtifactID < artifact.ArtifactID) {
+			return a, nil
+		}
+	}
+	return nil, c.findError(repoID, "previous", fmt.Errorf("no artifact before %v: %w", artifact.ArtifactID, ports.ErrRecordNotFound))
+}
+
+func (c *CompareController) findError(repoID models.RepoID, artifactID models.ArtifactID, err error) *compareError {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ports.ErrRecordNotFound) {
+		status = http.StatusNotFound
+	}
+	return &compareError{status, repoID, artifactID, err}
+}
+
+// The manifest returns manifest of artifact (see helperManifest).
+// The files are compared by size only, if manifest is not available.
+func (c *CompareController) manifest(artifact *models.Artifact) models.ManifestFiles {
+	manifest, err := helperManifest(artifact, func(name string) (fs.File, error) {
+		return c.artifactStorage.OpenFile(artifact.Storage, artifact.ArtifactID, name)
+	})
+	if err != nil {
+		c.log.Warn("unable read manifest", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("err", err))
+	}
+	return manifest
+}
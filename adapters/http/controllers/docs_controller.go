@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenApiFileName is the OpenAPI document location in the layered filesystem
+const OpenApiFileName = "static/openapi.yml"
+
+// DocsController serves OpenAPI document and api docs page
+type DocsController struct {
+	log    ports.Logger
+	render infra.Render
+	fs     fs.FS
+}
+
+func NewDocsController(log ports.Logger, render infra.Render, fs fs.FS) *DocsController {
+	log = log.With(slog.String("entity", "DocsController"))
+	c := &DocsController{
+		log:    log,
+		render: render,
+		fs:     fs,
+	}
+	return c
+}
+
+// Index renders interactive api docs page
+func (c *DocsController) Index(w http.ResponseWriter, r *http.Request) {
+	c.render.HTML(w, http.StatusOK, "api-docs", nil)
+}
+
+// OpenApi returns the OpenAPI document in json format
+func (c *DocsController) OpenApi(w http.ResponseWriter, r *http.Request) {
+	data, err := fs.ReadFile(c.fs, OpenApiFileName)
+	if err != nil { // 500
+		c.log.Error("unable read openapi document", slog.Any("err", err))
+		c.render.JSON(w, http.StatusInternalServerError, viewmodels.NewApiError("server_error", err.Error()))
+		return
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil { // 500
+		c.log.Error("unable parse openapi document", slog.Any("err", err))
+		c.render.JSON(w, http.StatusInternalServerError, viewmodels.NewApiError("server_error", err.Error()))
+		return
+	}
+
+	c.render.JSON(w, http.StatusOK, doc)
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// eventsTopics are the bus topics streamed to clients
+var eventsTopics = []ports.Topic{
+	ports.TopicRepoUpdated,
+	ports.TopicArtifactUpdated,
+	ports.TopicArtifactRemoved,
+	ports.TopicArtifactCreated,
+	ports.TopicArtifactExpired,
+	ports.TopicArtifactBroken,
+	ports.TopicBrokenRepoArtifact,
+	ports.TopicDanglingRepoArtifact,
+	ports.TopicReplicatedRepoArtifact,
+	ports.TopicRejectedRepoArtifact,
+}
+
+// eventsKeepAlive is the interval of comments sent to keep idle connection open
+const eventsKeepAlive = 30 * time.Second
+
+// eventsBuffer is the number of events queued per client.
+// The client not reading them out in time is disconnected,
+// so the stalled client does not pile up events in memory.
+const eventsBuffer = 64
+
+// EventsController streams event bus events as Server-Sent Events
+type EventsController struct {
+	log            ports.Logger
+	render         infra.Render
+	bus            ports.EventBus
+	repoRepository domain.RepoRepository
+}
+
+func NewEventsController(log ports.Logger, render infra.Render, bus ports.EventBus, repoRepository domain.RepoRepository) *EventsController {
+	log = log.With(slog.String("entity", "EventsController"))
+	c := &EventsController{
+		log:            log,
+		render:         render,
+		bus:            bus,
+		repoRepository: repoRepository,
+	}
+	return c
+}
+
+// Stream sends events as text/event-stream.
+// The query parameters repo and topic (both might be repeated) filter the events.
+// The events of repos not readable by request principal are not sent.
+func (c *EventsController) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok { // 500
+		c.render.JSON(w, http.StatusInternalServerError, viewmodels.NewApiError("server_error", "streaming is not supported"))
+		return
+	}
+	repos := r.URL.Query()["repo"]
+	topics := r.URL.Query()["topic"]
+	for _, topic := range topics {
+		if !slices.Contains(eventsTopics, topic) { // 400
+			c.render.JSON(w, http.StatusBadRequest, viewmodels.NewApiError("bad_request", "unknown topic "+topic))
+			return
+		}
+	}
+	if len(topics) == 0 {
+		topics = eventsTopics
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	disconnect := sync.OnceFunc(func() {
+		c.log.Warn("disconnect slow client", slog.String("remote", r.RemoteAddr))
+		cancel()
+		// Abort the write blocked by stalled client
+		http.NewResponseController(w).SetWriteDeadline(time.Now())
+	})
+	events := c.subscribe(ctx, topics, disconnect)
+	principal := helperPrincipal(r)
+	readable := map[models.RepoID]bool{}
+	isReadable := func(repoID models.RepoID) bool {
+		ok, known := readable[repoID]
+		if !known {
+			repo, err := c.repoRepository.FindByID(repoID)
+			ok = err == nil && principal.Can(repo, vo.PermissionRead)
+			readable[repoID] = ok
+		}
+		return ok
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case event := <-events:
+			if len(repos) != 0 && !slices.Contains(repos, event.RepoID) {
+				continue
+			}
+			if !isReadable(event.RepoID) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				c.log.Error("unable marshal event", slog.Any("event", event), slog.Any("err", err))
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Topic, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// subscribe merges events of all topics into single channel,
+// until the context is done.
+// The overflow is called, once the channel is full.
+func (c *EventsController) subscribe(ctx context.Context, topics []ports.Topic, overflow func()) chan *viewmodels.ApiEvent {
+	events := make(chan *viewmodels.ApiEvent, eventsBuffer)
+	for _, topic := range topics {
+		ch := c.bus.Sub(topic)
+		go func() {
+			// Keep reading till channel closed by unsub,
+			// so the bus is never blocked by gone or slow client
+			for event := range ch {
+				select {
+				case events <- viewmodels.NewApiEvent(topic, event):
+				default:
+					overflow()
+				}
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			c.bus.Unsub(ch)
+		}()
+	}
+	return events
+}
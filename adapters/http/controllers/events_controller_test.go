@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"bufio"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsStream(t *testing.T) {
+	assert := require.New(t)
+	bus := infra.NewEventBus()
+	defer bus.Shutdown()
+	repos := &testRepos{repos: []*models.Repo{
+		{RepoID: "r1"},
+		{RepoID: "r2"},
+		{RepoID: "r3", Access: models.Access{"alice": vo.PermissionRead}},
+	}}
+	c := NewEventsController(slog.Default(), infra.NewRender(fstest.MapFS{}, ""), bus, repos)
+	srv := httptest.NewServer(http.HandlerFunc(c.Stream))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?repo=r1&repo=r3")
+	assert.NoError(err)
+	defer resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(err)
+	assert.Equal(": connected\n", line)
+
+	// The event of other repo and not readable repo are filtered out
+	bus.Pub(ports.TopicArtifactUpdated, ports.Event{"r2", "a1"})
+	bus.Pub(ports.TopicArtifactUpdated, ports.Event{"r3", "a3"})
+	bus.Pub(ports.TopicArtifactUpdated, ports.Event{"r1", "a2"})
+	lines := []string{}
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		assert.NoError(err)
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal([]string{
+		"event: artifact-updated",
+		`data: {"topic":"artifact-updated","repo_id":"r1","artifact_id":"a2"}`,
+	}, lines)
+
+	// Unknown topic
+	resp2, err := http.Get(srv.URL + "?topic=unknown")
+	assert.NoError(err)
+	resp2.Body.Close()
+	assert.Equal(http.StatusBadRequest, resp2.StatusCode)
+}
+
+// The stalledWriter is response writer of client never reading events.
+// Its write blocks till write deadline set.
+type stalledWriter struct {
+	header   http.Header
+	writes   int
+	deadline chan struct{}
+}
+
+func (w *stalledWriter) Header() http.Header { return w.header }
+func (w *stalledWriter) WriteHeader(int)     {}
+func (w *stalledWriter) Flush()              {}
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	if w.writes++; w.writes == 1 { // connected
+		return len(p), nil
+	}
+	<-w.deadline
+	return 0, http.ErrHandlerTimeout
+}
+func (w *stalledWriter) SetWriteDeadline(time.Time) error {
+	close(w.deadline)
+	return nil
+}
+
+func TestEventsStreamStalled(t *testing.T) {
+	assert := require.New(t)
+	bus := infra.NewEventBus()
+	defer bus.Shutdown()
+	repos := &testRepos{repos: []*models.Repo{{RepoID: "r1"}}}
+	c := NewEventsController(slog.Default(), infra.NewRender(fstest.MapFS{}, ""), bus, repos)
+
+	w := &stalledWriter{header: http.Header{}, deadline: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Stream(w, httptest.NewRequest("GET", "/events?topic="+ports.TopicArtifactUpdated, nil))
+	}()
+	assert.Eventually(func() bool { return len(bus.Depths()[ports.TopicArtifactUpdated]) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// The client is disconnected, once its buffer is full
+	for x := 0; x < eventsBuffer*10; x++ {
+		bus.Pub(ports.TopicArtifactUpdated, ports.Event{"r1", "a1"})
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.FailNow("stalled client is not disconnected")
+	}
+	// ...and its events are not kept by the bus
+	assert.Eventually(func() bool { return len(bus.Depths()[ports.TopicArtifactUpdated]) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
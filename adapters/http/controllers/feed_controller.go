@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// The feedEntries is the number of newest artifacts in feed
+const feedEntries = 50
+
+// FeedController serves Atom feeds of new artifacts of all readable repos and of single repo.
+// The artifacts are filtered by query parameters (see helperArtifactFilter).
+type FeedController struct {
+	log    ports.Logger
+	render infra.Render
+	repos  domain.Repositories
+}
+
+func NewFeedController(log ports.Logger, render infra.Render, repos domain.Repositories) *FeedController {
+	log = log.With(slog.String("entity", "FeedController"))
+	c := &FeedController{
+		log:    log,
+		render: render,
+		repos:  repos,
+	}
+	return c
+}
+
+// All returns feed of artifacts of all readable repos
+func (c *FeedController) All(w http.ResponseWriter, r *http.Request) {
+	repos, err := c.repos.Repo().FindAll()
+	if err != nil { // 500
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	artifacts, err := c.repos.Artifact().FindAll()
+	if err != nil { // 500
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	artifacts = helperReadableFilter(helperReadableRepos(r, repos), artifacts, func(a *models.Artifact) models.RepoID { return a.RepoID })
+	c.feed(w, r, "swamp - artifacts", "/", artifacts)
+}
+
+// Repo returns feed of artifacts of the repo
+func (c *FeedController) Repo(w http.ResponseWriter, r *http.Request) {
+	repoID := chi.URLParam(r, "repoID")
+	title := "swamp - " + repoID
+	if repo := helperRepo(r); repo != nil && repo.Name != "" {
+		title += " - " + repo.Name
+	}
+	artifacts, err := c.repos.Artifact().FindAll(ports.WithRepoID(repoID))
+	if err != nil { // 500
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.feed(w, r, title, "/repo/"+repoID, artifacts)
+}
+
+func (c *FeedController) feed(w http.ResponseWriter, r *http.Request, title, alternatePath string, artifacts []*models.Artifact) {
+	artifacts, err := helperArtifactFilter(r, artifacts)
+	if err != nil { // 400
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(artifacts) > feedEntries {
+		artifacts = artifacts[:feedEntries]
+	}
+
+	selfPath := r.URL.Path
+	if r.URL.RawQuery != "" {
+		selfPath += "?" + r.URL.RawQuery
+	}
+	feed := viewmodels.NewAtomFeed(helperBaseURL(r), selfPath, alternatePath, title, artifacts)
+	if len(artifacts) != 0 {
+		w.Header().Set("Last-Modified", time.Unix(artifacts[0].CreatedAt, 0).UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		c.log.Warn("unable write feed", slog.Any("path", r.URL.Path), slog.Any("err", err))
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
 	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
 	"github.com/cloudcopper/swamp/infra"
 	"github.com/cloudcopper/swamp/lib"
 	"github.com/cloudcopper/swamp/ports"
@@ -36,22 +37,28 @@ func (c *FrontPageController) Index(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		errors = append(errors, err.Error())
 	}
+	repos = helperReadableRepos(r, repos)
 
 	artifacts, err := c.repos.Artifact().FindAll()
 	if err != nil {
 		errors = append(errors, err.Error())
 	}
+	artifacts = helperReadableFilter(repos, artifacts, func(a *models.Artifact) models.RepoID { return a.RepoID })
 
 	perPage := 20
 	artifacts, artifactsPage := helperPagination(r, artifacts, perPage)
 
 	data := struct {
 		Errors        []string
+		AuthEnabled   bool
+		Principal     *models.Principal
 		Repos         []*viewmodels.Repo
 		Artifacts     []*viewmodels.Artifact
 		ArtifactsPage int
 	}{
 		Errors:        errors,
+		AuthEnabled:   helperAuthEnabled(r),
+		Principal:     helperPrincipal(r),
 		Repos:         viewmodels.NewRepos(repos),
 		Artifacts:     viewmodels.NewArtifacts(artifacts),
 		ArtifactsPage: artifactsPage,
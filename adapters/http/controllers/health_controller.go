@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// healthErrorHidden replaces error of check, once repos are not revealed,
+// as the error might contain repo paths
+const healthErrorHidden = "details hidden, admin required"
+
+// HealthController serves health (/healthz) and readiness (/readyz) checks.
+// It responds 503 with the checks, if any check is not ok.
+type HealthController struct {
+	log    ports.Logger
+	render infra.Render
+	health ports.HealthChecker
+}
+
+func NewHealthController(log ports.Logger, render infra.Render, health ports.HealthChecker) *HealthController {
+	log = log.With(slog.String("entity", "HealthController"))
+	c := &HealthController{
+		log:    log,
+		render: render,
+		health: health,
+	}
+	return c
+}
+
+// Health reports watcher, database and repos directories
+func (c *HealthController) Health(w http.ResponseWriter, r *http.Request) {
+	c.renderChecks(w, r, c.health.Health())
+}
+
+// Ready reports health and the initial storage scan
+func (c *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	c.renderChecks(w, r, c.health.Ready())
+}
+
+func (c *HealthController) renderChecks(w http.ResponseWriter, r *http.Request, checks []ports.HealthCheck) {
+	// Do not reveal repos to anyone but admin, if authentication enabled
+	if helperAuthEnabled(r) && !helperPrincipal(r).Admin {
+		for x := range checks {
+			checks[x].RepoID, checks[x].Path = "", ""
+			if checks[x].Error != "" {
+				checks[x].Error = healthErrorHidden
+			}
+		}
+	}
+
+	health := viewmodels.NewApiHealth(checks)
+	status := http.StatusOK
+	if health.Status != ports.HealthOk {
+		c.log.Debug("not healthy", slog.String("path", r.URL.Path), slog.Any("checks", checks))
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	c.render.JSON(w, status, health)
+}
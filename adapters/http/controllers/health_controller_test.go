@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/stretchr/testify/require"
+)
+
+type testHealth []ports.HealthCheck
+
+func (t testHealth) Health() []ports.HealthCheck { return append([]ports.HealthCheck{}, t...) }
+func (t testHealth) Ready() []ports.HealthCheck {
+	return append(t.Health(), ports.HealthCheck{Name: "scan", Status: ports.HealthPending})
+}
+
+func TestHealthController(t *testing.T) {
+	health := testHealth{
+		{Name: "database", Status: ports.HealthOk},
+		{Name: "storage", RepoID: "secret", Path: "/storage/secret", Status: ports.HealthOk},
+	}
+	c := NewHealthController(slog.Default(), infra.NewRender(fstest.MapFS{}, ""), health)
+
+	testCases := []struct {
+		desc      string
+		handler   http.HandlerFunc
+		principal *models.Principal
+		status    int
+		health    string
+		repoID    string
+	}{
+		{"healthy", c.Health, nil, 200, ports.HealthOk, "secret"},
+		{"not ready", c.Ready, nil, 503, ports.HealthPending, "secret"},
+		{"hidden repos", c.Health, &models.Principal{Name: models.PrincipalAnonymous}, 200, ports.HealthOk, ""},
+		{"admin repos", c.Health, &models.Principal{Name: "alice", Admin: true}, 200, ports.HealthOk, "secret"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert := require.New(t)
+			r := httptest.NewRequest("GET", "/healthz", nil)
+			if tC.principal != nil {
+				r = r.WithContext(contextWithAuth(r.Context(), tC.principal, true))
+			}
+			w := httptest.NewRecorder()
+			tC.handler(w, r)
+			assert.Equal(tC.status, w.Code)
+			h := &viewmodels.ApiHealth{}
+			assert.NoError(json.Unmarshal(w.Body.Bytes(), h))
+			assert.Equal(tC.health, h.Status)
+			assert.Equal(tC.repoID, h.Checks[1].RepoID)
+		})
+	}
+}
+
+func TestHealthControllerHiddenError(t *testing.T) {
+	health := testHealth{
+		{Name: "input", RepoID: "secret", Path: "/input/secret", Status: ports.HealthDegraded, Error: "open /input/secret: permission denied"},
+	}
+	c := NewHealthController(slog.Default(), infra.NewRender(fstest.MapFS{}, ""), health)
+
+	testCases := []struct {
+		desc      string
+		principal *models.Principal
+		err       string
+	}{
+		{"auth disabled", nil, "open /input/secret: permission denied"},
+		{"anonymous", &models.Principal{Name: models.PrincipalAnonymous}, healthErrorHidden},
+		{"not admin", &models.Principal{Name: "bob"}, healthErrorHidden},
+		{"admin", &models.Principal{Name: "alice", Admin: true}, "open /input/secret: permission denied"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert := require.New(t)
+			r := httptest.NewRequest("GET", "/healthz", nil)
+			if tC.principal != nil {
+				r = r.WithContext(contextWithAuth(r.Context(), tC.principal, true))
+			}
+			w := httptest.NewRecorder()
+			c.Health(w, r)
+			assert.Equal(http.StatusServiceUnavailable, w.Code)
+			h := &viewmodels.ApiHealth{}
+			assert.NoError(json.Unmarshal(w.Body.Bytes(), h))
+			assert.Equal(ports.HealthDegraded, h.Status)
+			assert.Equal(tC.err, h.Checks[0].Error)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveFormat is the format of artifact archive download
+type archiveFormat struct {
+	suffix      string
+	contentType string
+	newWriter   func(w io.Writer) (archiveWriter, error)
+}
+
+// The archiveFormats are the archive formats by artifact download suffix
+var archiveFormats = []*archiveFormat{
+	{".zip", "application/zip", newZipArchive},
+	{".tar", "application/x-tar", newTarArchive(nil)},
+	{".tar.gz", "application/gzip", newTarArchive(func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil })},
+	{".tar.zst", "application/zstd", newTarArchive(func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1)) })},
+	{".tar.xz", "application/x-xz", newTarArchive(func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) })},
+}
+
+// helperArchiveFormat returns archive format and artifact id of the archive name (e.g. ID.tar.gz),
+// or nil if name has no archive suffix
+func helperArchiveFormat(name string) (*archiveFormat, models.ArtifactID) {
+	for _, format := range archiveFormats {
+		if artifactID, ok := strings.CutSuffix(name, format.suffix); ok {
+			return format, artifactID
+		}
+	}
+	return nil, name
+}
+
+// helperSelectFiles returns files matching any of glob patterns (see path.Match) sorted by name.
+// The pattern matching directory selects all files within.
+// The patterns might be also comma separated.
+// All files returned, if there are no patterns.
+func helperSelectFiles(files models.ArtifactFiles, patterns []string) models.ArtifactFiles {
+	selected := models.ArtifactFiles{}
+	for _, file := range files {
+		if len(patterns) == 0 || helperMatchFile(filepath.ToSlash(file.Name), patterns) {
+			selected = append(selected, file)
+		}
+	}
+	slices.SortFunc(selected, func(a, b *models.ArtifactFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return selected
+}
+
+func helperMatchFile(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		for _, pattern := range strings.Split(pattern, ",") {
+			pattern = strings.Trim(pattern, "/")
+			if pattern == "" {
+				continue
+			}
+			// Match the file or any of its directories
+			for n := name; n != "."; n = path.Dir(n) {
+				if ok, _ := path.Match(pattern, n); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// The archiveVersion is changed once archive content is changed,
+// so archives cached by previous versions are not served
+const archiveVersion = 2
+
+// helperArchiveKey returns name of the artifact archive in archive cache.
+// It is unique for the artifact content, so recreated artifact is never served from old archive.
+func helperArchiveKey(artifact *models.Artifact, format *archiveFormat) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v\x00%v\x00%v\x00%v\x00%v", archiveVersion, artifact.RepoID, artifact.ArtifactID, artifact.Checksum, artifact.CreatedAt)))
+	return hex.EncodeToString(sum[:16]) + format.suffix
+}
+
+// archiveWriter writes files into archive.
+// The parent directories are added prior files.
+type archiveWriter interface {
+	addDir(name string, modTime time.Time) error
+	addFile(name string, size int64, mode fs.FileMode, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+// archiveError is the error of archive generation
+type archiveError struct {
+	op       string
+	filename string
+	err      error
+}
+
+func (e *archiveError) Error() string {
+	return fmt.Sprintf("%v %v: %v", e.op, e.filename, e.err)
+}
+
+func (e *archiveError) Unwrap() error {
+	return e.err
+}
+
+// helperWriteArchive writes files sorted by name into archive.
+// The manifest of files (see helperManifest) is added as SHA256SUMS,
+// unless it is empty or there is such file already.
+// The archive is reproducible - all entries have modTime
+// and normalised modes (0755 for directories and executables, 0644 for others).
+// The files are opened by open function.
+// It returns *archiveError on failure.
+func helperWriteArchive(w io.Writer, format *archiveFormat, files models.ArtifactFiles, manifest models.ManifestFiles, modTime time.Time, open func(name string) (fs.File, error)) error {
+	archive, err := format.newWriter(w)
+	if err != nil {
+		return &archiveError{"create archive", "", err}
+	}
+	files = slices.Clone(files)
+	slices.SortFunc(files, func(a, b *models.ArtifactFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	dirs := map[string]bool{}
+	for _, modelFile := range files {
+		if op, err := func() (string, error) {
+			file, err := open(modelFile.Name)
+			if err != nil {
+				return "open file", err
+			}
+			defer file.Close()
+
+			// Get file information
+			info, err := file.Stat()
+			if err != nil {
+				return "stat file", err
+			}
+
+			// Add parent directories not added yet
+			name := filepath.ToSlash(modelFile.Name)
+			parents := []string{}
+			for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+				dirs[dir] = true
+				parents = append(parents, dir)
+			}
+			slices.Reverse(parents)
+			for _, dir := range parents {
+				if err := archive.addDir(dir, modTime); err != nil {
+					return "add directory to archive", err
+				}
+			}
+
+			mode := fs.FileMode(0o644)
+			if info.Mode()&0o111 != 0 {
+				mode = 0o755
+			}
+			if err := archive.addFile(name, info.Size(), mode, modTime, file); err != nil {
+				return "add file to archive", err
+			}
+			return "", nil
+		}(); err != nil {
+			archive.Close()
+			return &archiveError{op, modelFile.Name, err}
+		}
+	}
+
+	if len(manifest) > 0 && helperFindFile(&models.Artifact{Files: files}, manifestName) == nil {
+		buf := &bytes.Buffer{}
+		helperWriteManifest(buf, manifest)
+		if err := archive.addFile(manifestName, int64(buf.Len()), 0o644, modTime, buf); err != nil {
+			archive.Close()
+			return &archiveError{"add file to archive", manifestName, err}
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return &archiveError{"close archive", "", err}
+	}
+	return nil
+}
+
+// The zipArchive writes zip archive with deflated files
+type zipArchive struct {
+	w *zip.Writer
+}
+
+func newZipArchive(w io.Writer) (archiveWriter, error) {
+	return &zipArchive{zip.NewWriter(w)}, nil
+}
+
+func (a *zipArchive) addDir(name string, modTime time.Time) error {
+	header := &zip.FileHeader{Name: name + "/", Modified: modTime.UTC()}
+	header.SetMode(fs.ModeDir | 0o755)
+	_, err := a.w.CreateHeader(header)
+	return err
+}
+
+func (a *zipArchive) addFile(name string, size int64, mode fs.FileMode, modTime time.Time, r io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime.UTC()}
+	header.SetMode(mode)
+	fw, err := a.w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.w.Close()
+}
+
+// The tarArchive writes tar archive optionally compressed
+type tarArchive struct {
+	w          *tar.Writer
+	compressor io.WriteCloser
+}
+
+func newTarArchive(compress func(w io.Writer) (io.WriteCloser, error)) func(w io.Writer) (archiveWriter, error) {
+	return func(w io.Writer) (archiveWriter, error) {
+		a := &tarArchive{}
+		if compress != nil {
+			compressor, err := compress(w)
+			if err != nil {
+				return nil, err
+			}
+			a.compressor, w = compressor, compressor
+		}
+		a.w = tar.NewWriter(w)
+		return a, nil
+	}
+}
+
+func (a *tarArchive) addDir(name string, modTime time.Time) error {
+	return a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     0o755,
+		ModTime:  modTime,
+	})
+}
+
+func (a *tarArchive) addFile(name string, size int64, mode fs.FileMode, modTime time.Time, r io.Reader) error {
+	err := a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     int64(mode),
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(a.w, r)
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	err := a.w.Close()
+	if a.compressor != nil {
+		if err2 := a.compressor.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+func TestHelperArchiveFormat(t *testing.T) {
+	assert := require.New(t)
+	format, artifactID := helperArchiveFormat("01J2.tar.zst")
+	assert.Equal(".tar.zst", format.suffix)
+	assert.Equal("01J2", artifactID)
+	format, artifactID = helperArchiveFormat("01J2.tar")
+	assert.Equal(".tar", format.suffix)
+	assert.Equal("01J2", artifactID)
+	format, artifactID = helperArchiveFormat("01J2")
+	assert.Nil(format)
+	assert.Equal("01J2", artifactID)
+}
+
+func TestHelperSelectFiles(t *testing.T) {
+	files := models.ArtifactFiles{
+		{Name: "readme.txt"},
+		{Name: "bin/app.elf"},
+		{Name: "bin/app.map"},
+		{Name: "docs/api/index.html"},
+	}
+	names := func(files models.ArtifactFiles) []string {
+		n := []string{}
+		for _, f := range files {
+			n = append(n, f.Name)
+		}
+		return n
+	}
+	testCases := []struct {
+		desc     string
+		patterns []string
+		expected []string
+	}{
+		{"all", nil, []string{"bin/app.elf", "bin/app.map", "docs/api/index.html", "readme.txt"}},
+		{"glob", []string{"bin/*.elf"}, []string{"bin/app.elf"}},
+		{"directory", []string{"docs"}, []string{"docs/api/index.html"}},
+		{"nested directory", []string{"docs/api/"}, []string{"docs/api/index.html"}},
+		{"comma separated", []string{"readme.txt,bin/*.map"}, []string{"bin/app.map", "readme.txt"}},
+		{"many", []string{"readme.txt", "bin/*.map"}, []string{"bin/app.map", "readme.txt"}},
+		{"none", []string{"*.iso"}, []string{}},
+		{"bad pattern", []string{"[bin"}, []string{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			require.Equal(t, tC.expected, names(helperSelectFiles(files, tC.patterns)))
+		})
+	}
+}
+
+func TestHelperWriteArchive(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	fileTime := time.Date(2024, 5, 2, 8, 0, 0, 0, time.Local)
+	fsys := fstest.MapFS{
+		"readme.txt":          {Data: []byte("readme"), Mode: 0o644, ModTime: fileTime},
+		"bin/app.elf":         {Data: []byte("elf"), Mode: 0o700, ModTime: fileTime},
+		"docs/api/index.html": {Data: []byte("<html>"), Mode: 0o600, ModTime: fileTime},
+	}
+	files := helperSelectFiles(models.ArtifactFiles{{Name: "readme.txt"}, {Name: "docs/api/index.html"}, {Name: "bin/app.elf"}}, nil)
+	manifest := models.ManifestFiles{{Name: "readme.txt", Size: 6, Sha256: "8e54"}}
+	expected := []string{
+		"bin/ dir",
+		"bin/app.elf -rwxr-xr-x elf",
+		"docs/ dir",
+		"docs/api/ dir",
+		"docs/api/index.html -rw-r--r-- <html>",
+		"readme.txt -rw-r--r-- readme",
+		"SHA256SUMS -rw-r--r-- 8e54  readme.txt\n",
+	}
+
+	decompress := map[string]func(r io.Reader) (io.Reader, error){
+		".tar":     func(r io.Reader) (io.Reader, error) { return r, nil },
+		".tar.gz":  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		".tar.zst": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		".tar.xz":  func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+	}
+	for _, format := range archiveFormats {
+		t.Run(format.suffix, func(t *testing.T) {
+			assert := require.New(t)
+			buf := &bytes.Buffer{}
+			assert.NoError(helperWriteArchive(buf, format, files, manifest, modTime, fsys.Open))
+
+			// Reproducible regardless of files order
+			reversed := slices.Clone(files)
+			slices.Reverse(reversed)
+			again := &bytes.Buffer{}
+			assert.NoError(helperWriteArchive(again, format, reversed, manifest, modTime, fsys.Open))
+			assert.Equal(buf.Bytes(), again.Bytes())
+
+			entries := []string{}
+			if format.suffix == ".zip" {
+				zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+				assert.NoError(err)
+				for _, f := range zr.File {
+					assert.True(f.Modified.Equal(modTime), f.Name)
+					if f.Mode().IsDir() {
+						entries = append(entries, f.Name+" dir")
+						continue
+					}
+					rc, err := f.Open()
+					assert.NoError(err)
+					data, err := io.ReadAll(rc)
+					assert.NoError(err)
+					entries = append(entries, f.Name+" "+f.Mode().String()+" "+string(data))
+				}
+			} else {
+				r, err := decompress[format.suffix](buf)
+				assert.NoError(err)
+				tr := tar.NewReader(r)
+				for {
+					h, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					assert.NoError(err)
+					assert.True(h.ModTime.Equal(modTime), h.Name)
+					if h.Typeflag == tar.TypeDir {
+						entries = append(entries, h.Name+" dir")
+						continue
+					}
+					data, err := io.ReadAll(tr)
+					assert.NoError(err)
+					entries = append(entries, h.Name+" "+h.FileInfo().Mode().String()+" "+string(data))
+				}
+			}
+			assert.Equal(expected, entries)
+		})
+	}
+
+	// Missing file
+	files = append(files, &models.ArtifactFile{Name: "missing.bin"})
+	err := helperWriteArchive(io.Discard, archiveFormats[0], files, nil, modTime, fsys.Open)
+	archiveErr := &archiveError{}
+	require.ErrorAs(t, err, &archiveErr)
+	require.Equal(t, "open file", archiveErr.op)
+	require.Equal(t, "missing.bin", archiveErr.filename)
+}
+
+func TestHelperArchiveKey(t *testing.T) {
+	assert := require.New(t)
+	artifact := &models.Artifact{RepoID: "repo", ArtifactID: "01J2", Checksum: "abc", CreatedAt: 1714566600}
+	key := helperArchiveKey(artifact, archiveFormats[2])
+	assert.Regexp("^[0-9a-f]{32}\\.tar\\.gz$", key)
+	assert.Equal(key, helperArchiveKey(artifact, archiveFormats[2]))
+	assert.NotEqual(key, helperArchiveKey(artifact, archiveFormats[1]))
+	// Recreated artifact
+	artifact.CreatedAt++
+	assert.NotEqual(key, helperArchiveKey(artifact, archiveFormats[2]))
+}
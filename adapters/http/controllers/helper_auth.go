@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+)
+
+// The helperPrincipal returns principal of the request.
+// It is anonymous, if request not passed AuthController.Authenticate.
+func helperPrincipal(r *http.Request) *models.Principal {
+	if a, ok := r.Context().Value(authContextKey{}).(*authContext); ok {
+		return a.principal
+	}
+	return models.Anonymous
+}
+
+// The helperAuthEnabled returns true, if authentication configured
+func helperAuthEnabled(r *http.Request) bool {
+	if a, ok := r.Context().Value(authContextKey{}).(*authContext); ok {
+		return a.enabled
+	}
+	return false
+}
+
+// The helperReadableRepos returns the repos readable by the request principal
+func helperReadableRepos(r *http.Request, repos []*models.Repo) []*models.Repo {
+	principal := helperPrincipal(r)
+	readable := []*models.Repo{}
+	for _, repo := range repos {
+		if principal.Can(repo, vo.PermissionRead) {
+			readable = append(readable, repo)
+		}
+	}
+	return readable
+}
+
+// The helperReadableFilter returns items belonging to the readable repos only
+func helperReadableFilter[T any](repos []*models.Repo, items []T, repoID func(T) models.RepoID) []T {
+	ids := map[models.RepoID]bool{}
+	for _, repo := range repos {
+		ids[repo.RepoID] = true
+	}
+	filtered := []T{}
+	for _, item := range items {
+		if ids[repoID(item)] {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// The helperIsApiRequest returns true for requests expecting json response
+func helperIsApiRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/events"
+}
+
+// The helperIsCrossSite returns true for browser requests initiated by other sites.
+// Such requests shall not change anything, as browsers send basic auth credentials with them.
+func helperIsCrossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		return err != nil || u.Host != r.Host
+	}
+	return false
+}
+
+// The helperRepo returns the repo of the request allowed by AuthController.RequireRepo or nil
+func helperRepo(r *http.Request) *models.Repo {
+	repo, _ := r.Context().Value(repoContextKey{}).(*models.Repo)
+	return repo
+}
+
+// The helperClient returns the request client identity - authenticated principal or ip
+func helperClient(r *http.Request) string {
+	if principal := helperPrincipal(r); !principal.IsAnonymous() {
+		return "principal:" + principal.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
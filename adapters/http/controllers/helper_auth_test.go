@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperIsCrossSite(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		headers   map[string]string
+		crossSite bool
+	}{
+		{"curl", nil, false},
+		{"same origin", map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, false},
+		{"cross site", map[string]string{"Sec-Fetch-Site": "cross-site"}, true},
+		{"same site", map[string]string{"Sec-Fetch-Site": "same-site"}, true},
+		{"other origin", map[string]string{"Origin": "http://evil.example.com"}, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/repos/repo1/artifacts/a1/expire", nil)
+			for k, v := range tC.headers {
+				r.Header.Set(k, v)
+			}
+			require.Equal(t, tC.crossSite, helperIsCrossSite(r))
+		})
+	}
+}
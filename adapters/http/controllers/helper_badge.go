@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"io"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+)
+
+// The badge colors
+const (
+	badgeGreen       = "#4c1"
+	badgeYellowGreen = "#97ca00"
+	badgeYellow      = "#dfb317"
+	badgeOrange      = "#fe7d37"
+	badgeRed         = "#e05d44"
+	badgeGrey        = "#9f9f9f"
+	badgeLabelColor  = "#555"
+)
+
+// The badgeSVG is the flat badge of label and message
+var badgeSVG = template.Must(template.New("badge.svg").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{html .Label}}: {{html .Message}}">
+<title>{{html .Label}}: {{html .Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="{{.LabelWidth}}" height="20" fill="` + badgeLabelColor + `"/>
+<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+<rect width="{{.Width}}" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Label}}</text>
+<text x="{{.LabelX}}" y="14">{{html .Label}}</text>
+<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Message}}</text>
+<text x="{{.MessageX}}" y="14">{{html .Message}}</text>
+</g>
+</svg>
+`))
+
+// helperWriteBadge writes svg badge.
+// The text width is estimated, as fonts are not available.
+func helperWriteBadge(w io.Writer, label, message, color string) error {
+	textWidth := func(s string) int { return utf8.RuneCountInString(s)*7 + 10 }
+	labelWidth, messageWidth := textWidth(label), textWidth(message)
+	return badgeSVG.Execute(w, struct {
+		Label, Message, Color           string
+		Width, LabelWidth, MessageWidth int
+		LabelX, MessageX                float64
+	}{
+		Label:        label,
+		Message:      message,
+		Color:        color,
+		Width:        labelWidth + messageWidth,
+		LabelWidth:   labelWidth,
+		MessageWidth: messageWidth,
+		LabelX:       float64(labelWidth) / 2,
+		MessageX:     float64(labelWidth) + float64(messageWidth)/2,
+	})
+}
+
+// helperBadgeAge returns the age message and color of artifact created at given time
+func helperBadgeAge(createdAt, now time.Time) (string, string) {
+	message := humanize.RelTime(createdAt, now, "ago", "from now")
+	age := now.Sub(createdAt)
+	switch {
+	case age < 24*time.Hour:
+		return message, badgeGreen
+	case age < 7*24*time.Hour:
+		return message, badgeYellowGreen
+	case age < 30*24*time.Hour:
+		return message, badgeYellow
+	}
+	return message, badgeOrange
+}
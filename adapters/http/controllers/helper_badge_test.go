@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperWriteBadge(t *testing.T) {
+	assert := require.New(t)
+	buf := &bytes.Buffer{}
+	assert.NoError(helperWriteBadge(buf, "version", `<1.2.3 & "x">`, badgeGreen))
+	assert.Contains(buf.String(), `width="160"`)
+	assert.Contains(buf.String(), "&lt;1.2.3 &amp; &#34;x&#34;&gt;")
+	assert.NoError(xml.Unmarshal(buf.Bytes(), new(struct{})))
+}
+
+func TestHelperBadgeAge(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		age     time.Duration
+		message string
+		color   string
+	}{
+		{2 * time.Hour, "2 hours ago", badgeGreen},
+		{3 * 24 * time.Hour, "3 days ago", badgeYellowGreen},
+		{14 * 24 * time.Hour, "2 weeks ago", badgeYellow},
+		{60 * 24 * time.Hour, "2 months ago", badgeOrange},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.message, func(t *testing.T) {
+			message, color := helperBadgeAge(now.Add(-tC.age), now)
+			require.Equal(t, tC.message, message)
+			require.Equal(t, tC.color, color)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// helperFindFile returns the artifact file model by its name or nil
+func helperFindFile(artifact *models.Artifact, filename string) *models.ArtifactFile {
+	for _, modelFile := range artifact.Files {
+		if modelFile.Name == filename {
+			return modelFile
+		}
+	}
+	return nil
+}
+
+// helperServeFile serves the artifact file as attachment.
+// It supports range, conditional (ETag, Last-Modified) and HEAD requests.
+// It returns failed operation and error.
+func helperServeFile(w http.ResponseWriter, r *http.Request, storage ports.ArtifactStorage, artifact *models.Artifact, filename string) (op string, err error) {
+	// Open file
+	file, err := storage.OpenFile(artifact.Storage, artifact.ArtifactID, filename)
+	if err != nil {
+		return "open file", err
+	}
+	defer file.Close()
+
+	// Detect proper mime
+	ext := filepath.Ext(filename)
+	mimeType := mime.TypeByExtension(ext)
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	// Set headers for file
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Disposition", helperAttachment(filepath.Base(filename)))
+	w.Header().Set("ETag", helperETag(artifact, filename))
+
+	// The ServeContent handles Range, If-Range, If-None-Match, If-Modified-Since and HEAD
+	http.ServeContent(w, r, filename, time.Unix(artifact.CreatedAt, 0), file)
+	return "", nil
+}
+
+// helperAttachment returns Content-Disposition of attachment with the file name.
+// The name is quoted or encoded, as it might come from uploaded artifact.
+func helperAttachment(name string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": name})
+}
+
+// helperETag returns strong ETag of the artifact file.
+// The artifact files never change after creation,
+// so the ETag derived from the artifact checksum and file name.
+func helperETag(artifact *models.Artifact, filename string) string {
+	sum := sha256.Sum256([]byte(artifact.Checksum + "\x00" + filename))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
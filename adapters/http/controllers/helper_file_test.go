@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperServeFile(t *testing.T) {
+	assert := require.New(t)
+	fs := afero.NewMemMapFs()
+	assert.NoError(afero.WriteFile(fs, "/storage/a1/file.bin", []byte("0123456789"), 0o644))
+	storage, err := adapters.NewBasicArtifactStorageAdapter(slog.Default(), fs)
+	assert.NoError(err)
+	defer storage.Close()
+	artifact := &models.Artifact{Storage: "/storage", ArtifactID: "a1", Checksum: "0123456789abcdef", CreatedAt: 1700000000}
+	etag := helperETag(artifact, "file.bin")
+	lastModified := time.Unix(artifact.CreatedAt, 0).UTC().Format(http.TimeFormat)
+
+	serve := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/file.bin", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		_, err := helperServeFile(w, r, storage, artifact, "file.bin")
+		assert.NoError(err)
+		return w
+	}
+
+	// Full download
+	w := serve(http.MethodGet, nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("0123456789", w.Body.String())
+	assert.Equal(etag, w.Header().Get("ETag"))
+	assert.Equal(lastModified, w.Header().Get("Last-Modified"))
+	assert.Equal("bytes", w.Header().Get("Accept-Ranges"))
+
+	// Resume download
+	w = serve(http.MethodGet, map[string]string{"Range": "bytes=4-"})
+	assert.Equal(http.StatusPartialContent, w.Code)
+	assert.Equal("456789", w.Body.String())
+
+	// Resume download with same and changed ETag
+	w = serve(http.MethodGet, map[string]string{"Range": "bytes=4-", "If-Range": etag})
+	assert.Equal(http.StatusPartialContent, w.Code)
+	w = serve(http.MethodGet, map[string]string{"Range": "bytes=4-", "If-Range": `"other"`})
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("0123456789", w.Body.String())
+
+	// Conditional requests
+	w = serve(http.MethodGet, map[string]string{"If-None-Match": etag})
+	assert.Equal(http.StatusNotModified, w.Code)
+	w = serve(http.MethodGet, map[string]string{"If-Modified-Since": lastModified})
+	assert.Equal(http.StatusNotModified, w.Code)
+
+	// Head
+	w = serve(http.MethodHead, nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("10", w.Header().Get("Content-Length"))
+	assert.Empty(w.Body.String())
+}
+
+func TestHelperAttachment(t *testing.T) {
+	assert := require.New(t)
+	for name, expected := range map[string]string{
+		"file.bin":       `attachment; filename=file.bin`,
+		"my file.bin":    `attachment; filename="my file.bin"`,
+		`a"; b=c.bin`:    `attachment; filename="a\"; b=c.bin"`,
+		"файл.bin":       `attachment; filename*=utf-8''%D1%84%D0%B0%D0%B9%D0%BB.bin`,
+		"a\r\nX-Evil: 1": `attachment; filename*=utf-8''a%0D%0AX-Evil%3A%201`,
+	} {
+		v := helperAttachment(name)
+		assert.Equal(expected, v, name)
+		_, params, err := mime.ParseMediaType(v)
+		assert.NoError(err, name)
+		assert.Equal(name, params["filename"], name)
+	}
+}
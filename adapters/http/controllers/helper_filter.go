@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloudcopper/swamp/domain/models"
+)
+
+const metaFilterPrefix = "meta."
+
+// helperArtifactFilter filters artifacts by request query parameters:
+//   - state=ok|broken|expired
+//   - meta.KEY=VALUE - the artifact meta KEY must be equal to VALUE
+func helperArtifactFilter(r *http.Request, artifacts []*models.Artifact) ([]*models.Artifact, error) {
+	query := r.URL.Query()
+
+	state := query.Get("state")
+	switch state {
+	case "", "ok", "broken", "expired":
+	default:
+		return nil, fmt.Errorf("unknown state %q", state)
+	}
+
+	meta := map[string]string{}
+	for key, values := range query {
+		if k, ok := strings.CutPrefix(key, metaFilterPrefix); ok && k != "" {
+			meta[k] = values[0]
+		}
+	}
+
+	result := []*models.Artifact{}
+	for _, artifact := range artifacts {
+		switch {
+		case state == "ok" && !artifact.State.IsOK():
+			continue
+		case state == "broken" && !artifact.State.IsBroken():
+			continue
+		case state == "expired" && !artifact.State.IsExpired():
+			continue
+		}
+		if !helperMatchMeta(artifact.Meta, meta) {
+			continue
+		}
+		result = append(result, artifact)
+	}
+	return result, nil
+}
+
+func helperMatchMeta(metas models.ArtifactMetas, filter map[string]string) bool {
+	for key, value := range filter {
+		found := false
+		for _, m := range metas {
+			if m.Key == key && m.Value == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// helperLatestArtifact returns the newest good (neither broken nor expired) artifact
+// matching the request filters (see helperArtifactFilter) or nil.
+// The artifacts expected to be sorted newest first.
+func helperLatestArtifact(r *http.Request, artifacts []*models.Artifact) (*models.Artifact, error) {
+	artifacts, err := helperArtifactFilter(r, artifacts)
+	if err != nil {
+		return nil, err
+	}
+	for _, artifact := range artifacts {
+		if artifact.State.IsOK() {
+			return artifact, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperArtifactFilter(t *testing.T) {
+	artifacts := []*models.Artifact{
+		{ArtifactID: "a1", State: vo.ArtifactIsOK, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "main"}}},
+		{ArtifactID: "a2", State: vo.ArtifactIsBroken, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "main"}}},
+		{ArtifactID: "a3", State: vo.ArtifactIsExpired, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "dev"}}},
+		{ArtifactID: "a4", State: vo.ArtifactIsOK},
+	}
+	testCases := []struct {
+		desc  string
+		query string
+		ids   []string
+		err   bool
+	}{
+		{"no filter", "", []string{"a1", "a2", "a3", "a4"}, false},
+		{"state ok", "state=ok", []string{"a1", "a4"}, false},
+		{"state broken", "state=broken", []string{"a2"}, false},
+		{"state expired", "state=expired", []string{"a3"}, false},
+		{"meta branch", "meta.BRANCH=main", []string{"a1", "a2"}, false},
+		{"meta branch and state", "meta.BRANCH=main&state=ok", []string{"a1"}, false},
+		{"meta not found", "meta.BRANCH=none", []string{}, false},
+		{"wrong state", "state=unknown", nil, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert := require.New(t)
+			r := &http.Request{URL: &url.URL{RawQuery: tC.query}}
+			result, err := helperArtifactFilter(r, artifacts)
+			if tC.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			ids := []string{}
+			for _, a := range result {
+				ids = append(ids, a.ArtifactID)
+			}
+			assert.Equal(tC.ids, ids)
+		})
+	}
+}
+
+func TestHelperLatestArtifact(t *testing.T) {
+	artifacts := []*models.Artifact{
+		{ArtifactID: "a1", State: vo.ArtifactIsBroken, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "main"}}},
+		{ArtifactID: "a2", State: vo.ArtifactIsOK, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "dev"}}},
+		{ArtifactID: "a3", State: vo.ArtifactIsOK, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "main"}}},
+		{ArtifactID: "a4", State: vo.ArtifactIsExpired, Meta: models.ArtifactMetas{{Key: "BRANCH", Value: "feature"}}},
+	}
+	testCases := []struct {
+		desc  string
+		query string
+		id    string
+	}{
+		{"any branch", "", "a2"},
+		{"main branch", "meta.BRANCH=main", "a3"},
+		{"expired branch", "meta.BRANCH=feature", ""},
+		{"unknown branch", "meta.BRANCH=none", ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert := require.New(t)
+			r := &http.Request{URL: &url.URL{RawQuery: tC.query}}
+			artifact, err := helperLatestArtifact(r, artifacts)
+			assert.NoError(err)
+			if tC.id == "" {
+				assert.Nil(artifact)
+				return
+			}
+			assert.Equal(tC.id, artifact.ArtifactID)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+)
+
+// The helperLimitDownload applies the download limits of the request repo to the request client.
+// It returns response writer limited by bandwidth and release function to be called once download complete.
+// It renders 429 with Retry-After and returns nil writer, if client exceeds the limits.
+func helperLimitDownload(w http.ResponseWriter, r *http.Request, render infra.Render, limiter *infra.Limiter, repoID models.RepoID, archive bool) (http.ResponseWriter, func()) {
+	if limiter == nil {
+		return w, func() {}
+	}
+	var limits *models.Limits
+	if repo := helperRepo(r); repo != nil {
+		limits = repo.Limits
+	}
+
+	limited, release, delay := limiter.Download(r.Context(), repoID, limits, helperClient(r), w, archive)
+	if delay > 0 { // 429
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		message := "too many downloads, retry after " + w.Header().Get("Retry-After") + "s"
+		if archive {
+			message = "too many downloads or archives generated, retry after " + w.Header().Get("Retry-After") + "s"
+		}
+		if helperIsApiRequest(r) {
+			render.JSON(w, http.StatusTooManyRequests, viewmodels.NewApiError("too_many_requests", message))
+			return nil, nil
+		}
+		type Data struct {
+			Error string
+		}
+		render.HTML(w, http.StatusTooManyRequests, "errors/429", Data{message})
+		return nil, nil
+	}
+	return &limitedResponseWriter{ResponseWriter: w, w: limited}, release
+}
+
+// The limitedResponseWriter writes body to bandwidth limited writer
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (l *limitedResponseWriter) Write(p []byte) (int, error) {
+	return l.w.Write(p)
+}
+
+func (l *limitedResponseWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
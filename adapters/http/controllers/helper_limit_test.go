@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperLimitDownload(t *testing.T) {
+	assert := require.New(t)
+	render := infra.NewRender(fstest.MapFS{}, "")
+	limiter := infra.NewLimiter(nil)
+	repo := &models.Repo{RepoID: "repo", Limits: &models.Limits{Rate: 0.01, Burst: 1}}
+
+	download := func(remote string, p *models.Principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/v1/repos/repo/artifacts/a/files/f", nil)
+		r.RemoteAddr = remote
+		ctx := contextWithRepo(r.Context(), repo)
+		if p != nil {
+			ctx = contextWithAuth(ctx, p, true)
+		}
+		w := httptest.NewRecorder()
+		limited, release := helperLimitDownload(w, r.WithContext(ctx), render, limiter, "repo", false)
+		if limited != nil {
+			defer release()
+			limited.WriteHeader(http.StatusOK)
+		}
+		return w
+	}
+
+	assert.Equal(http.StatusOK, download("10.0.0.1:1000", nil).Code)
+	w := download("10.0.0.1:1001", nil)
+	assert.Equal(http.StatusTooManyRequests, w.Code, "same ip")
+	assert.NotEmpty(w.Header().Get("Retry-After"))
+	assert.Contains(w.Body.String(), "too_many_requests")
+	assert.Equal(http.StatusOK, download("10.0.0.2:1000", nil).Code, "other ip")
+	assert.Equal(http.StatusOK, download("10.0.0.1:1000", &models.Principal{Name: "ci"}).Code, "token is other client")
+	assert.Equal(http.StatusTooManyRequests, download("10.0.0.3:1000", &models.Principal{Name: "ci"}).Code, "same token")
+
+	// The limiter is optional
+	w = httptest.NewRecorder()
+	limited, release := helperLimitDownload(w, httptest.NewRequest("GET", "/", nil), render, nil, "repo", true)
+	assert.Equal(http.ResponseWriter(w), limited)
+	release()
+}
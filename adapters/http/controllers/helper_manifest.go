@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/lib"
+)
+
+// The manifestName is the name of manifest file in archives
+const manifestName = "SHA256SUMS"
+
+// The manifestChecksumSuffix is the suffix of artifact checksum files the manifest is made of
+const manifestChecksumSuffix = ".sha256sum"
+
+// helperManifest returns manifest of the artifact files sorted by name.
+// It is made of artifact checksum files kept in storage,
+// so lists only files listed in checksum files.
+// The files are opened by open function.
+func helperManifest(artifact *models.Artifact, open func(name string) (fs.File, error)) (models.ManifestFiles, error) {
+	manifest := models.ManifestFiles{}
+	added := map[string]bool{}
+	for _, checksumFile := range artifact.Files {
+		if !strings.HasSuffix(checksumFile.Name, manifestChecksumSuffix) {
+			continue
+		}
+		file, err := open(checksumFile.Name)
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Dir(filepath.ToSlash(checksumFile.Name))
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			a := strings.Fields(line)
+			if strings.HasPrefix(line, "#") || len(a) != 2 || len(a[0]) != sha256.Size*2 || !lib.IsSecureFileName(a[1]) {
+				continue
+			}
+			sum, name := a[0], path.Join(dir, a[1])
+			modelFile := helperFindFile(artifact, filepath.FromSlash(name))
+			if modelFile == nil || added[name] {
+				continue
+			}
+			added[name] = true
+			manifest = append(manifest, &models.ManifestFile{Name: name, Size: modelFile.Size, Sha256: sum})
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(manifest, func(a, b *models.ManifestFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return manifest, nil
+}
+
+// helperSelectManifest returns manifest of given files only
+func helperSelectManifest(manifest models.ManifestFiles, files models.ArtifactFiles) models.ManifestFiles {
+	selected := models.ManifestFiles{}
+	for _, m := range manifest {
+		if slices.ContainsFunc(files, func(f *models.ArtifactFile) bool { return filepath.ToSlash(f.Name) == m.Name }) {
+			selected = append(selected, m)
+		}
+	}
+	return selected
+}
+
+// helperWriteManifest writes manifest in sha256sum format
+func helperWriteManifest(w io.Writer, manifest models.ManifestFiles) error {
+	for _, m := range manifest {
+		if _, err := fmt.Fprintf(w, "%v  %v\n", m.Sha256, m.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The verifyScript is POSIX shell script verifying downloaded artifact files.
+// The files absent in directory are skipped, so it works for individually downloaded files.
+var verifyScript = template.Must(template.New("verify.sh").Parse(`#!/bin/sh
+# Verify downloaded files of artifact {{.ArtifactID}} of repo {{.RepoID}}
+# Usage: sh verify.sh [directory]
+# Exit code is 1, if any file is corrupted or no file found
+cd "${1:-.}" || exit 2
+if command -v sha256sum >/dev/null 2>&1; then
+	sum() { sha256sum "$1" | cut -d ' ' -f 1; }
+elif command -v shasum >/dev/null 2>&1; then
+	sum() { shasum -a 256 "$1" | cut -d ' ' -f 1; }
+elif command -v openssl >/dev/null 2>&1; then
+	sum() { openssl dgst -sha256 -r "$1" | cut -d ' ' -f 1; }
+else
+	echo "sha256sum, shasum or openssl is required" >&2
+	exit 2
+fi
+ok=0
+failed=0
+while read -r expected size name; do
+	if [ ! -f "$name" ]; then
+		continue
+	fi
+	if [ "$(wc -c <"$name" | tr -d ' ')" != "$size" ]; then
+		echo "$name: FAILED size"
+		failed=$((failed + 1))
+	elif [ "$(sum "$name")" != "$expected" ]; then
+		echo "$name: FAILED checksum"
+		failed=$((failed + 1))
+	else
+		echo "$name: OK"
+		ok=$((ok + 1))
+	fi
+done <<'SWAMP_MANIFEST'
+{{range .Files}}{{.Sha256}} {{.Size | printf "%d"}} {{.Name}}
+{{end}}SWAMP_MANIFEST
+echo "$ok verified, $failed failed"
+[ "$failed" -eq 0 ] && [ "$ok" -gt 0 ]
+`))
+
+// helperWriteVerifyScript writes verification script of the artifact manifest
+func helperWriteVerifyScript(w io.Writer, artifact *models.Artifact, manifest models.ManifestFiles) error {
+	return verifyScript.Execute(w, struct {
+		RepoID     models.RepoID
+		ArtifactID models.ArtifactID
+		Files      models.ManifestFiles
+	}{artifact.RepoID, artifact.ArtifactID, manifest})
+}
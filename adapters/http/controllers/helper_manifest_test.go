@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperManifest(t *testing.T) {
+	assert := require.New(t)
+	sum := func(prefix string) string { return prefix + strings.Repeat("0", 60) }
+	fsys := fstest.MapFS{
+		"41a8.sha256sum": {Data: []byte("# comment\n" +
+			sum("6443") + "  bin/app.elf\n" +
+			sum("8e54") + "  readme.txt\n" +
+			"bad line\n" +
+			"1234  readme.txt\n" +
+			sum("0000") + "  ../etc/passwd\n" +
+			sum("1111") + "  missing.bin\n")},
+		"docs/api.sha256sum": {Data: []byte(sum("95d5") + "  index.html\n")},
+	}
+	artifact := &models.Artifact{RepoID: "repo", ArtifactID: "01J2", Files: models.ArtifactFiles{
+		{Name: "readme.txt", Size: 6},
+		{Name: "bin/app.elf", Size: 3},
+		{Name: "docs/index.html", Size: 6},
+		{Name: "41a8.sha256sum", Size: 60},
+		{Name: "docs/api.sha256sum", Size: 30},
+	}}
+
+	manifest, err := helperManifest(artifact, fsys.Open)
+	assert.NoError(err)
+	assert.Equal(models.ManifestFiles{
+		{Name: "bin/app.elf", Size: 3, Sha256: sum("6443")},
+		{Name: "docs/index.html", Size: 6, Sha256: sum("95d5")},
+		{Name: "readme.txt", Size: 6, Sha256: sum("8e54")},
+	}, manifest)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(helperWriteManifest(buf, helperSelectManifest(manifest, helperSelectFiles(artifact.Files, []string{"bin,readme.txt"}))))
+	assert.Equal(sum("6443")+"  bin/app.elf\n"+sum("8e54")+"  readme.txt\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(helperWriteVerifyScript(buf, artifact, manifest))
+	assert.Contains(buf.String(), "# Verify downloaded files of artifact 01J2 of repo repo\n")
+	assert.Contains(buf.String(), "\n"+sum("6443")+" 3 bin/app.elf\n"+sum("95d5")+" 6 docs/index.html\n"+sum("8e54")+" 6 readme.txt\nSWAMP_MANIFEST\n")
+
+	// Missing checksum file
+	delete(fsys, "docs/api.sha256sum")
+	_, err = helperManifest(artifact, fsys.Open)
+	assert.Error(err)
+}
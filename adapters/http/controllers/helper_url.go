@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// The helperBaseURL returns absolute url of the server the request was sent to (e.g. https://swamp.example.com).
+// The X-Forwarded-Proto and X-Forwarded-Host headers of reverse proxy are respected.
+func helperBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	host := r.Host
+	if h := r.Header.Get("X-Forwarded-Host"); h != "" {
+		host, _, _ = strings.Cut(h, ",")
+		host = strings.TrimSpace(host)
+	}
+	return scheme + "://" + host
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelperBaseURL(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		tls     bool
+		headers map[string]string
+		url     string
+	}{
+		{"plain", false, nil, "http://example.com"},
+		{"tls", true, nil, "https://example.com"},
+		{"proxy", false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "swamp.example.com, proxy"}, "https://swamp.example.com"},
+		{"bad proto", true, map[string]string{"X-Forwarded-Proto": "ftp"}, "https://example.com"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/feed.atom", nil)
+			if tC.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tC.headers {
+				r.Header.Set(k, v)
+			}
+			require.Equal(t, tC.url, helperBaseURL(r))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// ManageController serves artifacts management api at /api/v1.
+// The routes are guarded by AuthController.RequireRepo.
+type ManageController struct {
+	log             ports.Logger
+	render          infra.Render
+	repos           domain.Repositories
+	artifactStorage ports.ArtifactStorage
+	bus             ports.EventBus
+}
+
+func NewManageController(log ports.Logger, render infra.Render, repos domain.Repositories, artifactStorage ports.ArtifactStorage, bus ports.EventBus) *ManageController {
+	log = log.With(slog.String("entity", "ManageController"))
+	c := &ManageController{
+		log:             log,
+		render:          render,
+		repos:           repos,
+		artifactStorage: artifactStorage,
+		bus:             bus,
+	}
+	return c
+}
+
+// Delete removes the artifact from storage and database.
+// The artifact is expired first, so it is removed by the next expired artifacts check,
+// if it can not be removed from storage now.
+func (c *ManageController) Delete(w http.ResponseWriter, r *http.Request) {
+	artifact, ok := c.findArtifact(w, r)
+	if !ok {
+		return
+	}
+	log := c.log.With(slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID))
+
+	log.Info("delete artifact")
+	// Expire model first, so background services would not see it broken
+	// and would not re-create it as dangling one
+	expired := !artifact.State.IsExpired()
+	c.expire(artifact)
+	if err := c.repos.Artifact().Update(artifact); err != nil {
+		if err == ports.ErrRecordNotFound { // 404 - removed meanwhile
+			c.renderErrorMessage(w, http.StatusNotFound, "artifact_not_found", "artifact "+artifact.ArtifactID+" not found in repo "+artifact.RepoID)
+			return
+		}
+		log.Error("artifact update failed", slog.Any("err", err))
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+	if err := c.artifactStorage.RemoveArtifact(artifact.Storage, artifact.ArtifactID); err != nil { // 500
+		log.Error("artifact path remove failed", slog.Any("storage", artifact.Storage), slog.Any("err", err))
+		c.bus.Pub(ports.TopicArtifactUpdated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+		if expired {
+			c.bus.Pub(ports.TopicArtifactExpired, ports.Event{artifact.RepoID, artifact.ArtifactID})
+		}
+		c.renderErrorMessage(w, http.StatusInternalServerError, "server_error", "unable remove artifact "+artifact.ArtifactID+", it is expired and will be removed later")
+		return
+	}
+	if err := c.repos.Artifact().Delete(artifact); err != nil {
+		if err == ports.ErrRecordNotFound { // 204 - removed meanwhile by expired artifacts check
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		log.Error("artifact model delete failed", slog.Any("err", err))
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return
+	}
+	c.bus.Pub(ports.TopicArtifactRemoved, ports.Event{artifact.RepoID, artifact.ArtifactID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Expire marks the artifact expired now.
+// It is removed by the next expired artifacts check.
+func (c *ManageController) Expire(w http.ResponseWriter, r *http.Request) {
+	artifact, ok := c.findArtifact(w, r)
+	if !ok {
+		return
+	}
+
+	c.expire(artifact)
+	if c.update(w, artifact) {
+		c.bus.Pub(ports.TopicArtifactExpired, ports.Event{artifact.RepoID, artifact.ArtifactID})
+	}
+}
+
+// Retention changes the artifact retention counted from its creation.
+// The zero retention means the artifact never expires.
+func (c *ManageController) Retention(w http.ResponseWriter, r *http.Request) {
+	artifact, ok := c.findArtifact(w, r)
+	if !ok {
+		return
+	}
+
+	body := struct {
+		Retention string `json:"retention"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil { // 400
+		c.renderError(w, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	retention, err := types.ParseDuration(body.Retention)
+	if err != nil || retention < 0 { // 400
+		c.renderErrorMessage(w, http.StatusBadRequest, "bad_request", "invalid retention "+body.Retention)
+		return
+	}
+
+	wasExpired := artifact.State.IsExpired()
+	artifact.ExpiredAt = artifact.CreatedAt + int64(time.Duration(retention)/time.Second)
+	artifact.State &^= vo.ArtifactIsExpired
+	if artifact.ExpiredAt != artifact.CreatedAt && artifact.ExpiredAt < time.Now().UTC().Unix() {
+		artifact.State |= vo.ArtifactIsExpired
+	}
+	if c.update(w, artifact) && !wasExpired && artifact.State.IsExpired() {
+		c.bus.Pub(ports.TopicArtifactExpired, ports.Event{artifact.RepoID, artifact.ArtifactID})
+	}
+}
+
+// The expire marks the artifact expired now
+func (c *ManageController) expire(artifact *models.Artifact) {
+	expiredAt := time.Now().UTC().Unix() - 1
+	if expiredAt == artifact.CreatedAt {
+		expiredAt-- // equal means never expire
+	}
+	artifact.ExpiredAt = expiredAt
+	artifact.State |= vo.ArtifactIsExpired
+}
+
+// The update saves the artifact and renders it.
+// It returns true, if the artifact saved.
+func (c *ManageController) update(w http.ResponseWriter, artifact *models.Artifact) bool {
+	log := c.log.With(slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID))
+	log.Info("update artifact", slog.Any("expiredAt", artifact.ExpiredAt), slog.Any("state", artifact.State))
+	err := c.repos.Artifact().Update(artifact)
+	if err == ports.ErrRecordNotFound { // 404 - removed meanwhile
+		c.renderErrorMessage(w, http.StatusNotFound, "artifact_not_found", "artifact "+artifact.ArtifactID+" not found in repo "+artifact.RepoID)
+		return false
+	}
+	if err != nil { // 500
+		log.Error("artifact update failed", slog.Any("err", err))
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return false
+	}
+	c.bus.Pub(ports.TopicArtifactUpdated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+
+	// Reload artifact with relationships for response
+	artifact, err = c.repos.Artifact().FindByID(artifact.RepoID, artifact.ArtifactID, ports.WithRelationship(true))
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return true
+	}
+	c.render.JSON(w, http.StatusOK, viewmodels.NewApiArtifact(viewmodels.NewArtifact(artifact)))
+	return true
+}
+
+// The findArtifact returns the artifact of the request.
+// The cross site requests are forbidden, as browsers send basic auth credentials with them.
+func (c *ManageController) findArtifact(w http.ResponseWriter, r *http.Request) (*models.Artifact, bool) {
+	if helperIsCrossSite(r) { // 403
+		c.renderErrorMessage(w, http.StatusForbidden, "cross_site_request", "cross site request")
+		return nil, false
+	}
+	repoID := chi.URLParam(r, "repoID")
+	artifactID := chi.URLParam(r, "artifactID")
+	artifact, err := c.repos.Artifact().FindByID(repoID, artifactID)
+	if err == ports.ErrRecordNotFound { // 404
+		c.renderErrorMessage(w, http.StatusNotFound, "artifact_not_found", "artifact "+artifactID+" not found in repo "+repoID)
+		return nil, false
+	}
+	if err != nil { // 500
+		c.renderError(w, http.StatusInternalServerError, "server_error", err)
+		return nil, false
+	}
+	return artifact, true
+}
+
+func (c *ManageController) renderError(w http.ResponseWriter, status int, code string, err error) {
+	c.renderErrorMessage(w, status, code, err.Error())
+}
+
+func (c *ManageController) renderErrorMessage(w http.ResponseWriter, status int, code string, message string) {
+	c.render.JSON(w, status, viewmodels.NewApiError(code, message))
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/adapters/repository"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// testRepositories returns repositories over in memory database
+func testRepositories(t *testing.T, fs afero.Fs) domain.Repositories {
+	assert := require.New(t)
+	db, closeDb, err := infra.NewDatabase(slog.Default(), infra.DriverSqlite, infra.SourceSqliteInMemory)
+	assert.NoError(err)
+	t.Cleanup(closeDb)
+	assert.NoError(db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFile), new(models.ArtifactComponent)))
+	rr, err := repository.NewRepoRepository(db, fs)
+	assert.NoError(err)
+	ar, err := repository.NewArtifactRepository(db, fs)
+	assert.NoError(err)
+	return repository.NewRepositories(rr, ar)
+}
+
+// TestManageController:
+//   - The cross site requests are forbidden
+//   - Delete removes artifact from storage and database
+//   - Delete failed to remove artifact from storage keeps it expired
+//   - Expire and Retention expire artifact
+//   - Each change is signaled
+func TestManageController(t *testing.T) {
+	assert := require.New(t)
+	log := slog.Default()
+	bus := infra.NewEventBus()
+	defer bus.Shutdown()
+
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{"/input/repo1", "/storage/repo1"} {
+		assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+	}
+	repos := testRepositories(t, fs)
+	rr, ar := repos.Repo(), repos.Artifact()
+	assert.NoError(rr.Create(&models.Repo{RepoID: "repo1", Name: "Repo1", Input: "/input/repo1", Storage: "/storage/repo1"}))
+	createdAt := time.Now().Add(-time.Hour).Unix()
+	for _, artifactID := range []string{"a1", "a2", "a3", "a4"} {
+		assert.NoError(afero.WriteFile(fs, "/storage/repo1/"+artifactID+"/file.bin", []byte("data"), 0o644))
+		assert.NoError(ar.Create(&models.Artifact{RepoID: "repo1", ArtifactID: artifactID, Storage: "/storage/repo1", Size: 4, CreatedAt: createdAt, ExpiredAt: createdAt, Checksum: "0123456789abcdef"}))
+	}
+
+	newRouter := func(fs afero.Fs) http.Handler {
+		storage, err := adapters.NewBasicArtifactStorageAdapter(log, fs)
+		assert.NoError(err)
+		c := NewManageController(log, infra.NewRender(fstest.MapFS{}, ""), repos, storage, bus)
+		router := chi.NewRouter()
+		router.Delete("/api/v1/repos/{repoID}/artifacts/{artifactID}", c.Delete)
+		router.Post("/api/v1/repos/{repoID}/artifacts/{artifactID}/expire", c.Expire)
+		router.Put("/api/v1/repos/{repoID}/artifacts/{artifactID}/retention", c.Retention)
+		return router
+	}
+	router := newRouter(fs)
+	do := func(router http.Handler, method, artifactID, suffix, body string, headers ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v1/repos/repo1/artifacts/"+artifactID+suffix, strings.NewReader(body))
+		for x := 0; x+1 < len(headers); x += 2 {
+			r.Header.Set(headers[x], headers[x+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	events := map[ports.Topic]chan ports.Event{}
+	for _, topic := range []ports.Topic{ports.TopicArtifactRemoved, ports.TopicArtifactExpired} {
+		events[topic] = bus.Sub(topic)
+		defer bus.Unsub(events[topic])
+	}
+	signaled := func(topic ports.Topic, artifactID models.ArtifactID) {
+		select {
+		case event := <-events[topic]:
+			assert.Equal(ports.Event{"repo1", artifactID}, event, topic)
+		case <-time.After(5 * time.Second):
+			assert.FailNow("no event", topic)
+		}
+	}
+	notSignaled := func() {
+		for topic, ch := range events {
+			select {
+			case event := <-ch:
+				assert.Fail("unexpected event", topic, event)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	exists := func(artifactID models.ArtifactID) (bool, bool) {
+		_, err := ar.FindByID("repo1", artifactID)
+		stored, _ := afero.DirExists(fs, "/storage/repo1/"+artifactID)
+		return err == nil, stored
+	}
+	state := func(artifactID models.ArtifactID) bool {
+		artifact, err := ar.FindByID("repo1", artifactID)
+		assert.NoError(err)
+		return artifact.State.IsExpired()
+	}
+
+	// The cross site requests are forbidden
+	for _, headers := range [][]string{{"Sec-Fetch-Site", "cross-site"}, {"Origin", "http://evil.example.com"}} {
+		w := do(router, "DELETE", "a1", "", "", headers...)
+		assert.Equal(http.StatusForbidden, w.Code)
+		assert.Contains(w.Body.String(), "cross_site_request")
+		assert.Equal(http.StatusForbidden, do(router, "POST", "a1", "/expire", "", headers...).Code)
+		assert.Equal(http.StatusForbidden, do(router, "PUT", "a1", "/retention", `{"retention":"1s"}`, headers...).Code)
+	}
+	inDB, stored := exists("a1")
+	assert.True(inDB && stored)
+	assert.False(state("a1"))
+	notSignaled()
+
+	// Delete
+	assert.Equal(http.StatusNoContent, do(router, "DELETE", "a1", "", "").Code)
+	inDB, stored = exists("a1")
+	assert.False(inDB, "model deleted")
+	assert.False(stored, "storage removed")
+	signaled(ports.TopicArtifactRemoved, "a1")
+	assert.Equal(http.StatusNotFound, do(router, "DELETE", "a1", "", "").Code)
+
+	// Delete failed to remove storage
+	w := do(newRouter(afero.NewReadOnlyFs(fs)), "DELETE", "a2", "", "")
+	assert.Equal(http.StatusInternalServerError, w.Code)
+	assert.Contains(w.Body.String(), "will be removed later")
+	inDB, stored = exists("a2")
+	assert.True(inDB && stored)
+	assert.True(state("a2"), "expired to be removed later")
+	signaled(ports.TopicArtifactExpired, "a2")
+	notSignaled()
+
+	// Expire
+	assert.Equal(http.StatusOK, do(router, "POST", "a3", "/expire", "").Code)
+	assert.True(state("a3"))
+	signaled(ports.TopicArtifactExpired, "a3")
+
+	// Retention
+	assert.Equal(http.StatusBadRequest, do(router, "PUT", "a4", "/retention", `{"retention":"x"}`).Code)
+	assert.Equal(http.StatusOK, do(router, "PUT", "a4", "/retention", `{"retention":"2h"}`).Code)
+	assert.False(state("a4"))
+	notSignaled()
+	assert.Equal(http.StatusOK, do(router, "PUT", "a4", "/retention", `{"retention":"1m"}`).Code)
+	assert.True(state("a4"))
+	signaled(ports.TopicArtifactExpired, "a4")
+	assert.Equal(http.StatusOK, do(router, "PUT", "a4", "/retention", `{"retention":"30m"}`).Code)
+	assert.True(state("a4"))
+	notSignaled()
+	assert.Equal(http.StatusOK, do(router, "PUT", "a4", "/retention", `{"retention":"0"}`).Code)
+	assert.False(state("a4"), "never expires")
+	notSignaled()
+}
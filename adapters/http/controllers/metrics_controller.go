@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsController serves prometheus metrics at /metrics
+type MetricsController struct {
+	log     ports.Logger
+	render  infra.Render
+	handler http.Handler
+}
+
+func NewMetricsController(log ports.Logger, render infra.Render, gatherer prometheus.Gatherer) *MetricsController {
+	log = log.With(slog.String("entity", "MetricsController"))
+	c := &MetricsController{
+		log:    log,
+		render: render,
+		handler: promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(log.Handler(), slog.LevelError),
+			ErrorHandling: promhttp.ContinueOnError,
+		}),
+	}
+	return c
+}
+
+func (c *MetricsController) Index(w http.ResponseWriter, r *http.Request) {
+	c.handler.ServeHTTP(w, r)
+}
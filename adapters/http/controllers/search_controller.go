@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+type SearchController struct {
+	log    ports.Logger
+	render infra.Render
+	repos  domain.Repositories
+}
+
+func NewSearchController(log ports.Logger, render infra.Render, repos domain.Repositories) *SearchController {
+	log = log.With(slog.String("entity", "SearchController"))
+	c := &SearchController{
+		log:    log,
+		render: render,
+		repos:  repos,
+	}
+	return c
+}
+
+// The searchMaxFiles is the max number of files found by name or build id,
+// and the max number of components found by name
+const searchMaxFiles = 1000
+
+// Index searches artifacts by file name, build id and component.
+// The global search query (q) searches by all of them.
+func (c *SearchController) Index(w http.ResponseWriter, r *http.Request) {
+	errors := []string{}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	fileName := strings.TrimSpace(r.URL.Query().Get("file"))
+	buildID := strings.TrimSpace(r.URL.Query().Get("build-id"))
+	component := strings.TrimSpace(r.URL.Query().Get("component"))
+	version := strings.TrimSpace(r.URL.Query().Get("version"))
+	if query != "" {
+		fileName, buildID, component = query, query, query
+	}
+
+	// Show results of readable repos only
+	repos, err := c.repos.Repo().FindAll()
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+	repos = helperReadableRepos(r, repos)
+	repoIDs := []string{}
+	for _, repo := range repos {
+		repoIDs = append(repoIDs, repo.RepoID)
+	}
+
+	nameFiles := []*models.ArtifactFile{}
+	if fileName != "" {
+		var err error
+		// The results of not readable repos shall not take the limit
+		nameFiles, err = c.repos.Artifact().FindAllFiles(ports.WithFileName(fileName), ports.WithRepoIDs(repoIDs), ports.Limit(searchMaxFiles))
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	nameFilesLimited := len(nameFiles) == searchMaxFiles
+
+	files := []*models.ArtifactFile{}
+	if buildID != "" {
+		var err error
+		files, err = c.repos.Artifact().FindAllFiles(ports.WithBuildID(buildID), ports.WithRepoIDs(repoIDs), ports.Limit(searchMaxFiles))
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	filesLimited := len(files) == searchMaxFiles
+
+	components := []*models.ArtifactComponent{}
+	componentsLimited := false
+	if component != "" {
+		constraint, err := types.ParseVersionConstraint(version)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		all, err := c.repos.Artifact().FindAllComponents(ports.WithComponentName(component), ports.WithRepoIDs(repoIDs), ports.Limit(searchMaxFiles))
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		componentsLimited = len(all) == searchMaxFiles
+		for _, c := range all {
+			if constraint.Match(c.Version) {
+				components = append(components, c)
+			}
+		}
+	}
+
+	perPage := 50
+	nameFilesPages := (len(nameFiles) + perPage - 1) / perPage
+	nameFiles, nameFilesPage := helperPagination(r, nameFiles, perPage)
+	files, filesPage := helperPagination(r, files, perPage)
+	components, componentsPage := helperPagination(r, components, perPage)
+
+	data := struct {
+		Errors            []string
+		Query             string
+		FileName          string
+		NameFiles         []*models.ArtifactFile
+		NameFilesPage     int
+		NameFilesPages    int
+		NameFilesPrev     int
+		NameFilesNext     int
+		NameFilesLimited  bool
+		BuildID           string
+		Files             []*models.ArtifactFile
+		FilesPage         int
+		FilesLimited      bool
+		Component         string
+		Version           string
+		Components        []*models.ArtifactComponent
+		ComponentsPage    int
+		ComponentsLimited bool
+	}{
+		Errors:            errors,
+		Query:             query,
+		FileName:          fileName,
+		NameFiles:         nameFiles,
+		NameFilesPage:     nameFilesPage,
+		NameFilesPages:    nameFilesPages,
+		NameFilesPrev:     nameFilesPage - 1,
+		NameFilesNext:     nameFilesPage + 1,
+		NameFilesLimited:  nameFilesLimited,
+		BuildID:           buildID,
+		Files:             files,
+		FilesPage:         filesPage,
+		FilesLimited:      filesLimited,
+		Component:         component,
+		Version:           version,
+		Components:        components,
+		ComponentsPage:    componentsPage,
+		ComponentsLimited: componentsLimited,
+	}
+
+	c.render.HTML(w, http.StatusOK, "search", data)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSearchController:
+//   - The not readable repo has more matching files and components than search limit
+//   - The results of readable repo are found anyway
+func TestSearchController(t *testing.T) {
+	assert := require.New(t)
+	fs := afero.NewMemMapFs()
+	repos := testRepositories(t, fs)
+	createdAt := time.Now().Unix()
+	for _, repo := range []*models.Repo{
+		{RepoID: "public"},
+		{RepoID: "secret", Access: models.Access{"alice": vo.PermissionRead}},
+	} {
+		repo.Name, repo.Input, repo.Storage = repo.RepoID, "/input/"+repo.RepoID, "/storage/"+repo.RepoID
+		for _, dir := range []string{repo.Input, repo.Storage} {
+			assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+		}
+		assert.NoError(repos.Repo().Create(repo))
+
+		count := 1
+		if repo.RepoID == "secret" {
+			count = searchMaxFiles
+		}
+		artifact := &models.Artifact{RepoID: repo.RepoID, ArtifactID: "a1", Storage: repo.Storage, Size: 1, CreatedAt: createdAt, ExpiredAt: createdAt, Checksum: "0123456789abcdef"}
+		for x := 0; x < count; x++ {
+			artifact.Files = append(artifact.Files, &models.ArtifactFile{Name: fmt.Sprintf("file%v.bin", x), Size: 1, BuildID: "abcd"})
+			artifact.Components = append(artifact.Components, &models.ArtifactComponent{File: "sbom.json", Index: x, Name: "zlib", Version: "1.3"})
+		}
+		assert.NoError(repos.Artifact().Create(artifact))
+	}
+
+	render := infra.NewRender(fstest.MapFS{"templates/search.html": {Data: []byte(
+		`{{range .NameFiles}}{{.RepoID}} {{end}}{{.NameFilesLimited}}|{{range .Files}}{{.RepoID}} {{end}}{{.FilesLimited}}|{{range .Components}}{{.RepoID}} {{end}}{{.ComponentsLimited}}`,
+	)}}, "")
+	c := NewSearchController(slog.Default(), render, repos)
+	for query, expected := range map[string]string{
+		"?file=file0.bin": "public false|false|false",
+		"?build-id=abcd":  "false|public false|false",
+		"?component=zlib": "false|false|public false",
+	} {
+		w := httptest.NewRecorder()
+		c.Index(w, httptest.NewRequest("GET", "/search"+query, nil))
+		assert.Equal(http.StatusOK, w.Code, query)
+		assert.Equal(expected, w.Body.String(), query)
+	}
+}
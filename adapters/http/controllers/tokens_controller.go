@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/go-chi/chi/v5"
+)
+
+// TokensController manages scoped api tokens at /admin/tokens
+type TokensController struct {
+	log            ports.Logger
+	render         infra.Render
+	repoRepository domain.RepoRepository
+	tokens         domain.ApiTokenRepository
+}
+
+func NewTokensController(log ports.Logger, render infra.Render, repoRepository domain.RepoRepository, tokens domain.ApiTokenRepository) *TokensController {
+	log = log.With(slog.String("entity", "TokensController"))
+	c := &TokensController{
+		log:            log,
+		render:         render,
+		repoRepository: repoRepository,
+		tokens:         tokens,
+	}
+	return c
+}
+
+func (c *TokensController) Index(w http.ResponseWriter, r *http.Request) {
+	c.index(w, http.StatusOK, nil, "")
+}
+
+// Create creates token by form values name, repo (empty for all), scope and expires (empty for never).
+// The token secret is shown once.
+func (c *TokensController) Create(w http.ResponseWriter, r *http.Request) {
+	if helperIsCrossSite(r) {
+		c.index(w, http.StatusForbidden, []string{"cross site request"}, "")
+		return
+	}
+
+	now := time.Now().UTC()
+	name := strings.TrimSpace(r.FormValue("name"))
+	repoID := strings.TrimSpace(r.FormValue("repo"))
+	token, secret, err := func() (*models.ApiToken, string, error) {
+		scope, err := models.ParseApiTokenScope(r.FormValue("scope"))
+		if err != nil {
+			return nil, "", err
+		}
+		expiresAt := int64(0)
+		if s := strings.TrimSpace(r.FormValue("expires")); s != "" {
+			d, err := types.ParseDuration(s)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid expires %q: %w", s, err)
+			}
+			if d != 0 {
+				expiresAt = now.Add(time.Duration(d)).Unix()
+			}
+		}
+		if repoID != "" {
+			if _, err := c.repoRepository.FindByID(repoID); err != nil {
+				return nil, "", fmt.Errorf("repo %v: %w", repoID, err)
+			}
+		}
+		return models.NewApiToken(name, repoID, scope, now.Unix(), expiresAt)
+	}()
+	if err != nil {
+		c.index(w, http.StatusBadRequest, []string{err.Error()}, "")
+		return
+	}
+	if err := c.tokens.Create(token); err != nil {
+		c.log.Error("unable to create api token", slog.Any("err", err))
+		c.index(w, http.StatusInternalServerError, []string{err.Error()}, "")
+		return
+	}
+
+	c.log.Info("api token created", slog.String("principal", helperPrincipal(r).Name), slog.String("tokenID", token.TokenID), slog.String("name", token.Name), slog.String("repoID", token.RepoID), slog.String("scope", token.ScopeName()))
+	c.index(w, http.StatusCreated, nil, secret)
+}
+
+func (c *TokensController) Revoke(w http.ResponseWriter, r *http.Request) {
+	if helperIsCrossSite(r) {
+		c.index(w, http.StatusForbidden, []string{"cross site request"}, "")
+		return
+	}
+
+	tokenID := chi.URLParam(r, "tokenID")
+	if err := c.tokens.Revoke(tokenID, time.Now().UTC().Unix()); err != nil {
+		c.log.Error("unable to revoke api token", slog.String("tokenID", tokenID), slog.Any("err", err))
+		c.index(w, http.StatusNotFound, []string{"token " + tokenID + ": " + err.Error()}, "")
+		return
+	}
+
+	c.log.Info("api token revoked", slog.String("principal", helperPrincipal(r).Name), slog.String("tokenID", tokenID))
+	http.Redirect(w, r, "/admin/tokens", http.StatusSeeOther)
+}
+
+func (c *TokensController) index(w http.ResponseWriter, status int, errors []string, secret string) {
+	if errors == nil {
+		errors = []string{}
+	}
+	tokens, err := c.tokens.FindAll()
+	if err != nil {
+		c.log.Error("unable to fetch api tokens", slog.Any("err", err))
+		errors = append(errors, err.Error())
+	}
+
+	data := struct {
+		Errors []string
+		Secret string
+		Tokens []*viewmodels.ApiToken
+	}{
+		Errors: errors,
+		Secret: secret,
+		Tokens: viewmodels.NewApiTokens(tokens, time.Now().UTC().Unix()),
+	}
+	c.render.HTML(w, status, "admin/tokens", data)
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// webhooksHistory is the number of deliveries shown at admin page
+const webhooksHistory = 200
+
+// WebhooksController serves webhook deliveries history at /admin/webhooks
+type WebhooksController struct {
+	log        ports.Logger
+	render     infra.Render
+	deliveries domain.WebhookDeliveryRepository
+}
+
+func NewWebhooksController(log ports.Logger, render infra.Render, deliveries domain.WebhookDeliveryRepository) *WebhooksController {
+	log = log.With(slog.String("entity", "WebhooksController"))
+	c := &WebhooksController{
+		log:        log,
+		render:     render,
+		deliveries: deliveries,
+	}
+	return c
+}
+
+func (c *WebhooksController) Index(w http.ResponseWriter, r *http.Request) {
+	flags := []interface{}{ports.Limit(webhooksHistory)}
+	repoID := r.URL.Query().Get("repo")
+	if repoID != "" {
+		flags = append(flags, ports.WithRepoID(repoID))
+	}
+
+	errors := []string{}
+	deliveries, err := c.deliveries.FindAll(flags...)
+	if err != nil {
+		c.log.Error("unable to fetch webhook deliveries", slog.Any("err", err))
+		errors = append(errors, err.Error())
+	}
+
+	data := struct {
+		Errors     []string
+		Repo       string
+		Deliveries []*viewmodels.WebhookDelivery
+	}{
+		Errors:     errors,
+		Repo:       repoID,
+		Deliveries: viewmodels.NewWebhookDeliveries(deliveries),
+	}
+	c.render.HTML(w, http.StatusOK, "admin/webhooks", data)
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudcopper/swamp/infra/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// The metricsMiddleware accounts requests, response bytes and downloaded bytes per repo.
+// The route pattern is used as label, so the cardinality stays low.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "none"
+		repoID := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+			repoID = rctx.URLParam("repoID")
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.HttpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		metrics.HttpResponseBytesTotal.WithLabelValues(route).Add(float64(ww.BytesWritten()))
+		if repoID != "" && isDownloadRequest(r) && status < 300 {
+			metrics.DownloadBytesTotal.WithLabelValues(repoID).Add(float64(ww.BytesWritten()))
+		}
+	})
+}
@@ -1,25 +1,125 @@
 package http
 
 import (
+	"net"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/cloudcopper/swamp/domain/models"
 	"github.com/cloudcopper/swamp/ports"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	slogchi "github.com/samber/slog-chi"
 )
 
-func NewRouter(log ports.Logger) ports.Router {
+// NewRouter creates router with common middlewares.
+// The client ip is taken from forwarded headers of trusted proxies of limits only.
+func NewRouter(log ports.Logger, limits *models.Limits) ports.Router {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(realIP(limits))
 	r.Use(slogchi.New(log))
+	r.Use(metricsMiddleware)
 	r.Use(middleware.Recoverer)
+	r.Use(middleware.GetHead)
 	if os.Getenv("GO_ENV") != "development" {
-		r.Use(middleware.Timeout(10 * time.Second))
+		r.Use(timeout(10*time.Second, isLongRequest))
 	}
 
 	return r
 }
+
+// isLongRequest returns true for requests which may legitimately
+// last longer than request timeout - downloads of files and archives, event stream
+func isLongRequest(r *http.Request) bool {
+	return r.URL.Path == "/events" || isDownloadRequest(r) || isArchiveChecksumRequest(r)
+}
+
+// isArchiveChecksumRequest returns true for checksums of archives,
+// as the archive might be generated to get it
+func isArchiveChecksumRequest(r *http.Request) bool {
+	path, ok := strings.CutSuffix(r.URL.Path, ".sha256")
+	if !ok {
+		return false
+	}
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDownloadRequest returns true for downloads of files and archives
+func isDownloadRequest(r *http.Request) bool {
+	path := r.URL.Path
+	if strings.Contains(path, "/file/") || strings.Contains(path, "/files/") {
+		return true
+	}
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// The archiveSuffixes are the suffixes of artifact archive downloads
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tar.zst", ".tar.xz"}
+
+// realIP is the middleware replacing remote address by client ip forwarded by trusted proxy.
+// The X-Forwarded-For is walked from right to left, as the proxies append to it,
+// and the first not trusted address is the client. Otherwise it is X-Real-IP.
+// The forwarded headers of not trusted peers are ignored, as any client could fake them.
+func realIP(limits *models.Limits) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				peer = r.RemoteAddr
+			}
+			if !limits.IsTrustedProxy(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ip := ""
+			forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for x := len(forwarded) - 1; x >= 0; x-- {
+				addr := strings.TrimSpace(forwarded[x])
+				if net.ParseIP(addr) == nil {
+					break
+				}
+				ip = addr
+				if !limits.IsTrustedProxy(addr) {
+					break
+				}
+			}
+			if ip == "" {
+				if addr := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(addr) != nil {
+					ip = addr
+				}
+			}
+			if ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// timeout is the middleware.Timeout not applied to requests matching skip
+func timeout(d time.Duration, skip func(r *http.Request) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := middleware.Timeout(d)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
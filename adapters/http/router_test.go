@@ -0,0 +1,65 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRouterRealIP:
+//   - The client spoofing X-Forwarded-For is keyed by its peer address
+//     and does not get new limiter bucket by each request
+//   - The client ip forwarded by trusted proxy is used
+func TestRouterRealIP(t *testing.T) {
+	assert := require.New(t)
+	limits := &models.Limits{TrustedProxies: []string{"10.0.0.1"}}
+	assert.NoError(limits.Compile())
+	limiter := infra.NewLimiter(&models.Limits{Rate: 0.01, Burst: 1})
+
+	router := NewRouter(slog.New(slog.NewTextHandler(io.Discard, nil)), limits)
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		_, release, delay := limiter.Download(context.Background(), "repo", nil, "ip:"+host, w, false)
+		if delay > 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+		w.Write([]byte(host))
+	})
+	get := func(remote string, forwarded string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("192.168.0.1:1000", "1.1.1.1")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("192.168.0.1", w.Body.String(), "not trusted peer")
+	assert.Equal(http.StatusTooManyRequests, get("192.168.0.1:1001", "2.2.2.2").Code, "spoofed ip")
+	assert.Equal(http.StatusTooManyRequests, get("192.168.0.1:1002", "").Code)
+
+	w = get("10.0.0.1:1000", "1.1.1.1, 3.3.3.3")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("3.3.3.3", w.Body.String(), "appended by trusted proxy")
+	assert.Equal(http.StatusTooManyRequests, get("10.0.0.1:1000", "4.4.4.4, 3.3.3.3").Code, "spoofed ip behind proxy")
+	w = get("10.0.0.1:1000", "4.4.4.4")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("4.4.4.4", w.Body.String())
+}
@@ -0,0 +1,51 @@
+package viewmodels
+
+import (
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+)
+
+type ApiToken struct {
+	TokenID    models.ApiTokenID
+	Name       string
+	Hint       string
+	RepoID     models.RepoID
+	Scope      string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	IsValid    bool
+}
+
+func NewApiToken(token *models.ApiToken, now int64) *ApiToken {
+	unix := func(t int64) *time.Time {
+		if t == 0 {
+			return nil
+		}
+		v := time.Unix(t, 0)
+		return &v
+	}
+	t := &ApiToken{
+		TokenID:    token.TokenID,
+		Name:       token.Name,
+		Hint:       token.Hint,
+		RepoID:     token.RepoID,
+		Scope:      token.ScopeName(),
+		CreatedAt:  time.Unix(token.CreatedAt, 0),
+		ExpiresAt:  unix(token.ExpiresAt),
+		LastUsedAt: unix(token.LastUsedAt),
+		RevokedAt:  unix(token.RevokedAt),
+		IsValid:    token.IsValid(now),
+	}
+	return t
+}
+
+func NewApiTokens(tokens []*models.ApiToken, now int64) []*ApiToken {
+	t := []*ApiToken{}
+	for _, token := range tokens {
+		t = append(t, NewApiToken(token, now))
+	}
+	return t
+}
@@ -0,0 +1,293 @@
+package viewmodels
+
+import (
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// ApiRepo is the json representation of repo in /api/v1
+type ApiRepo struct {
+	RepoID         models.RepoID     `json:"id"`
+	Name           string            `json:"name"`
+	Description    string            `json:"description"`
+	Retention      string            `json:"retention"`
+	Size           int64             `json:"size"`
+	ArtifactsCount int               `json:"artifacts_count"`
+	Meta           map[string]string `json:"meta"`
+}
+
+// ApiArtifact is the json representation of artifact in /api/v1.
+// The files and components are listed in artifact details only.
+type ApiArtifact struct {
+	RepoID     models.RepoID     `json:"repo_id"`
+	ArtifactID models.ArtifactID `json:"id"`
+	Size       int64             `json:"size"`
+	State      string            `json:"state"`
+	CreatedAt  int64             `json:"created_at"`
+	ExpiredAt  int64             `json:"expired_at,omitempty"`
+	Checksum   string            `json:"checksum"`
+	Meta       map[string]string `json:"meta"`
+	Files      []*ApiFile        `json:"files,omitempty"`
+	Components []*ApiComponent   `json:"components,omitempty"`
+}
+
+type ApiFile struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	State    string `json:"state"`
+	MimeType string `json:"mime_type,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	BuildID  string `json:"build_id,omitempty"`
+	Entries  int    `json:"entries,omitempty"`
+}
+
+// ApiManifest is the json representation of artifact verification manifest
+type ApiManifest struct {
+	RepoID     models.RepoID      `json:"repo_id"`
+	ArtifactID models.ArtifactID  `json:"artifact_id"`
+	Files      []*ApiManifestFile `json:"files"`
+}
+
+type ApiManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// ApiArtifactDiff is the json representation of difference of artifact (to) from other artifact (from).
+// The artifacts are given without files and components.
+type ApiArtifactDiff struct {
+	From      *ApiArtifact   `json:"from"`
+	To        *ApiArtifact   `json:"to"`
+	SizeDelta int64          `json:"size_delta"`
+	Files     []*ApiFileDiff `json:"files"`
+	Unchanged int            `json:"unchanged_files"`
+	Meta      []*ApiMetaDiff `json:"meta"`
+}
+
+type ApiFileDiff struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	FromSize   int64  `json:"from_size"`
+	ToSize     int64  `json:"to_size"`
+	SizeDelta  int64  `json:"size_delta"`
+	FromSha256 string `json:"from_sha256,omitempty"`
+	ToSha256   string `json:"to_sha256,omitempty"`
+}
+
+type ApiMetaDiff struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+type ApiComponent struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Purl    string `json:"purl,omitempty"`
+	License string `json:"license,omitempty"`
+	File    string `json:"file"`
+}
+
+// ApiPage is the paginated list of items
+type ApiPage[T any] struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+	Items   []T `json:"items"`
+}
+
+// ApiError is the error object returned by /api/v1 with non 2xx status
+type ApiError struct {
+	Error ApiErrorDetails `json:"error"`
+}
+
+type ApiErrorDetails struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewApiError(code string, message string) *ApiError {
+	return &ApiError{ApiErrorDetails{code, message}}
+}
+
+// ApiEvent is the json representation of event bus event in /events stream
+type ApiEvent struct {
+	Topic      string `json:"topic"`
+	RepoID     string `json:"repo_id"`
+	ArtifactID string `json:"artifact_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// NewApiEvent converts bus event to api event.
+// The event is expected to start with repo id,
+// followed by artifact id (or checksum file and reason for rejected artifacts).
+func NewApiEvent(topic ports.Topic, event ports.Event) *ApiEvent {
+	e := &ApiEvent{Topic: topic}
+	if len(event) > 0 {
+		e.RepoID = event[0]
+	}
+	switch {
+	case topic == ports.TopicRejectedRepoArtifact && len(event) > 2:
+		e.Reason = event[2]
+	case len(event) > 1:
+		e.ArtifactID = event[1]
+	}
+	return e
+}
+
+// ApiWebhookPayload is the json body posted to webhooks.
+// The artifact details are absent for removed artifact.
+type ApiWebhookPayload struct {
+	Event      string       `json:"event"`
+	DeliveryID string       `json:"delivery_id"`
+	Timestamp  int64        `json:"timestamp"`
+	RepoID     string       `json:"repo_id"`
+	ArtifactID string       `json:"artifact_id"`
+	Artifact   *ApiArtifact `json:"artifact,omitempty"`
+}
+
+// ApiState returns text representation of the state
+func ApiState(state vo.ArtifactState) string {
+	switch {
+	case state.IsBroken() && state.IsExpired():
+		return "broken,expired"
+	case state.IsBroken():
+		return "broken"
+	case state.IsExpired():
+		return "expired"
+	}
+	return "ok"
+}
+
+func NewApiRepo(repo *Repo) *ApiRepo {
+	r := &ApiRepo{
+		RepoID:         repo.RepoID,
+		Name:           repo.Name,
+		Description:    repo.Description,
+		Retention:      repo.Retention.String(),
+		Size:           int64(repo.Size),
+		ArtifactsCount: repo.ArtifactsCount,
+		Meta:           map[string]string{},
+	}
+	for _, m := range repo.Meta {
+		r.Meta[m.Key] = m.Value
+	}
+	return r
+}
+
+func NewApiRepos(repos []*Repo) []*ApiRepo {
+	a := []*ApiRepo{}
+	for _, repo := range repos {
+		a = append(a, NewApiRepo(repo))
+	}
+	return a
+}
+
+func NewApiArtifact(artifact *Artifact) *ApiArtifact {
+	a := &ApiArtifact{
+		RepoID:     artifact.RepoID,
+		ArtifactID: artifact.ArtifactID,
+		Size:       int64(artifact.Size),
+		State:      ApiState(artifact.State),
+		CreatedAt:  artifact.CreatedAt.Unix(),
+		Checksum:   artifact.Checksum,
+		Meta:       map[string]string{},
+	}
+	if t := time.Time(artifact.ExpiredAt); !t.IsZero() {
+		a.ExpiredAt = t.Unix()
+	}
+	for _, m := range artifact.Meta {
+		a.Meta[m.Key] = m.Value
+	}
+	for _, f := range artifact.Files {
+		a.Files = append(a.Files, &ApiFile{
+			Name:     f.Name,
+			Size:     int64(f.Size),
+			State:    ApiState(f.State),
+			MimeType: f.MimeType,
+			Arch:     f.Arch,
+			BuildID:  f.BuildID,
+			Entries:  f.Entries,
+		})
+	}
+	for _, c := range artifact.Components {
+		a.Components = append(a.Components, &ApiComponent{
+			Name:    c.Name,
+			Version: c.Version,
+			Purl:    c.Purl,
+			License: c.License,
+			File:    c.File,
+		})
+	}
+	return a
+}
+
+func NewApiArtifacts(artifacts []*Artifact) []*ApiArtifact {
+	a := []*ApiArtifact{}
+	for _, artifact := range artifacts {
+		a = append(a, NewApiArtifact(artifact))
+	}
+	return a
+}
+
+func NewApiManifest(repoID models.RepoID, artifactID models.ArtifactID, files models.ManifestFiles) *ApiManifest {
+	m := &ApiManifest{RepoID: repoID, ArtifactID: artifactID, Files: []*ApiManifestFile{}}
+	for _, f := range files {
+		m.Files = append(m.Files, &ApiManifestFile{Name: f.Name, Size: int64(f.Size), Sha256: f.Sha256})
+	}
+	return m
+}
+
+func NewApiArtifactDiff(diff *models.ArtifactDiff) *ApiArtifactDiff {
+	d := &ApiArtifactDiff{
+		From:      NewApiArtifact(NewArtifact(diff.From)),
+		To:        NewApiArtifact(NewArtifact(diff.To)),
+		SizeDelta: diff.SizeDelta,
+		Files:     []*ApiFileDiff{},
+		Unchanged: diff.Unchanged,
+		Meta:      []*ApiMetaDiff{},
+	}
+	d.From.Files, d.From.Components = nil, nil
+	d.To.Files, d.To.Components = nil, nil
+	for _, f := range diff.Files {
+		d.Files = append(d.Files, &ApiFileDiff{
+			Name:       f.Name,
+			Status:     f.Status,
+			FromSize:   int64(f.FromSize),
+			ToSize:     int64(f.ToSize),
+			SizeDelta:  f.SizeDelta,
+			FromSha256: f.FromSha256,
+			ToSha256:   f.ToSha256,
+		})
+	}
+	for _, m := range diff.Meta {
+		d.Meta = append(d.Meta, &ApiMetaDiff{Key: m.Key, Status: m.Status, From: m.FromValue, To: m.ToValue})
+	}
+	return d
+}
+
+// ApiHealth is the json representation of health checks at /healthz and /readyz.
+// The status is degraded, if any check is degraded,
+// or pending, if any check is pending.
+type ApiHealth struct {
+	Status string              `json:"status"`
+	Checks []ports.HealthCheck `json:"checks"`
+}
+
+func NewApiHealth(checks []ports.HealthCheck) *ApiHealth {
+	h := &ApiHealth{Status: ports.HealthOk, Checks: checks}
+	for _, check := range checks {
+		switch {
+		case check.Status == ports.HealthDegraded:
+			h.Status = ports.HealthDegraded
+		case check.Status == ports.HealthPending && h.Status == ports.HealthOk:
+			h.Status = ports.HealthPending
+		}
+	}
+	return h
+}
@@ -0,0 +1,59 @@
+package viewmodels
+
+import (
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/lib/types"
+)
+
+type ArtifactDiff struct {
+	From      *Artifact
+	To        *Artifact
+	SizeDelta string
+	Files     []*FileDiff
+	Unchanged int
+	Meta      []*models.MetaDiff
+}
+
+type FileDiff struct {
+	Name       string
+	Status     models.DiffStatus
+	FromSize   types.Size
+	ToSize     types.Size
+	SizeDelta  string
+	FromSha256 string
+	ToSha256   string
+}
+
+func NewArtifactDiff(diff *models.ArtifactDiff) *ArtifactDiff {
+	d := &ArtifactDiff{
+		From:      NewArtifact(diff.From),
+		To:        NewArtifact(diff.To),
+		SizeDelta: sizeDelta(diff.SizeDelta),
+		Files:     []*FileDiff{},
+		Unchanged: diff.Unchanged,
+		Meta:      diff.Meta,
+	}
+	for _, f := range diff.Files {
+		d.Files = append(d.Files, &FileDiff{
+			Name:       f.Name,
+			Status:     f.Status,
+			FromSize:   f.FromSize,
+			ToSize:     f.ToSize,
+			SizeDelta:  sizeDelta(f.SizeDelta),
+			FromSha256: f.FromSha256,
+			ToSha256:   f.ToSha256,
+		})
+	}
+	return d
+}
+
+// The sizeDelta returns human readable signed size difference
+func sizeDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + types.Size(delta).String()
+	case delta < 0:
+		return "-" + types.Size(-delta).String()
+	}
+	return "0 B"
+}
@@ -23,6 +23,7 @@ type Artifact struct {
 	Checksum   string
 	Meta       models.ArtifactMetas
 	Files      models.ArtifactFiles
+	Components models.ArtifactComponents
 }
 
 type expiredTime time.Time
@@ -52,6 +53,7 @@ func NewArtifact(artifact *models.Artifact) *Artifact {
 		ExpiredAt:  expiredAt,
 		Checksum:   artifact.Checksum,
 		Meta:       artifact.Meta,
+		Components: artifact.Components,
 	}
 	for _, f := range artifact.Files {
 		f.Name = strings.TrimPrefix(f.Name, filepath.Join(artifact.Storage, artifact.ArtifactID)+string(filepath.Separator))
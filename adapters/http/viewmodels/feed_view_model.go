@@ -0,0 +1,117 @@
+package viewmodels
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// AtomFeed is the Atom (RFC 4287) feed of artifacts
+type AtomFeed struct {
+	XMLName xml.Name     `xml:"feed"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Author  AtomAuthor   `xml:"author"`
+	Links   []*AtomLink  `xml:"link"`
+	Entries []*AtomEntry `xml:"entry"`
+}
+
+type AtomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type AtomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type AtomEntry struct {
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Updated   string        `xml:"updated"`
+	Published string        `xml:"published"`
+	Links     []*AtomLink   `xml:"link"`
+	Category  *AtomCategory `xml:"category"`
+	Summary   string        `xml:"summary"`
+	Content   *AtomContent  `xml:"content"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type AtomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// NewAtomFeed returns feed of artifacts sorted newest first.
+// The baseURL is the absolute url of the server (e.g. https://swamp.example.com),
+// the selfPath and alternatePath are paths of the feed and of its html page.
+// The feed updated time is the newest artifact creation time.
+func NewAtomFeed(baseURL, selfPath, alternatePath, title string, artifacts []*models.Artifact) *AtomFeed {
+	updated := time.Unix(0, 0)
+	if len(artifacts) != 0 {
+		updated = time.Unix(artifacts[0].CreatedAt, 0)
+	}
+	feed := &AtomFeed{
+		XMLNS:   atomNamespace,
+		ID:      baseURL + selfPath,
+		Title:   title,
+		Updated: atomTime(updated),
+		Author:  AtomAuthor{Name: "swamp"},
+		Links: []*AtomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: baseURL + selfPath},
+			{Rel: "alternate", Type: "text/html", Href: baseURL + alternatePath},
+		},
+		Entries: []*AtomEntry{},
+	}
+	for _, artifact := range artifacts {
+		feed.Entries = append(feed.Entries, newAtomEntry(baseURL, artifact))
+	}
+	return feed
+}
+
+func newAtomEntry(baseURL string, artifact *models.Artifact) *AtomEntry {
+	href := baseURL + "/repo/" + artifact.RepoID + "/artifact/" + artifact.ArtifactID
+	createdAt := atomTime(time.Unix(artifact.CreatedAt, 0))
+	state := ApiState(artifact.State)
+
+	content := &strings.Builder{}
+	fmt.Fprintf(content, "<p>Size %v, state %v</p>\n", html.EscapeString(artifact.Size.String()), html.EscapeString(state))
+	if len(artifact.Meta) != 0 {
+		content.WriteString("<table>\n")
+		for _, m := range artifact.Meta {
+			fmt.Fprintf(content, "<tr><td>%v</td><td>%v</td></tr>\n", html.EscapeString(m.Key), html.EscapeString(m.Value))
+		}
+		content.WriteString("</table>\n")
+	}
+	fmt.Fprintf(content, `<p><a href="%v">Artifact</a> | <a href="%v.zip">zip</a> | <a href="%v.tar.gz">tar.gz</a></p>`, html.EscapeString(href), html.EscapeString(href), html.EscapeString(href))
+
+	return &AtomEntry{
+		ID:        href,
+		Title:     artifact.RepoID + "/" + artifact.ArtifactID,
+		Updated:   createdAt,
+		Published: createdAt,
+		Links: []*AtomLink{
+			{Rel: "alternate", Type: "text/html", Href: href},
+			{Rel: "enclosure", Type: "application/zip", Href: href + ".zip"},
+		},
+		Category: &AtomCategory{Term: artifact.RepoID},
+		Summary:  fmt.Sprintf("Size %v, state %v", artifact.Size, state),
+		Content:  &AtomContent{Type: "html", Body: content.String()},
+	}
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
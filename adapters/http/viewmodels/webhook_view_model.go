@@ -0,0 +1,52 @@
+package viewmodels
+
+import (
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+)
+
+type WebhookDelivery struct {
+	DeliveryID models.WebhookDeliveryID
+	URL        string
+	Event      string
+	RepoID     models.RepoID
+	ArtifactID models.ArtifactID
+	State      models.WebhookDeliveryState
+	IsPending  bool
+	IsFailed   bool
+	Attempts   int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	NextAt     time.Time
+	Status     int
+	Error      string
+}
+
+func NewWebhookDelivery(delivery *models.WebhookDelivery) *WebhookDelivery {
+	d := &WebhookDelivery{
+		DeliveryID: delivery.DeliveryID,
+		URL:        delivery.URL,
+		Event:      delivery.Event,
+		RepoID:     delivery.RepoID,
+		ArtifactID: delivery.ArtifactID,
+		State:      delivery.State,
+		IsPending:  delivery.State == models.WebhookDeliveryPending,
+		IsFailed:   delivery.State == models.WebhookDeliveryFailed,
+		Attempts:   delivery.Attempts,
+		CreatedAt:  time.Unix(delivery.CreatedAt, 0),
+		UpdatedAt:  time.Unix(delivery.UpdatedAt, 0),
+		NextAt:     time.Unix(delivery.NextAt, 0),
+		Status:     delivery.Status,
+		Error:      delivery.Error,
+	}
+	return d
+}
+
+func NewWebhookDeliveries(deliveries []*models.WebhookDelivery) []*WebhookDelivery {
+	d := []*WebhookDelivery{}
+	for _, delivery := range deliveries {
+		d = append(d, NewWebhookDelivery(delivery))
+	}
+	return d
+}
@@ -0,0 +1,49 @@
+package adapters
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+func RegisterInspectAlgo(prio int, pattern string, algo ports.InspectAlgo) {
+	info := InspectAlgoInfo{prio, pattern, algo}
+	inspectAlgos = append(inspectAlgos, info)
+	sort.Slice(inspectAlgos, func(i, j int) bool {
+		lib.Assert(inspectAlgos[i].prio != inspectAlgos[j].prio)
+		return inspectAlgos[i].prio < inspectAlgos[j].prio
+	})
+}
+
+type InspectAlgoInfo struct {
+	prio    int
+	pattern string
+	algo    ports.InspectAlgo
+}
+
+var inspectAlgos = []InspectAlgoInfo{}
+
+// InspectFile inspects file content by all algos
+// matching the file name and returns collected details.
+// The path must be absolute.
+func InspectFile(log ports.Logger, f ports.FS, fileName string) ports.FileInspection {
+	lib.Assert(lib.IsAbs(fileName))
+
+	info := ports.FileInspection{}
+	base := filepath.Base(fileName)
+	for _, it := range inspectAlgos {
+		if ok, err := filepath.Match(it.pattern, base); !ok || err != nil {
+			continue
+		}
+		err := it.algo.Inspect(f, fileName, &info)
+		if err != nil && !errors.Is(err, ports.ErrWrongInspectFormat) {
+			log.Warn("unable to inspect file", slog.String("fileName", fileName), slog.String("pattern", it.pattern), slog.Any("err", err))
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+type ApiTokenRepository struct {
+	db ports.DB
+}
+
+func NewApiTokenRepository(db ports.DB) (*ApiTokenRepository, error) {
+	r := &ApiTokenRepository{
+		db: db,
+	}
+	_, err := r.FindAll(ports.Limit(1))
+	return r, err
+}
+
+func (r *ApiTokenRepository) Create(model *models.ApiToken) error {
+	err := r.db.Create(model).Error
+	return err
+}
+
+// FindAll returns tokens, newest first
+func (r *ApiTokenRepository) FindAll(flags ...interface{}) ([]*models.ApiToken, error) {
+	var tokens []*models.ApiToken
+	db := r.db
+	db = db.Order("created_at DESC, token_id DESC")
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case ports.Limit:
+			db = db.Limit(int(v))
+		case ports.WithRepoID:
+			db = db.Where("repo_id = ?", string(v))
+		default:
+			panic(flag)
+		}
+	}
+	err := db.Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *ApiTokenRepository) FindByID(id models.ApiTokenID) (*models.ApiToken, error) {
+	var token *models.ApiToken
+	err := r.db.First(&token, models.ApiToken{TokenID: id}).Error
+	return token, err
+}
+
+func (r *ApiTokenRepository) FindByHash(hash string) (*models.ApiToken, error) {
+	var token *models.ApiToken
+	err := r.db.First(&token, models.ApiToken{Hash: hash}).Error
+	return token, err
+}
+
+// Touch updates last used time of token only
+func (r *ApiTokenRepository) Touch(id models.ApiTokenID, now int64) error {
+	err := r.db.Model(&models.ApiToken{TokenID: id}).Update("last_used_at", now).Error
+	return err
+}
+
+// Revoke marks token revoked.
+// It returns ports.ErrRecordNotFound for unknown or already revoked token.
+func (r *ApiTokenRepository) Revoke(id models.ApiTokenID, now int64) error {
+	db := r.db.Model(&models.ApiToken{}).Where("token_id = ? AND revoked_at = 0", id).Update("revoked_at", now)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return ports.ErrRecordNotFound
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/cloudcopper/swamp/domain/models"
 	"github.com/cloudcopper/swamp/domain/vo"
@@ -30,6 +32,7 @@ func NewArtifactRepository(db ports.DB, f ports.FS) (*ArtifactRepository, error)
 func (r *ArtifactRepository) Create(model *models.Artifact) error {
 	err := r.db.Transaction(func(db *gorm.DB) error {
 		model.Meta.Secure()
+		model.Files.Index()
 
 		if err := model.Validate(r.validator); err != nil {
 			return fmt.Errorf("invalid artifact object: %w", err)
@@ -51,33 +54,59 @@ func (r *ArtifactRepository) Create(model *models.Artifact) error {
 	return err
 }
 
+// Update saves the artifact state and expiration time.
+// It never creates the artifact - the ports.ErrRecordNotFound is returned,
+// if the artifact is deleted meanwhile.
 func (r *ArtifactRepository) Update(model *models.Artifact) error {
 	db := r.db
-	err := db.Save(model).Error
-	return err
+	tx := db.Model(&models.Artifact{}).Where("repo_id = ? AND artifact_id = ?", model.RepoID, model.ArtifactID).Updates(map[string]interface{}{
+		"state":      model.State,
+		"expired_at": model.ExpiredAt,
+	})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ports.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *ArtifactRepository) Delete(model *models.Artifact) error {
 	err := r.db.Transaction(func(db *gorm.DB) error {
+		// Delete artifact model first,
+		// so the repo counters are not modified twice by concurrent deletes
+		tx := db.Delete(model)
+		if tx.Error != nil {
+			return tx.Error
+		}
+		if tx.RowsAffected == 0 {
+			return ports.ErrRecordNotFound
+		}
+
 		// Modify the Repo.Size
 		if err := db.Model(&models.Repo{}).Where("repo_id = ?", model.RepoID).Update("size", gorm.Expr("size - ?", model.Size)).Error; err != nil {
 			return err
 		}
 		// Modify the Repo.ArtifactsCount
-		if err := db.Model(&models.Repo{}).Where("repo_id = ?", model.RepoID).Update("artifacts_count", gorm.Expr("artifacts_count - ?", 1)).Error; err != nil {
-			return err
-		}
-
-		err := db.Delete(model).Error
+		err := db.Model(&models.Repo{}).Where("repo_id = ?", model.RepoID).Update("artifacts_count", gorm.Expr("artifacts_count - ?", 1)).Error
 		return err
 	})
 	return err
 }
 
-func (r *ArtifactRepository) FindAll() ([]*models.Artifact, error) {
+func (r *ArtifactRepository) FindAll(flags ...interface{}) ([]*models.Artifact, error) {
 	var artifacts []*models.Artifact
 	db := r.db
 	db = db.Order("created_at DESC")
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case ports.WithRepoID:
+			db = db.Where("repo_id = ?", string(v))
+		default:
+			panic(flag)
+		}
+	}
 	db = db.Preload("Meta", func(db ports.DB) ports.DB {
 		return db.Order("key DESC")
 	})
@@ -99,6 +128,9 @@ func (r *ArtifactRepository) FindByID(repoID models.RepoID, artifactID models.Ar
 				return db.Order("key DESC")
 			})
 			db = db.Preload("Files")
+			db = db.Preload("Components", func(db ports.DB) ports.DB {
+				return db.Order("name ASC, version ASC")
+			})
 		default:
 			panic(flag)
 		}
@@ -180,3 +212,71 @@ func (r *ArtifactRepository) IterateAll(callback func(repo *models.Artifact) (bo
 	db = db.Order("created_at DESC")
 	return iterateAll[models.Artifact](db, callback)
 }
+
+// FindAllFiles returns files of all artifacts matching flags
+func (r *ArtifactRepository) FindAllFiles(flags ...interface{}) ([]*models.ArtifactFile, error) {
+	var files []*models.ArtifactFile
+	db := r.db
+	db = db.Order("repo_id ASC, artifact_id ASC, name ASC")
+
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case ports.WithBuildID:
+			db = db.Where("build_id = ?", strings.ToLower(string(v)))
+		case ports.WithFileName:
+			db = whereFileName(db, string(v))
+		case ports.WithRepoIDs:
+			db = db.Where("repo_id IN ?", []string(v))
+		case ports.Limit:
+			db = db.Limit(int(v))
+		default:
+			panic(flag)
+		}
+	}
+
+	err := db.Find(&files).Error
+	return files, err
+}
+
+// FindAllComponents returns components of all artifacts matching flags
+func (r *ArtifactRepository) FindAllComponents(flags ...interface{}) ([]*models.ArtifactComponent, error) {
+	var components []*models.ArtifactComponent
+	db := r.db
+	db = db.Order("repo_id ASC, artifact_id ASC, name ASC, version ASC")
+
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case ports.WithComponentName:
+			db = db.Where("LOWER(name) = ?", strings.ToLower(string(v)))
+		case ports.WithRepoIDs:
+			db = db.Where("repo_id IN ?", []string(v))
+		case ports.Limit:
+			db = db.Limit(int(v))
+		default:
+			panic(flag)
+		}
+	}
+
+	err := db.Find(&components).Error
+	return components, err
+}
+
+// The whereFileName narrows files by name pattern (case insensitive):
+//   - extension (e.g. .bin) - the indexed extension is equal
+//   - glob (e.g. boot*.bin or */lib/*.so) - the base name (or whole name, if pattern has path separator) matches
+//   - substring (e.g. bootloader) - the base name (or whole name, if pattern has path separator) contains
+func whereFileName(db ports.DB, pattern string) ports.DB {
+	pattern = strings.ToLower(filepath.FromSlash(strings.TrimSpace(pattern)))
+	column := "base_name"
+	if strings.ContainsRune(pattern, filepath.Separator) {
+		column = "LOWER(name)"
+	}
+	switch {
+	case strings.HasPrefix(pattern, ".") && len(pattern) > 1 && !strings.ContainsAny(pattern[1:], ".*?[") && column == "base_name":
+		return db.Where("ext = ?", pattern)
+	case strings.ContainsAny(pattern, "*?["):
+		return db.Where(column+" GLOB ?", pattern)
+	}
+	like := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pattern)
+	return db.Where(column+` LIKE ? ESCAPE '\'`, "%"+like+"%")
+}
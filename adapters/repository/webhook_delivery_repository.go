@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+type WebhookDeliveryRepository struct {
+	db ports.DB
+}
+
+func NewWebhookDeliveryRepository(db ports.DB) (*WebhookDeliveryRepository, error) {
+	r := &WebhookDeliveryRepository{
+		db: db,
+	}
+	_, err := r.FindAll(ports.Limit(1))
+	return r, err
+}
+
+func (r *WebhookDeliveryRepository) Create(model *models.WebhookDelivery) error {
+	err := r.db.Create(model).Error
+	return err
+}
+
+func (r *WebhookDeliveryRepository) Update(model *models.WebhookDelivery) error {
+	err := r.db.Save(model).Error
+	return err
+}
+
+// FindAll returns deliveries, newest first
+func (r *WebhookDeliveryRepository) FindAll(flags ...interface{}) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	db := r.db
+	db = db.Order("created_at DESC, delivery_id DESC")
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case ports.Limit:
+			db = db.Limit(int(v))
+		case ports.WithRepoID:
+			db = db.Where("repo_id = ?", string(v))
+		default:
+			panic(flag)
+		}
+	}
+	err := db.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindAllPending returns pending deliveries due by now, oldest first
+func (r *WebhookDeliveryRepository) FindAllPending(now int64, flags ...interface{}) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	db := r.db
+	db = db.Order("next_at ASC, delivery_id ASC")
+	db = db.Where("state = ?", models.WebhookDeliveryPending)
+	db = db.Where("next_at <= ?", now)
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case ports.Limit:
+			db = db.Limit(int(v))
+		default:
+			panic(flag)
+		}
+	}
+	err := db.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// DeleteAllCompleted removes delivered and failed deliveries
+// last updated before given unix time
+func (r *WebhookDeliveryRepository) DeleteAllCompleted(before int64) error {
+	db := r.db
+	db = db.Where("state != ?", models.WebhookDeliveryPending)
+	db = db.Where("updated_at < ?", before)
+	err := db.Delete(&models.WebhookDelivery{}).Error
+	return err
+}
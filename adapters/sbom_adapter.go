@@ -0,0 +1,51 @@
+package adapters
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+func RegisterSbomAlgo(prio int, pattern string, algo ports.SbomAlgo) {
+	info := SbomAlgoInfo{prio, pattern, algo}
+	sbomAlgos = append(sbomAlgos, info)
+	sort.Slice(sbomAlgos, func(i, j int) bool {
+		lib.Assert(sbomAlgos[i].prio != sbomAlgos[j].prio)
+		return sbomAlgos[i].prio < sbomAlgos[j].prio
+	})
+}
+
+type SbomAlgoInfo struct {
+	prio    int
+	pattern string
+	algo    ports.SbomAlgo
+}
+
+var sbomAlgos = []SbomAlgoInfo{}
+
+// ParseSbomFile parses sbom file via algos
+// and returns listed components.
+// It returns ports.ErrWrongSbomFormat, if the file is not sbom.
+func ParseSbomFile(log ports.Logger, f ports.FS, sbomFileName string) ([]ports.SbomComponent, error) {
+	lib.Assert(lib.IsAbs(sbomFileName))
+
+	fileName := filepath.Base(sbomFileName)
+	for _, it := range sbomAlgos {
+		if ok, err := filepath.Match(it.pattern, fileName); !ok || err != nil {
+			continue
+		}
+
+		components, err := it.algo.ParseSbomFile(f, sbomFileName)
+		if errors.Is(err, ports.ErrWrongSbomFormat) {
+			continue
+		}
+		log.Debug("sbom file parsed", slog.String("sbomFileName", sbomFileName), slog.String("pattern", it.pattern), slog.Int("components", len(components)), slog.Any("err", err))
+		return components, err
+	}
+
+	return nil, ports.ErrWrongSbomFormat
+}
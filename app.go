@@ -15,6 +15,7 @@ import (
 	"github.com/cloudcopper/swamp/infra"
 	"github.com/cloudcopper/swamp/infra/config"
 	"github.com/cloudcopper/swamp/infra/disk"
+	"github.com/cloudcopper/swamp/infra/metrics"
 	"github.com/cloudcopper/swamp/lib"
 	"github.com/cloudcopper/swamp/ports"
 	"github.com/spf13/afero"
@@ -29,7 +30,8 @@ func App(log ports.Logger, topFS interface{}) error {
 	var realFS ports.FS = afero.NewOsFs()
 
 	// EventBus
-	var bus ports.EventBus = infra.NewEventBus()
+	eventBus := infra.NewEventBus()
+	var bus ports.EventBus = eventBus
 	defer bus.Shutdown()
 
 	// Create layered filesystem
@@ -56,7 +58,7 @@ func App(log ports.Logger, topFS interface{}) error {
 	}
 	defer closeDb()
 	// Sync database
-	if err := db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFile)); err != nil {
+	if err := db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFile), new(models.ArtifactComponent)); err != nil {
 		log.Error("unable sync database", slog.Any("err", err), slog.String("driver", driver), slog.String("source", source))
 		return lib.NewErrorCode(err, errors.RetMigrateDatabaseError)
 	}
@@ -73,6 +75,27 @@ func App(log ports.Logger, topFS interface{}) error {
 	}
 	repositories := repository.NewRepositories(repoRepository, artifactRepository)
 
+	// Open state database
+	// It keeps the state which shall survive restart (webhook deliveries, api tokens)
+	if config.StateDatabase == "" {
+		log.Warn("no state database - webhook deliveries and api tokens are not persistent")
+	}
+	stateDb, closeStateDb, err := openStateDatabase(log)
+	if err != nil {
+		return err
+	}
+	defer closeStateDb()
+	webhookDeliveryRepository, err := repository.NewWebhookDeliveryRepository(stateDb)
+	if err != nil {
+		log.Error("unable create webhook delivery repository", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetCreateWebhookRepositoryError)
+	}
+	apiTokenRepository, err := repository.NewApiTokenRepository(stateDb)
+	if err != nil {
+		log.Error("unable create api token repository", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetCreateApiTokenRepositoryError)
+	}
+
 	// Create artifact storage
 	artifactStorage, err := adapters.NewBasicArtifactStorageAdapter(log, realFS)
 	if err != nil {
@@ -94,6 +117,10 @@ func App(log ports.Logger, topFS interface{}) error {
 	// - handling artifacts retention
 	repoService := NewRepoService(log, bus, disk.NewFilepathWalk(realFS), repoRepository)
 	defer repoService.Close()
+	// Create webhook service
+	// - deliver artifact events to webhooks
+	webhookService := NewWebhookService(log, bus, repositories, webhookDeliveryRepository, cfg.Webhooks)
+	defer webhookService.Close()
 	// Create filesystem watcher for input files
 	inputWatcher, err := infra.NewWatcherService("input", log, bus)
 	if err != nil {
@@ -106,34 +133,60 @@ func App(log ports.Logger, topFS interface{}) error {
 	if err := startup(log, cfg, bus, repoRepository); err != nil {
 		return err
 	}
+	// Create replication service
+	// - pull missing artifacts from upstream of repos
+	replicationService := NewReplicationService(log, bus, realFS, repositories)
+	defer replicationService.Close()
+
+	// Create health service
+	// - checks watcher, database, repos directories and initial storage scan
+	healthService := NewHealthService(log, realFS, repoRepository, inputWatcher, repoService, eventBus)
 
 	// Create router
-	router := http.NewRouter(log)
+	router := http.NewRouter(log, cfg.Limits)
 	// Create render object
 	// It also loads templates
 	render := infra.NewRender(fs, "layout")
+	// Create metrics registry
+	registry := metrics.NewRegistry(func() ([]*models.Repo, error) { return repoRepository.FindAll() }, eventBus)
+	// Create download limiter
+	limiter := infra.NewLimiter(cfg.Limits)
+	// Create archive cache
+	// - keeps generated archives of artifacts, if enabled
+	var archiveCache *infra.ArchiveCache
+	if config.ArchiveCacheDir != "" {
+		archiveCache, err = infra.NewArchiveCache(log, realFS, config.ArchiveCacheDir, config.ArchiveCacheSize)
+		if err != nil {
+			log.Error("unable create archive cache", slog.Any("err", err), slog.String("dir", config.ArchiveCacheDir))
+			return lib.NewErrorCode(err, errors.RetCreateArchiveCacheError)
+		}
+	}
+	// Create archive browser
+	// - lists and extracts entries of archive files
+	archiveBrowser := infra.NewArchiveBrowser(config.ArchiveMaxEntries, config.ArchiveEntryMaxSize)
+	filePreviewer := infra.NewFilePreviewer(config.PreviewMaxSize)
 	// Create controllers
-	frontPageController := controllers.NewFrontPageController(log, render, repositories)
-	repoContoller := controllers.NewRepoController(log, render, repoRepository)
-	artifactController := controllers.NewArtifactController(log, render, artifactRepository, artifactStorage)
-	aboutPageController := controllers.NewAboutPageController(log, render)
+	appControllers := &Controllers{
+		FrontPage: controllers.NewFrontPageController(log, render, repositories),
+		Repo:      controllers.NewRepoController(log, render, repoRepository),
+		Artifact:  controllers.NewArtifactController(log, render, artifactRepository, artifactStorage, limiter, archiveCache, archiveBrowser, filePreviewer),
+		AboutPage: controllers.NewAboutPageController(log, render),
+		Search:    controllers.NewSearchController(log, render, repositories),
+		Api:       controllers.NewApiController(log, render, repositories, artifactStorage, limiter),
+		Docs:      controllers.NewDocsController(log, render, fs),
+		Manage:    controllers.NewManageController(log, render, repositories, artifactStorage, bus),
+		Events:    controllers.NewEventsController(log, render, bus, repoRepository),
+		Webhooks:  controllers.NewWebhooksController(log, render, webhookDeliveryRepository),
+		Auth:      controllers.NewAuthController(log, render, repoRepository, apiTokenRepository, cfg.Auth, config.AdminToken),
+		Tokens:    controllers.NewTokensController(log, render, repoRepository, apiTokenRepository),
+		Metrics:   controllers.NewMetricsController(log, render, registry),
+		Health:    controllers.NewHealthController(log, render, healthService),
+		Compare:   controllers.NewCompareController(log, render, repositories, artifactStorage),
+		Feed:      controllers.NewFeedController(log, render, repositories),
+		Badge:     controllers.NewBadgeController(log, render, artifactRepository),
+	}
 	// Add routes
-	router.Get("/", frontPageController.Index)
-	router.Get("/about", aboutPageController.Index)
-	router.Get("/repo/{repoID}/artifact/{artifactID}/file/*", artifactController.DownloadSingleFile)
-	// WARN Next two routes are more like documentation as those are not working
-	// Please see https://github.com/go-chi/chi/issues/758 and related
-	// So the artifactController.GetHandler would handle calling proper handled
-	// base on suffix
-	router.Get("/repo/{repoID}/artifact/{artifactID}.tar.gz", artifactController.DownloadGzip)
-	router.Get("/repo/{repoID}/artifact/{artifactID}.zip", artifactController.DownloadZip)
-	router.Get("/repo/{repoID}/artifact/{artifactID}", artifactController.Get)
-	router.Get("/repo/{repoID}", repoContoller.Get)
-	// Static file handler
-	fileServer := http.FileServer(http.FS(fs))
-	router.Handle("/static/*", fileServer)
-	// 404 handler
-	router.NotFound(frontPageController.NotFound)
+	AddRoutes(router, fs, appControllers)
 	// Create http server
 	// The router must has all routes already
 	// It will start server in separate goroutine
@@ -159,3 +212,25 @@ func App(log ports.Logger, topFS interface{}) error {
 	// TODO Optionally dump whole db to debug file ?
 	return nil
 }
+
+// The openStateDatabase opens and syncs the state database given by config.StateDatabase
+// or in memory one, if not set
+func openStateDatabase(log ports.Logger) (ports.DB, func(), error) {
+	driver := infra.DriverSqlite
+	source := infra.SourceSqliteStateInMemory
+	if config.StateDatabase != "" {
+		source = infra.SourceSqliteFile(config.StateDatabase)
+	}
+	db, closeDb, err := infra.NewDatabase(log, driver, source)
+	if err != nil {
+		log.Error("unable to create state database", slog.Any("err", err), slog.String("driver", driver), slog.String("source", source))
+		return nil, nil, lib.NewErrorCode(err, errors.RetCreateStateDatabaseError)
+	}
+	// Sync state database
+	if err := db.AutoMigrate(new(models.WebhookDelivery), new(models.ApiToken)); err != nil {
+		closeDb()
+		log.Error("unable sync state database", slog.Any("err", err), slog.String("driver", driver), slog.String("source", source))
+		return nil, nil, lib.NewErrorCode(err, errors.RetMigrateStateDatabaseError)
+	}
+	return db, closeDb, nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/cloudcopper/swamp/domain/vo"
 	"github.com/cloudcopper/swamp/infra/config"
 	"github.com/cloudcopper/swamp/infra/disk"
+	"github.com/cloudcopper/swamp/infra/metrics"
 	"github.com/cloudcopper/swamp/lib"
 	"github.com/cloudcopper/swamp/lib/types"
 	"github.com/cloudcopper/swamp/ports"
@@ -30,16 +31,18 @@ import (
 //     and if so, then create new artifact by checksum file
 //   - dangling-repo-artifact - to check/add dangling repo artifact
 type ArtifactService struct {
-	log                         ports.Logger
-	bus                         ports.EventBus
-	artifactStorage             ports.ArtifactStorage
-	repositories                domain.Repositories
-	storageFs                   ports.FS
-	brokenFs                    ports.FS
-	chTopicRepoUpdated          chan ports.Event
-	chTopicInputFileModified    chan ports.Event
-	chTopicDanglingRepoArtifact chan ports.Event
-	closeWg                     sync.WaitGroup
+	log                           ports.Logger
+	bus                           ports.EventBus
+	artifactStorage               ports.ArtifactStorage
+	repositories                  domain.Repositories
+	storageFs                     ports.FS
+	brokenFs                      ports.FS
+	chTopicRepoUpdated            chan ports.Event
+	chTopicInputFileModified      chan ports.Event
+	chTopicInputFileRemoved       chan ports.Event
+	chTopicDanglingRepoArtifact   chan ports.Event
+	chTopicReplicatedRepoArtifact chan ports.Event
+	closeWg                       sync.WaitGroup
 }
 
 func NewArtifactService(log ports.Logger, bus ports.EventBus, artifactStorage ports.ArtifactStorage, repositories domain.Repositories) (*ArtifactService, error) {
@@ -50,14 +53,16 @@ func NewArtifactService(log ports.Logger, bus ports.EventBus, artifactStorage po
 	}
 
 	s := &ArtifactService{
-		log:                         log,
-		bus:                         bus,
-		artifactStorage:             artifactStorage,
-		repositories:                repositories,
-		storageFs:                   afero.NewOsFs(),
-		chTopicRepoUpdated:          bus.Sub(ports.TopicRepoUpdated),
-		chTopicInputFileModified:    bus.Sub(ports.TopicInputFileModified),
-		chTopicDanglingRepoArtifact: bus.Sub(ports.TopicDanglingRepoArtifact),
+		log:                           log,
+		bus:                           bus,
+		artifactStorage:               artifactStorage,
+		repositories:                  repositories,
+		storageFs:                     afero.NewOsFs(),
+		chTopicRepoUpdated:            bus.Sub(ports.TopicRepoUpdated),
+		chTopicInputFileModified:      bus.Sub(ports.TopicInputFileModified),
+		chTopicInputFileRemoved:       bus.Sub(ports.TopicInputFileRemoved),
+		chTopicDanglingRepoArtifact:   bus.Sub(ports.TopicDanglingRepoArtifact),
+		chTopicReplicatedRepoArtifact: bus.Sub(ports.TopicReplicatedRepoArtifact),
 	}
 	log.Info("created")
 
@@ -74,7 +79,9 @@ func NewArtifactService(log ports.Logger, bus ports.EventBus, artifactStorage po
 
 func (s *ArtifactService) Close() {
 	s.log.Info("closing")
+	s.bus.Unsub(s.chTopicReplicatedRepoArtifact)
 	s.bus.Unsub(s.chTopicDanglingRepoArtifact)
+	s.bus.Unsub(s.chTopicInputFileRemoved)
 	s.bus.Unsub(s.chTopicInputFileModified)
 	s.bus.Unsub(s.chTopicRepoUpdated)
 	s.closeWg.Wait()
@@ -117,12 +124,24 @@ func (s *ArtifactService) background() {
 			// TODO Should we change watcher to some poll/scan mode watcher which would ran well over ports.FS?
 			// TODO Event should have inputFS!!!
 			s.checkInputFile(repos, s.storageFs, path)
+		case event, ok := <-s.chTopicInputFileRemoved:
+			if !ok {
+				return
+			}
+			path := event[0]
+			s.removeInputFile(s.storageFs, path)
 		case event, ok := <-s.chTopicDanglingRepoArtifact:
 			if !ok {
 				return
 			}
 			repoID, artifactID := event[0], event[1]
-			s.checkRepoArtifact(repoID, artifactID)
+			s.checkRepoArtifact(repoID, artifactID, false)
+		case event, ok := <-s.chTopicReplicatedRepoArtifact:
+			if !ok {
+				return
+			}
+			repoID, artifactID := event[0], event[1]
+			s.checkRepoArtifact(repoID, artifactID, true)
 		case _, ok := <-timerExpired.C:
 			if !ok {
 				return
@@ -163,35 +182,75 @@ func (s *ArtifactService) checkInputFile(repos []*models.Repo, f ports.FS, path
 	}
 	return errors.ErrNotMatchRepoInput
 }
+
+// The removeInputFile from background where any input file is removed.
+// The rejection reason of removed checksum file is removed as well.
+func (s *ArtifactService) removeInputFile(f ports.FS, path string) {
+	if adapters.IsChecksumFile(path) && lib.NoSuchFile(f, path) {
+		removeRejectedInputArtifact(s.log, f, path)
+	}
+}
+
 func (s *ArtifactService) checkRepoInput(repo *models.Repo, f ports.FS, checksumFile string) error {
 	log := s.log.With(slog.Any("checksumFile", checksumFile), slog.Any("repoID", repo.RepoID))
+	start, result, skipped := time.Now(), metrics.IngestSuccess, false
+	defer func() {
+		// Account checksum files only, as other input files are modified prior checksum file
+		if adapters.IsChecksumFile(checksumFile) && !skipped {
+			metrics.Ingest(repo.RepoID, result, time.Since(start))
+		}
+	}()
 
 	// Check the path is a good checksum
 	da := checksumDiskArtifact(log, f, checksumFile)
 	if da.checksumError != nil {
+		result = metrics.IngestInvalid
 		return da.checksumError
 	}
 	log.Info("checksum file verified", slog.Any("files.Good", da.files.Good))
 
-	// get artifact meta and files
+	// Skip artifact already rejected, unless any of its files modified since
+	if isRejectedInputArtifact(f, da.checksumFile, da.files.Good) {
+		log.Info("skip - artifact already rejected")
+		skipped = true
+		return nil
+	}
+
+	// Detect artifact id and its location within input
+	subdir := lib.GetFirstSubdir(repo.Input, da.checksumFile)
+	artifactID := subdir
+	if artifactID == "" {
+		artifactID = ulid.Make().String()
+	}
+	da.location = filepath.Join(repo.Input, subdir)
+
+	// get artifact meta, files and components
 	meta := da.getArtifactMeta(log)
 	files := da.getArtifactFiles(log)
+	components := da.getArtifactComponents(log)
+
+	// Check meta against repo meta schema prior accepting artifact
+	if err := repo.MetaSchema.Validate(meta); err != nil {
+		log.Warn("artifact rejected", slog.Any("err", err))
+		result = metrics.IngestRejected
+		rejectInputArtifact(log, f, da.checksumFile, err)
+		s.bus.Pub(ports.TopicRejectedRepoArtifact, ports.Event{repo.RepoID, da.checksumFile, err.Error()})
+		return err
+	}
 
 	// Create new artifacts
 	artifacts := da.files.Good
-	artifactID := lib.GetFirstSubdir(repo.Input, da.checksumFile)
-	if artifactID == "" {
-		artifactID = ulid.Make().String()
-	}
 	log.Info("new artifact", slog.Any("artifactID", artifactID))
 
 	info, err := s.artifactStorage.NewArtifact(f, repo.Input, artifacts, repo.Storage, artifactID)
 	if err != nil {
 		log.Error("unable to create new artifacts", slog.Any("err", err))
+		result = metrics.IngestFailure
 	}
 
 	// Cleanup input artifacts
 	cleanInputArtifacts(log, f, repo.Input, artifacts)
+	removeRejectedInputArtifact(log, f, da.checksumFile)
 
 	// Insert artifact record
 	createdAt := info.CreatedAt
@@ -208,17 +267,22 @@ func (s *ArtifactService) checkRepoInput(repo *models.Repo, f ports.FS, checksum
 		Checksum:   string(da.checksum),
 		Meta:       meta,
 		Files:      files,
+		Components: components,
 	}
 	if err := s.repositories.Artifact().Create(artifact); err != nil {
 		log.Error("unable create artifact record", slog.Any("artifactID", artifact.ArtifactID), slog.Any("err", err))
+		result = metrics.IngestFailure
+		return err
 	}
 	s.bus.Pub(ports.TopicArtifactUpdated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+	s.bus.Pub(ports.TopicArtifactCreated, ports.Event{artifact.RepoID, artifact.ArtifactID})
 	return nil
 }
 
 // The checkRepoArtifact checks the artifact inside repo storage.
 // If it dangling, it creates new artifact model.
-func (s *ArtifactService) checkRepoArtifact(repoID models.RepoID, artifactID models.ArtifactID) {
+// The created artifact is signaled as new one, if created is set (e.g. replicated).
+func (s *ArtifactService) checkRepoArtifact(repoID models.RepoID, artifactID models.ArtifactID, created bool) {
 	log := s.log.With(slog.Any("repoID", repoID), slog.Any("artifactID", artifactID))
 	repo, err := s.repositories.Repo().FindByID(repoID)
 	if err != nil {
@@ -247,9 +311,10 @@ func (s *ArtifactService) checkRepoArtifact(repoID models.RepoID, artifactID mod
 			state |= vo.ArtifactIsExpired
 		}
 
-		// get artifact meta and files
+		// get artifact meta, files and components
 		meta := da.getArtifactMeta(log)
 		files := da.getArtifactFiles(log)
+		components := da.getArtifactComponents(log)
 
 		artifact := &models.Artifact{
 			ArtifactID: artifactID,
@@ -262,6 +327,7 @@ func (s *ArtifactService) checkRepoArtifact(repoID models.RepoID, artifactID mod
 			ExpiredAt:  expiredAt,
 			Meta:       meta,
 			Files:      files,
+			Components: components,
 		}
 
 		if err := s.repositories.Artifact().Create(artifact); err != nil {
@@ -270,6 +336,11 @@ func (s *ArtifactService) checkRepoArtifact(repoID models.RepoID, artifactID mod
 		}
 		log.Info("artifact re-created")
 		s.bus.Pub(ports.TopicArtifactUpdated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+		// The artifacts found in storage at startup are not new ones,
+		// but the replicated artifacts are
+		if created {
+			s.bus.Pub(ports.TopicArtifactCreated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+		}
 		return
 	}
 	if artifact.ArtifactID != artifactID {
@@ -334,8 +405,11 @@ func (s *ArtifactService) markExpiredArtifacts(now int64) {
 		err := s.repositories.Artifact().Update(artifact)
 		if err != nil {
 			log.Error("unable set artifact expired", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("err", err))
+			continue
 		}
+		metrics.ArtifactsExpiredTotal.WithLabelValues(artifact.RepoID).Inc()
 		s.bus.Pub(ports.TopicArtifactUpdated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+		s.bus.Pub(ports.TopicArtifactExpired, ports.Event{artifact.RepoID, artifact.ArtifactID})
 	}
 }
 
@@ -356,7 +430,10 @@ func (s *ArtifactService) removeExpiredArtifacts(limit int) {
 		}
 		if err := s.repositories.Artifact().Delete(artifact); err != nil {
 			log.Error("artifact model delete failed", slog.Any("err", err))
+			continue
 		}
+		metrics.ArtifactsRemovedTotal.WithLabelValues(artifact.RepoID, metrics.RemovedExpired).Inc()
+		s.bus.Pub(ports.TopicArtifactRemoved, ports.Event{artifact.RepoID, artifact.ArtifactID})
 	}
 }
 
@@ -372,9 +449,19 @@ func (s *ArtifactService) checkBrokenArtifacts(limit int, artifacts []*models.Ar
 	}
 
 	for x := 0; x < limit && len(artifacts) > 0; x++ {
-		artifact := artifacts[0]
+		// The artifact might be removed or changed since the list fetched
+		artifact, err := s.repositories.Artifact().FindByID(artifacts[0].RepoID, artifacts[0].ArtifactID)
 		artifacts = artifacts[1:]
-		lib.Assert(!artifact.State.IsBroken())
+		if errors.Is(err, ports.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Error("unable to find artifact", slog.Any("err", err))
+			continue
+		}
+		if artifact.State.IsBroken() {
+			continue
+		}
 		s.checkBrokenArtifact(artifact)
 	}
 	return artifacts
@@ -407,8 +494,11 @@ func (s *ArtifactService) checkBrokenArtifact(artifact *models.Artifact) {
 		err := s.repositories.Artifact().Update(artifact)
 		if err != nil {
 			log.Error("unable set artifact broken", slog.Any("repoID", artifact.RepoID), slog.Any("artifactID", artifact.ArtifactID), slog.Any("err", err))
+			return
 		}
+		metrics.ArtifactsBrokenTotal.WithLabelValues(artifact.RepoID).Inc()
 		s.bus.Pub(ports.TopicArtifactUpdated, ports.Event{artifact.RepoID, artifact.ArtifactID})
+		s.bus.Pub(ports.TopicArtifactBroken, ports.Event{artifact.RepoID, artifact.ArtifactID})
 	}
 }
 
@@ -457,7 +547,10 @@ func (s *ArtifactService) removeBrokenArtifacts(limit int) {
 		}
 		if err := s.repositories.Artifact().Delete(artifact); err != nil {
 			log.Error("artifact model delete failed", slog.Any("err", err))
+			continue
 		}
+		metrics.ArtifactsRemovedTotal.WithLabelValues(artifact.RepoID, metrics.RemovedBroken).Inc()
+		s.bus.Pub(ports.TopicArtifactRemoved, ports.Event{artifact.RepoID, artifact.ArtifactID})
 	}
 }
 
@@ -484,6 +577,49 @@ func cleanInputArtifacts(log ports.Logger, f ports.FS, input string, artifacts [
 	}
 }
 
+// The rejectInputArtifact leaves the reason of rejection next to checksum file.
+// The artifact files itself are kept untouched in input.
+func rejectInputArtifact(log ports.Logger, f ports.FS, checksumFile string, reason error) {
+	name := checksumFile + ".rejected"
+	content := fmt.Sprintf("artifact rejected at %v\n", time.Now().UTC().Format(time.RFC3339))
+	var violation errors.ErrMetaSchemaViolation
+	if errors.As(reason, &violation) {
+		content += "reason: meta schema violation\n"
+		for _, v := range violation.Violations {
+			content += fmt.Sprintf("  - %v\n", v)
+		}
+	} else {
+		content += fmt.Sprintf("reason: %v\n", reason)
+	}
+	if err := afero.WriteFile(f, name, []byte(content), 0o644); err != nil {
+		log.Warn("unable to write rejection reason", slog.String("name", name), slog.Any("err", err))
+	}
+}
+
+// The isRejectedInputArtifact returns true, if the artifact has rejection reason
+// and none of the files is modified after it
+func isRejectedInputArtifact(f ports.FS, checksumFile string, files []string) bool {
+	rejected, err := f.Stat(checksumFile + ".rejected")
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		info, err := f.Stat(file)
+		if err != nil || info.ModTime().After(rejected.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// The removeRejectedInputArtifact removes the reason of rejection next to checksum file, if any
+func removeRejectedInputArtifact(log ports.Logger, f ports.FS, checksumFile string) {
+	name := checksumFile + ".rejected"
+	if err := f.Remove(name); err != nil && !lib.NoSuchFile(f, name) {
+		log.Warn("unable to remove rejection reason", slog.String("name", name), slog.Any("err", err))
+	}
+}
+
 type diskArtifact struct {
 	fs            ports.FS
 	location      string
@@ -606,7 +742,7 @@ func (da *diskArtifact) getArtifactMeta(log ports.Logger) models.ArtifactMetas {
 	return meta
 }
 
-func (da *diskArtifact) getArtifactFiles(ports.Logger) models.ArtifactFiles {
+func (da *diskArtifact) getArtifactFiles(log ports.Logger) models.ArtifactFiles {
 	files := models.ArtifactFiles{}
 	addFile := func(filePath string, state vo.ArtifactState) {
 		fileName := strings.TrimPrefix(strings.TrimPrefix(filePath, da.location), string(filepath.Separator))
@@ -619,6 +755,14 @@ func (da *diskArtifact) getArtifactFiles(ports.Logger) models.ArtifactFiles {
 			Size:  types.Size(size),
 			State: state,
 		}
+		// Inspect content of good files only
+		if state.IsOK() {
+			info := adapters.InspectFile(log, da.fs, filePath)
+			file.MimeType = info.MimeType
+			file.Arch = info.Arch
+			file.BuildID = info.BuildID
+			file.Entries = info.Entries
+		}
 		files = append(files, file)
 	}
 	for _, f := range da.files.Good {
@@ -633,3 +777,34 @@ func (da *diskArtifact) getArtifactFiles(ports.Logger) models.ArtifactFiles {
 
 	return files
 }
+
+// The getArtifactComponents parses SBOM files of the artifact
+// and returns all components listed there
+func (da *diskArtifact) getArtifactComponents(log ports.Logger) models.ArtifactComponents {
+	components := models.ArtifactComponents{}
+	for _, f := range da.files.Good {
+		list, err := adapters.ParseSbomFile(log, da.fs, f)
+		if err != nil {
+			if !errors.Is(err, ports.ErrWrongSbomFormat) {
+				log.Warn("unable parse sbom file", slog.String("file", f), slog.Any("err", err))
+			}
+			continue
+		}
+		fileName := strings.TrimPrefix(strings.TrimPrefix(f, da.location), string(filepath.Separator))
+		for index, c := range list {
+			if c.Name == "" {
+				continue
+			}
+			components = append(components, &models.ArtifactComponent{
+				File:    fileName,
+				Index:   index,
+				Name:    c.Name,
+				Version: c.Version,
+				Purl:    c.Purl,
+				License: c.License,
+			})
+		}
+	}
+
+	return components
+}
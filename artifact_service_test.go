@@ -138,6 +138,27 @@ func TestArtifactServiceScenario1(t *testing.T) {
 		assert.NotEmpty(artifactModel.Meta)
 		// ...artifact has files
 		assert.Len(artifactModel.Files, 5)
+		// ...and files are found by name
+		for pattern, count := range map[string]int{".bin": 2, ".BIN": 2, "file1*": 1, "*.txt": 2, "ILE2": 1, "_exp": 1, "%": 0, "file?.bin": 2, "*/file1.bin": 0, ".b": 0} {
+			files, err := ar.FindAllFiles(ports.WithFileName(pattern))
+			assert.NoError(err)
+			assert.Len(files, count, pattern)
+		}
+		// ...and narrowed by repo ids before limit
+		for _, it := range []struct {
+			ids   []string
+			count int
+		}{{[]string{}, 0}, {[]string{"other"}, 0}, {[]string{testRepoID}, 1}, {[]string{"other", testRepoID}, 1}} {
+			files, err := ar.FindAllFiles(ports.WithFileName(".bin"), ports.WithRepoIDs(it.ids), ports.Limit(1))
+			assert.NoError(err)
+			assert.Len(files, it.count, it.ids)
+		}
+		// ...named relative to artifact location and inspected
+		for _, f := range artifactModel.Files {
+			assert.False(filepath.IsAbs(f.Name), f.Name)
+			assert.True(lib.First(afero.Exists(fs, filepath.Join(storage, artifactModel.ArtifactID, f.Name))), f.Name)
+			assert.NotEmpty(f.MimeType, f.Name)
+		}
 		// ...artifact metas updated
 		metas = models.ArtifactMetas{}
 		assert.NoError(db.Find(&metas).Error)
@@ -212,5 +233,233 @@ func TestArtifactServiceScenario1(t *testing.T) {
 		exist, err := afero.DirExists(fs, storedArtifactPath)
 		assert.NoError(err)
 		assert.False(exist)
+
+		//
+		// - Stale removed artifact is not re-created
+		//
+		assert.ErrorIs(ar.Update(artifactModel), ports.ErrRecordNotFound)
+		assert.Empty(as.checkBrokenArtifacts(limit, []*models.Artifact{artifactModel}))
+		a, err = ar.FindAll()
+		assert.NoError(err)
+		assert.Empty(a)
+		repoModel, err = rr.FindByID(testRepoID)
+		assert.NoError(err)
+		assert.Zero(repoModel.ArtifactsCount)
+	})
+}
+
+// TestArtifactServiceCreateFailure:
+//   - Create artifact with invalid creation time
+//   - The artifact record is not created
+//   - And no artifact events signaled
+func TestArtifactServiceCreateFailure(t *testing.T) {
+	assert := require.New(t)
+
+	testRepoID := "repo1"
+	input := "/var/lib/swamp/input/" + testRepoID
+	storage := "/var/lib/swamp/storage/" + testRepoID
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{input, storage} {
+		assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+	}
+
+	repos := []*models.Repo{
+		{
+			RepoID:  testRepoID,
+			Name:    "Repo1",
+			Input:   input,
+			Storage: storage,
+		},
+	}
+
+	testFakeApp(t, fs, repos, func(app *testFakeAppInternals) {
+		fs, ar, as := app.fs, app.ar, app.as
+
+		chCreated := as.bus.Sub(ports.TopicArtifactCreated)
+		defer as.bus.Unsub(chCreated)
+
+		assert.NoError(afero.WriteFile(fs, filepath.Join(input, "file1.bin"), random.ByteSlice(1024), 0o644))
+		assert.NoError(afero.WriteFile(fs, filepath.Join(input, "_createdAt.txt"), []byte("0"), 0o644))
+		checksumFileName := sealArtifact(t, fs, input)
+
+		err := as.checkInputFile(repos, fs, checksumFileName)
+		assert.Error(err)
+
+		// ...no artifact created
+		a, err := ar.FindAll()
+		assert.NoError(err)
+		assert.Empty(a)
+		// ...and not signaled
+		select {
+		case event := <-chCreated:
+			assert.Fail("unexpected event", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+// TestArtifactServiceReplicated:
+//   - Put dangling and replicated artifacts to storage
+//   - Both artifacts are created
+//   - Only replicated artifact is signaled as created
+func TestArtifactServiceReplicated(t *testing.T) {
+	assert := require.New(t)
+
+	testRepoID := "repo1"
+	input := "/var/lib/swamp/input/" + testRepoID
+	storage := "/var/lib/swamp/storage/" + testRepoID
+	fs := afero.NewMemMapFs()
+	assert.NoError(fs.MkdirAll(input, os.ModePerm))
+	for _, artifactID := range []string{"dangling", "replicated"} {
+		dir := filepath.Join(storage, artifactID)
+		assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+		assert.NoError(afero.WriteFile(fs, filepath.Join(dir, "file1.bin"), random.ByteSlice(1024), 0o644))
+		assert.NoError(afero.WriteFile(fs, filepath.Join(dir, "_createdAt.txt"), []byte(fmt.Sprint(time.Now().Unix())), 0o644))
+		sealArtifact(t, fs, dir)
+	}
+
+	repos := []*models.Repo{
+		{
+			RepoID:  testRepoID,
+			Name:    "Repo1",
+			Input:   input,
+			Storage: storage,
+		},
+	}
+
+	testFakeApp(t, fs, repos, func(app *testFakeAppInternals) {
+		ar, as := app.ar, app.as
+
+		chCreated := as.bus.Sub(ports.TopicArtifactCreated)
+		defer as.bus.Unsub(chCreated)
+
+		as.checkRepoArtifact(testRepoID, "dangling", false)
+		as.checkRepoArtifact(testRepoID, "replicated", true)
+
+		// ...both artifacts created
+		a, err := ar.FindAll()
+		assert.NoError(err)
+		assert.Len(a, 2)
+		// ...and only replicated one signaled
+		select {
+		case event := <-chCreated:
+			assert.Equal(ports.Event{testRepoID, "replicated"}, event)
+		case <-time.After(5 * time.Second):
+			assert.FailNow("no created artifact")
+		}
+		select {
+		case event := <-chCreated:
+			assert.Fail("unexpected event", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+// TestArtifactServiceMetaSchemaReject:
+//   - Creates repo with meta schema
+//   - Create artifact not conforming meta schema
+//   - Check the artifact is rejected and stays in input
+//   - Check the rejected artifact is skipped, unless its files modified
+//   - Check the rejection reason is removed with checksum file or once artifact accepted
+func TestArtifactServiceMetaSchemaReject(t *testing.T) {
+	assert := require.New(t)
+
+	testRepoID := "release"
+	input := "/var/lib/swamp/input/" + testRepoID
+	storage := "/var/lib/swamp/storage/" + testRepoID
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{input, storage} {
+		assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+	}
+
+	repos := []*models.Repo{
+		{
+			RepoID:  testRepoID,
+			Name:    "Release",
+			Input:   input,
+			Storage: storage,
+			MetaSchema: models.MetaSchema{
+				"GIT_COMMIT": {Required: true},
+				"VERSION":    {Required: true},
+			},
+		},
+	}
+
+	testFakeApp(t, fs, repos, func(app *testFakeAppInternals) {
+		fs, rr, ar, as := app.fs, app.rr, app.ar, app.as
+
+		// ...meta schema is kept by repo repository
+		repoModel, err := rr.FindByID(testRepoID)
+		assert.NoError(err)
+		assert.Equal(repos[0].MetaSchema, repoModel.MetaSchema)
+
+		chRejected := as.bus.Sub(ports.TopicRejectedRepoArtifact)
+		defer as.bus.Unsub(chRejected)
+
+		// Create input artifact with meta missing GIT_COMMIT
+		assert.NoError(afero.WriteFile(fs, filepath.Join(input, "file1.bin"), random.ByteSlice(1024), 0o644))
+		assert.NoError(afero.WriteFile(fs, filepath.Join(input, "_export.txt"), []byte("declare -x VERSION=\"1.0.0\"\n"), 0o644))
+		checksumFileName := sealArtifact(t, fs, input)
+
+		err = as.checkInputFile(repos, fs, checksumFileName)
+		assert.Error(err)
+
+		// ...rejection is signaled over event bus
+		event := <-chRejected
+		assert.Equal(testRepoID, event[0])
+		assert.Equal(checksumFileName, event[1])
+		assert.Contains(event[2], "GIT_COMMIT")
+
+		// ...input artifacts stay in input with the reason
+		assert.True(lib.First(afero.Exists(fs, filepath.Join(input, "file1.bin"))))
+		assert.True(lib.First(afero.Exists(fs, checksumFileName)))
+		reason, err := afero.ReadFile(fs, checksumFileName+".rejected")
+		assert.NoError(err)
+		assert.Contains(string(reason), "GIT_COMMIT: required key is missing")
+
+		// ...and no artifact created
+		a, err := ar.FindAll()
+		assert.NoError(err)
+		assert.Empty(a)
+
+		noRejection := func() {
+			select {
+			case event := <-chRejected:
+				assert.Fail("unexpected rejection", event)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+
+		// ...already rejected artifact is skipped
+		assert.NoError(as.checkInputFile(repos, fs, checksumFileName))
+		noRejection()
+
+		// ...but rejected again, once modified
+		modified := time.Now().Add(time.Second)
+		assert.NoError(fs.Chtimes(checksumFileName, modified, modified))
+		assert.Error(as.checkInputFile(repos, fs, checksumFileName))
+		event = <-chRejected
+		assert.Equal(checksumFileName, event[1])
+
+		// ...the reason is removed with checksum file
+		assert.NoError(fs.Remove(checksumFileName))
+		as.removeInputFile(fs, checksumFileName)
+		assert.True(lib.NoSuchFile(fs, checksumFileName+".rejected"))
+
+		// ...the reason is removed, once artifact accepted
+		checksumFileName = sealArtifact(t, fs, input)
+		assert.Error(as.checkInputFile(repos, fs, checksumFileName))
+		<-chRejected
+		assert.False(lib.NoSuchFile(fs, checksumFileName+".rejected"))
+		relaxed := *repos[0]
+		relaxed.MetaSchema = nil
+		modified = modified.Add(time.Second)
+		assert.NoError(fs.Chtimes(checksumFileName, modified, modified))
+		assert.NoError(as.checkInputFile([]*models.Repo{&relaxed}, fs, checksumFileName))
+		assert.True(lib.NoSuchFile(fs, checksumFileName+".rejected"))
+		noRejection()
+		a, err = ar.FindAll()
+		assert.NoError(err)
+		assert.Len(a, 1)
 	})
 }
@@ -29,6 +29,16 @@ func main() {
 	config.ReposConfigFileName = "lake_repos.yml"
 	config.ReposConfigFileName = lib.GetEnvDefault("LAKE_REPO_CONFIG", config.ReposConfigFileName)
 
+	// Use admin token from env LAKE_ADMIN_TOKEN
+	// The token could also be given by command line, but it is visible in process list then
+	config.AdminToken = lib.GetEnvDefault("LAKE_ADMIN_TOKEN", config.AdminToken)
+
+	// Use persistent state database from env LAKE_STATE_DB
+	config.StateDatabase = lib.GetEnvDefault("LAKE_STATE_DB", config.StateDatabase)
+
+	// Use generated archives cache directory from env LAKE_ARCHIVE_CACHE
+	config.ArchiveCacheDir = lib.GetEnvDefault("LAKE_ARCHIVE_CACHE", config.ArchiveCacheDir)
+
 	// First filesystem layer location (default is current working dir)
 	config.TopRootFileSystemPath = lib.GetEnvDefault("LAKE_ROOT", config.TopRootFileSystemPath)
 	// Second layer is this app embed fs - see mainFS
@@ -44,12 +54,30 @@ func main() {
 	flag.DurationVar(&config.TimerBrokenStart, "broken-start", config.TimerBrokenStart, "broken start timer")
 	flag.DurationVar(&config.TimerBrokenInterval, "broken-interval", config.TimerBrokenInterval, "broken check interval")
 	flag.IntVar(&config.TimerBrokenLimit, "broken-limit", config.TimerBrokenLimit, "broken check limit")
+	flag.StringVar(&config.AdminToken, "admin-token", config.AdminToken, "management api bearer token (empty disables management api)")
+	flag.StringVar(&config.StateDatabase, "state", config.StateDatabase, "persistent state sqlite database file (empty keeps state in memory)")
+	flag.StringVar(&config.ArchiveCacheDir, "archive-cache", config.ArchiveCacheDir, "generated archives cache directory (empty disables cache)")
+	flag.Var(&config.ArchiveCacheSize, "archive-cache-size", "generated archives cache max size")
 	flag.Parse()
 
 	//
 	// Create logger
 	//
 	log := slog.Default()
+
+	// Handle token subcommand, e.g. "lake -state state.db token list"
+	if flag.Arg(0) == "token" {
+		code := retNoErrorCode
+		if err := swamp.TokenCommand(log, flag.Args()[1:], os.Stdout); err != nil {
+			code = retGenericErrorCode
+			if i, ok := err.(lib.ErrorCode); ok {
+				code = i.Code()
+			}
+			log.Error("exit", slog.Int("code", code), slog.Any("err", err))
+		}
+		os.Exit(code)
+	}
+
 	log.Info("starting")
 
 	topFS, err := infra.NewLayerFileSystem(config.TopRootFileSystemPath, mainFS)
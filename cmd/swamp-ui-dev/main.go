@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"mime"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/cloudcopper/swamp"
 	"github.com/cloudcopper/swamp/adapters/http"
 	"github.com/cloudcopper/swamp/adapters/http/controllers"
 	"github.com/cloudcopper/swamp/adapters/repository"
@@ -21,6 +23,7 @@ import (
 	"github.com/cloudcopper/swamp/domain/vo"
 	"github.com/cloudcopper/swamp/infra"
 	"github.com/cloudcopper/swamp/infra/config"
+	"github.com/cloudcopper/swamp/infra/metrics"
 	"github.com/cloudcopper/swamp/lib"
 	"github.com/cloudcopper/swamp/lib/random"
 	"github.com/cloudcopper/swamp/lib/types"
@@ -98,6 +101,11 @@ func app(log *slog.Logger) error {
 	// Force development environment
 	os.Setenv("GO_ENV", "development")
 
+	// EventBus
+	eventBus := infra.NewEventBus()
+	var bus ports.EventBus = eventBus
+	defer bus.Shutdown()
+
 	// Create layered filesystem
 	fs, err := infra.NewLayerFileSystem(os.Getwd)
 	if err != nil {
@@ -115,7 +123,7 @@ func app(log *slog.Logger) error {
 	}
 	defer closeDb()
 	// Sync database
-	if err := db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFile)); err != nil {
+	if err := db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFile), new(models.ArtifactComponent)); err != nil {
 		log.Error("unable sync database", slog.Any("err", err), slog.String("driver", driver), slog.String("source", source))
 		return lib.NewErrorCode(err, errors.RetMigrateDatabaseError)
 	}
@@ -137,34 +145,70 @@ func app(log *slog.Logger) error {
 	artifactRepository = NewBadArtifactRepository(artifactRepository)
 	repositories := repository.NewRepositories(repoRepository, artifactRepository)
 
+	// Open state database
+	stateDb, closeStateDb, err := infra.NewDatabase(log, driver, infra.SourceSqliteStateInMemory)
+	if err != nil {
+		log.Error("unable to create state database", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetCreateStateDatabaseError)
+	}
+	defer closeStateDb()
+	if err := stateDb.AutoMigrate(new(models.WebhookDelivery), new(models.ApiToken)); err != nil {
+		log.Error("unable sync state database", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetMigrateStateDatabaseError)
+	}
+	webhookDeliveryRepository, err := repository.NewWebhookDeliveryRepository(stateDb)
+	if err != nil {
+		log.Error("unable create webhook delivery repository", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetCreateWebhookRepositoryError)
+	}
+	apiTokenRepository, err := repository.NewApiTokenRepository(stateDb)
+	if err != nil {
+		log.Error("unable create api token repository", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetCreateApiTokenRepositoryError)
+	}
+
 	// Perform neccesery startup operations
 	if err := startup(log, repositories); err != nil {
 		return err
 	}
+	if err := startupWebhookDeliveries(log, repositories, webhookDeliveryRepository); err != nil {
+		return err
+	}
 
+	// Create health service
+	// The ui-dev has no watcher and does not scan storage
+	healthService := swamp.NewHealthService(log, realFS, repoRepository, nil, nil, eventBus)
 	// Create router
-	router := http.NewRouter(log)
+	router := http.NewRouter(log, nil)
 	// Create render object
 	// It also loads templates
 	render := infra.NewRender(fs, "layout")
+	// Create metrics registry
+	registry := metrics.NewRegistry(func() ([]*models.Repo, error) { return repoRepository.FindAll() }, eventBus)
+	// Create download limiter
+	limiter := infra.NewLimiter(nil)
 	// Create controllers
-	frontPageController := controllers.NewFrontPageController(log, render, repositories)
-	repoContoller := controllers.NewRepoController(log, render, repoRepository)
-	artifactController := controllers.NewArtifactController(log, render, artifactRepository, fakeStorage)
-	aboutPageController := controllers.NewAboutPageController(log, render)
+	appControllers := &swamp.Controllers{
+		FrontPage: controllers.NewFrontPageController(log, render, repositories),
+		Repo:      controllers.NewRepoController(log, render, repoRepository),
+		Artifact:  controllers.NewArtifactController(log, render, artifactRepository, fakeStorage, limiter, nil, infra.NewArchiveBrowser(config.ArchiveMaxEntries, config.ArchiveEntryMaxSize), infra.NewFilePreviewer(config.PreviewMaxSize)),
+		AboutPage: controllers.NewAboutPageController(log, render),
+		Search:    controllers.NewSearchController(log, render, repositories),
+		Api:       controllers.NewApiController(log, render, repositories, fakeStorage, limiter),
+		Docs:      controllers.NewDocsController(log, render, fs),
+		Manage:    controllers.NewManageController(log, render, repositories, fakeStorage, bus),
+		Events:    controllers.NewEventsController(log, render, bus, repoRepository),
+		Webhooks:  controllers.NewWebhooksController(log, render, webhookDeliveryRepository),
+		Auth:      controllers.NewAuthController(log, render, repoRepository, apiTokenRepository, nil, lib.GetEnvDefault("SWAMP_ADMIN_TOKEN", "")),
+		Tokens:    controllers.NewTokensController(log, render, repoRepository, apiTokenRepository),
+		Metrics:   controllers.NewMetricsController(log, render, registry),
+		Health:    controllers.NewHealthController(log, render, healthService),
+		Compare:   controllers.NewCompareController(log, render, repositories, fakeStorage),
+		Feed:      controllers.NewFeedController(log, render, repositories),
+		Badge:     controllers.NewBadgeController(log, render, artifactRepository),
+	}
 	// Add routes
-	router.Get("/", frontPageController.Index)
-	router.Get("/about", aboutPageController.Index)
-	router.Get("/repo/{repoID}/artifact/{artifactID}/file/*", artifactController.DownloadSingleFile)
-	router.Get("/repo/{repoID}/artifact/{artifactID}.tar.gz", artifactController.DownloadGzip)
-	router.Get("/repo/{repoID}/artifact/{artifactID}.zip", artifactController.DownloadZip)
-	router.Get("/repo/{repoID}/artifact/{artifactID}", artifactController.Get)
-	router.Get("/repo/{repoID}", repoContoller.Get)
-	// Static file handler
-	fileServer := http.FileServer(http.FS(fs))
-	router.Handle("/static/*", fileServer)
-	// 404 handler
-	router.NotFound(frontPageController.NotFound)
+	swamp.AddRoutes(router, fs, appControllers)
 	// Create http server
 	// The router must has all routes already
 	// It will start server in separate goroutine
@@ -288,6 +332,42 @@ func startup(log ports.Logger, repos domain.Repositories) error {
 	return nil
 }
 
+// Prefill webhook deliveries history with random data
+func startupWebhookDeliveries(log ports.Logger, repos domain.Repositories, deliveries domain.WebhookDeliveryRepository) error {
+	artifacts, err := repos.Artifact().FindAll()
+	if err != nil {
+		return err
+	}
+	urls := []string{"https://chat.example.com/hooks/swamp", "http://deploy.example.com/swamp", "http://localhost:9999/broken"}
+	for n := 0; n < rv([]int{0, 50}) && len(artifacts) > 0; n++ {
+		artifact := random.Element(artifacts)
+		now := time.Now().UTC().Unix() - int64(rv([]int{0, 7 * 24 * 3600}))
+		delivery := &models.WebhookDelivery{
+			DeliveryID: ulid.Make().String(),
+			URL:        rs(urls),
+			Event:      random.Element(ports.WebhookTopics),
+			RepoID:     artifact.RepoID,
+			ArtifactID: artifact.ArtifactID,
+			Payload:    "{}",
+			State:      rs([]string{models.WebhookDeliveryDelivered, models.WebhookDeliveryDelivered, models.WebhookDeliveryPending, models.WebhookDeliveryFailed}),
+			Attempts:   rv([]int{1, 10}),
+			CreatedAt:  now,
+			NextAt:     now + int64(rv([]int{0, 3600})),
+			UpdatedAt:  now,
+		}
+		if delivery.State != models.WebhookDeliveryDelivered {
+			delivery.Status = ri([]int{0, 404, 500, 502})
+			delivery.Error = "unexpected status"
+		} else {
+			delivery.Status = 200
+		}
+		if err := deliveries.Create(delivery); err != nil {
+			log.Error("unable create webhook delivery", slog.Any("err", err))
+		}
+	}
+	return nil
+}
+
 func genRepoID(name string, l, n []int) string {
 	repoID := strings.ReplaceAll(name, " ", "_")
 	repoID = repoID[:rv(l)]
@@ -354,6 +434,12 @@ func getArtifactFiles(checksum string, createdAt int64) models.ArtifactFiles {
 			Size:  types.Size(rv([]int{128, 150000000})),
 			State: vo.ArtifactState(random.Element([]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1})),
 		}
+		file.MimeType = mime.TypeByExtension(filepath.Ext(file.Name))
+		if strings.HasSuffix(file.Name, ".bin") {
+			file.MimeType = "application/x-executable"
+			file.Arch = rs([]string{"x86_64", "aarch64", "arm", "riscv"})
+			file.BuildID = genChecksum()[:40]
+		}
 		countGeneratedFile++
 		if countGeneratedFile%(numFiles[1]/6) == 0 {
 			// break file name
@@ -419,7 +505,7 @@ func (*FakeStorage) NewArtifact(ports.FS, string, []string, string, models.Artif
 	panic("not expected to be called atm!!!")
 }
 func (*FakeStorage) RemoveArtifact(string, models.ArtifactID) error {
-	panic("not expected to be called atm!!!")
+	return nil
 }
 func (s *FakeStorage) OpenFile(storage, artifactID, filename string) (ports.File, error) {
 	if strings.Contains(artifactID, "bad") {
@@ -500,9 +586,9 @@ func (b *badArtifactRepository) Update(model *models.Artifact) error {
 func (b *badArtifactRepository) Delete(model *models.Artifact) error {
 	return b.repo.Delete(model)
 }
-func (b *badArtifactRepository) FindAll() ([]*models.Artifact, error) {
+func (b *badArtifactRepository) FindAll(flags ...interface{}) ([]*models.Artifact, error) {
 	b.lastFindByID = ""
-	return b.repo.FindAll()
+	return b.repo.FindAll(flags...)
 }
 func (b *badArtifactRepository) FindAllTimeExpired(now int64) ([]*models.Artifact, error) {
 	return b.repo.FindAllTimeExpired(now)
@@ -534,3 +620,9 @@ func (b *badArtifactRepository) FindByID(repoID models.RepoID, artifactID models
 func (b *badArtifactRepository) IterateAll(callback func(*models.Artifact) (bool, error)) error {
 	return b.repo.IterateAll(callback)
 }
+func (b *badArtifactRepository) FindAllFiles(flags ...interface{}) ([]*models.ArtifactFile, error) {
+	return b.repo.FindAllFiles(flags...)
+}
+func (b *badArtifactRepository) FindAllComponents(flags ...interface{}) ([]*models.ArtifactComponent, error) {
+	return b.repo.FindAllComponents(flags...)
+}
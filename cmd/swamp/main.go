@@ -5,6 +5,7 @@ import (
 	"flag"
 	"log/slog"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudcopper/swamp"
 	"github.com/cloudcopper/swamp/infra"
@@ -28,6 +29,20 @@ func main() {
 	// Note the config file might be embedded!!!
 	config.ReposConfigFileName = lib.GetEnvDefault("SWAMP_REPO_CONFIG", config.ReposConfigFileName)
 
+	// Use admin token from env SWAMP_ADMIN_TOKEN
+	// The token could also be given by command line, but it is visible in process list then
+	config.AdminToken = lib.GetEnvDefault("SWAMP_ADMIN_TOKEN", config.AdminToken)
+
+	// Use persistent state database from env SWAMP_STATE_DB
+	config.StateDatabase = lib.GetEnvDefault("SWAMP_STATE_DB", config.StateDatabase)
+
+	// Use state directory from env SWAMP_STATE_DIR
+	config.StateDir = lib.GetEnvDefault("SWAMP_STATE_DIR", config.StateDir)
+
+	// Use generated archives cache directory from env SWAMP_ARCHIVE_CACHE
+	// or archives in state directory
+	config.ArchiveCacheDir = lib.GetEnvDefault("SWAMP_ARCHIVE_CACHE", filepath.Join(config.StateDir, "archives"))
+
 	// First filesystem layer location (default is current working dir)
 	config.TopRootFileSystemPath = lib.GetEnvDefault("SWAMP_ROOT", config.TopRootFileSystemPath)
 	// Second layer is this app embed fs - see mainFS
@@ -43,12 +58,35 @@ func main() {
 	flag.DurationVar(&config.TimerBrokenStart, "broken-start", config.TimerBrokenStart, "broken start timer")
 	flag.DurationVar(&config.TimerBrokenInterval, "broken-interval", config.TimerBrokenInterval, "broken check interval")
 	flag.IntVar(&config.TimerBrokenLimit, "broken-limit", config.TimerBrokenLimit, "broken check limit")
+	flag.StringVar(&config.AdminToken, "admin-token", config.AdminToken, "management api bearer token (empty disables management api)")
+	flag.StringVar(&config.StateDatabase, "state", config.StateDatabase, "persistent state sqlite database file (empty keeps state in memory)")
+	flag.StringVar(&config.StateDir, "state-dir", config.StateDir, "private directory of state kept on disk")
+	flag.StringVar(&config.ArchiveCacheDir, "archive-cache", config.ArchiveCacheDir, "generated archives cache directory (empty disables cache)")
+	flag.Var(&config.ArchiveCacheSize, "archive-cache-size", "generated archives cache max size")
 	flag.Parse()
+	// The archives cache follows state directory, unless given explicitly
+	if _, ok := os.LookupEnv("SWAMP_ARCHIVE_CACHE"); !ok && !isFlagSet("archive-cache") {
+		config.ArchiveCacheDir = filepath.Join(config.StateDir, "archives")
+	}
 
 	//
 	// Create logger
 	//
 	log := slog.Default()
+
+	// Handle token subcommand, e.g. "swamp -state state.db token list"
+	if flag.Arg(0) == "token" {
+		code := retNoErrorCode
+		if err := swamp.TokenCommand(log, flag.Args()[1:], os.Stdout); err != nil {
+			code = retGenericErrorCode
+			if i, ok := err.(lib.ErrorCode); ok {
+				code = i.Code()
+			}
+			log.Error("exit", slog.Int("code", code), slog.Any("err", err))
+		}
+		os.Exit(code)
+	}
+
 	log.Info("starting")
 
 	topFS, err := infra.NewLayerFileSystem(config.TopRootFileSystemPath, mainFS)
@@ -70,3 +108,12 @@ func main() {
 
 	os.Exit(code)
 }
+
+// The isFlagSet returns true, if the flag given by command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "github.com/cloudcopper/swamp/domain/models"
+
+type ApiTokenRepository interface {
+	Create(model *models.ApiToken) error
+	FindAll(flags ...interface{}) ([]*models.ApiToken, error)
+	FindByID(id models.ApiTokenID) (*models.ApiToken, error)
+	FindByHash(hash string) (*models.ApiToken, error)
+	Touch(id models.ApiTokenID, now int64) error
+	Revoke(id models.ApiTokenID, now int64) error
+}
@@ -6,11 +6,13 @@ type ArtifactRepository interface {
 	Create(model *models.Artifact) error
 	Update(model *models.Artifact) error
 	Delete(model *models.Artifact) error
-	FindAll() ([]*models.Artifact, error)
+	FindAll(flags ...interface{}) ([]*models.Artifact, error)
 	FindAllTimeExpired(now int64) ([]*models.Artifact, error)
 	FindAllStatusExpired(flags ...interface{}) ([]*models.Artifact, error)
 	FindAllStatusNotBroken() ([]*models.Artifact, error)
 	FindAllStatusBroken(flags ...interface{}) ([]*models.Artifact, error)
 	FindByID(repoID models.RepoID, artifactID models.ArtifactID, flags ...interface{}) (*models.Artifact, error)
 	IterateAll(func(*models.Artifact) (bool, error)) error
+	FindAllFiles(flags ...interface{}) ([]*models.ArtifactFile, error)
+	FindAllComponents(flags ...interface{}) ([]*models.ArtifactComponent, error)
 }
@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/cloudcopper/swamp/lib"
 )
@@ -14,6 +15,7 @@ const ErrUnsecureFileName = lib.Error("unsecure file name")
 const ErrArtifactIsBroken = lib.Error("artifact is broken")
 const ErrIncorrectMetaID = lib.Error("incorrect meta id")
 const ErrIncorrectFileID = lib.Error("incorrect file id")
+const ErrIncorrectComponentID = lib.Error("incorrect component id")
 const ErrNotMatchRepoInput = lib.Error("not match repo input")
 
 type ErrArtifactAlreadyExists struct {
@@ -24,4 +26,13 @@ func (e ErrArtifactAlreadyExists) Error() string {
 	return fmt.Sprintf("artifact already exists %v", e.Path)
 }
 
+type ErrMetaSchemaViolation struct {
+	Violations []string
+}
+
+func (e ErrMetaSchemaViolation) Error() string {
+	return "meta schema violation: " + strings.Join(e.Violations, "; ")
+}
+
 var Is = errors.Is
+var As = errors.As
@@ -11,6 +11,12 @@ const (
 	RetCreateArtifactStorageError    = 15
 	RetCreateChecksumServiceError    = 16
 	RetCreateInputWatcherError       = 17
+	RetCreateStateDatabaseError      = 18
+	RetMigrateStateDatabaseError     = 19
 	RetCreateRepoRecordError         = 20
+	RetCreateWebhookRepositoryError  = 21
+	RetCreateApiTokenRepositoryError = 22
+	RetTokenCommandError             = 23
+	RetCreateArchiveCacheError       = 24
 	RetCreateWebServerError          = 40
 )
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/oklog/ulid/v2"
+)
+
+type ApiTokenID = string
+
+// ApiTokenPrefix is the prefix of all api token secrets,
+// so they are easy to recognize (e.g. by secret scanners)
+const ApiTokenPrefix = "swamp_"
+
+// The apiTokenScopes maps token scope names to repo permission
+var apiTokenScopes = map[string]vo.Permission{
+	"read":   vo.PermissionRead,
+	"write":  vo.PermissionWrite,
+	"delete": vo.PermissionAdmin,
+}
+
+// ApiToken is the scoped machine credential (e.g. for CI).
+// Only sha256 hash of the token secret is stored, so the secret is shown once at creation.
+// The token is limited by its scope to single repo (or all repos)
+// and it is not affected by the repos access.
+type ApiToken struct {
+	TokenID    ApiTokenID    `gorm:"primaryKey;not null"`
+	Name       string        `gorm:"index;not null"`
+	Hash       string        `gorm:"uniqueIndex;not null"` // hex sha256 of secret
+	Hint       string        `gorm:"string"`               // first chars of secret
+	RepoID     RepoID        `gorm:"index"`                // empty for all repos
+	Scope      vo.Permission `gorm:"int64"`
+	CreatedAt  int64         `gorm:"index"`
+	ExpiresAt  int64         `gorm:"int64"` // unix time or 0 for never
+	LastUsedAt int64         `gorm:"int64"`
+	RevokedAt  int64         `gorm:"int64"`
+}
+
+// NewApiToken returns new token and its secret
+func NewApiToken(name string, repoID RepoID, scope vo.Permission, now, expiresAt int64) (*ApiToken, string, error) {
+	if err := checkPrincipalName(name); err != nil {
+		return nil, "", fmt.Errorf("token %v: %w", name, err)
+	}
+	if scope < vo.PermissionRead || scope > vo.PermissionAdmin {
+		return nil, "", fmt.Errorf("invalid token scope %v", scope)
+	}
+	if expiresAt != 0 && expiresAt <= now {
+		return nil, "", fmt.Errorf("token expires in the past")
+	}
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := ApiTokenPrefix + hex.EncodeToString(b)
+	token := &ApiToken{
+		TokenID:   ulid.Make().String(),
+		Name:      name,
+		Hash:      HashApiToken(secret),
+		Hint:      secret[:len(ApiTokenPrefix)+4],
+		RepoID:    repoID,
+		Scope:     scope,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	return token, secret, nil
+}
+
+// HashApiToken returns the hash of token secret as stored
+func HashApiToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseApiTokenScope parses scope name (read, write or delete)
+func ParseApiTokenScope(s string) (vo.Permission, error) {
+	scope, ok := apiTokenScopes[s]
+	if !ok {
+		return vo.PermissionNone, fmt.Errorf("unknown token scope %q", s)
+	}
+	return scope, nil
+}
+
+// ScopeName returns the scope name of token
+func (t *ApiToken) ScopeName() string {
+	for name, scope := range apiTokenScopes {
+		if scope == t.Scope {
+			return name
+		}
+	}
+	return t.Scope.String()
+}
+
+// IsValid returns true, if token is neither revoked nor expired
+func (t *ApiToken) IsValid(now int64) bool {
+	return t.RevokedAt == 0 && (t.ExpiresAt == 0 || now < t.ExpiresAt)
+}
+
+// Permission returns the token permission to the repo
+func (t *ApiToken) Permission(repoID RepoID) vo.Permission {
+	if t.RepoID != "" && t.RepoID != repoID {
+		return vo.PermissionNone
+	}
+	return t.Scope
+}
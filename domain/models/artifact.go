@@ -17,16 +17,17 @@ const EmptyArtifactID = ArtifactID("")
 type Artifacts []*Artifact
 
 type Artifact struct {
-	RepoID     RepoID           `gorm:"primaryKey;not null" validate:"required,validid"`
-	ArtifactID ArtifactID       `gorm:"primaryKey;not null" validate:"required,validid"`
-	Storage    string           `gorm:"not null" validate:"required,min=3,dir,abspath"`
-	Size       types.Size       `gorm:"not null" validate:"required,gt=0"`
-	State      vo.ArtifactState `gorm:"int" validate:"min=0,max=3"`
-	CreatedAt  int64            `gorm:"index;column:created_at" validate:"required,gt=0"` // UTC Unix time of creation - equal to ```date +%s```
-	ExpiredAt  int64            `gorm:"index;column:expired_at" validate:"required,gt=0"` // UTC Unix time at which the artifacts expires
-	Checksum   string           `gorm:"not null" validate:"required,min=8"`
-	Meta       ArtifactMetas    `gorm:"foreignKey:RepoID,ArtifactID;constraint:OnDelete:CASCADE;" validate:"-"`
-	Files      ArtifactFiles    `gorm:"foreignKey:RepoID,ArtifactID;constraint:OnDelete:CASCADE;" valudate:"-"`
+	RepoID     RepoID             `gorm:"primaryKey;not null" validate:"required,validid"`
+	ArtifactID ArtifactID         `gorm:"primaryKey;not null" validate:"required,validid"`
+	Storage    string             `gorm:"not null" validate:"required,min=3,dir,abspath"`
+	Size       types.Size         `gorm:"not null" validate:"required,gt=0"`
+	State      vo.ArtifactState   `gorm:"int" validate:"min=0,max=3"`
+	CreatedAt  int64              `gorm:"index;column:created_at" validate:"required,gt=0"` // UTC Unix time of creation - equal to ```date +%s```
+	ExpiredAt  int64              `gorm:"index;column:expired_at" validate:"required,gt=0"` // UTC Unix time at which the artifacts expires
+	Checksum   string             `gorm:"not null" validate:"required,min=8"`
+	Meta       ArtifactMetas      `gorm:"foreignKey:RepoID,ArtifactID;constraint:OnDelete:CASCADE;" validate:"-"`
+	Files      ArtifactFiles      `gorm:"foreignKey:RepoID,ArtifactID;constraint:OnDelete:CASCADE;" valudate:"-"`
+	Components ArtifactComponents `gorm:"foreignKey:RepoID,ArtifactID;constraint:OnDelete:CASCADE;" validate:"-"`
 }
 
 func (model *Artifact) Validate(val *validator.Validate) error {
@@ -75,6 +76,20 @@ func (model *Artifact) Validate(val *validator.Validate) error {
 			model.State |= vo.ArtifactIsBroken
 		}
 	}
+	for _, c := range model.Components {
+		if c.RepoID == "" {
+			c.RepoID = model.RepoID
+		}
+		if c.RepoID != model.RepoID {
+			return errors.ErrIncorrectComponentID
+		}
+		if c.ArtifactID == "" {
+			c.ArtifactID = model.ArtifactID
+		}
+		if c.ArtifactID != model.ArtifactID {
+			return errors.ErrIncorrectComponentID
+		}
+	}
 
 	return nil
 }
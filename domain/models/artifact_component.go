@@ -0,0 +1,16 @@
+package models
+
+type ArtifactComponents []*ArtifactComponent
+
+// ArtifactComponent is a software component listed
+// by SBOM (SPDX, CycloneDX) file of the artifact
+type ArtifactComponent struct {
+	RepoID     RepoID     `gorm:"primaryKey;not null" validate:"required,validid"`
+	ArtifactID ArtifactID `gorm:"primaryKey;not null" validate:"required,validid"`
+	File       string     `gorm:"primaryKey;not null" validate:"required"` // SBOM file name within artifact
+	Index      int        `gorm:"primaryKey;autoIncrement:false"`          // position within SBOM file
+	Name       string     `gorm:"index;not null" validate:"required"`
+	Version    string
+	Purl       string
+	License    string
+}
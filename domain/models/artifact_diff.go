@@ -0,0 +1,129 @@
+package models
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cloudcopper/swamp/lib/types"
+)
+
+type DiffStatus = string
+
+const (
+	DiffAdded   = DiffStatus("added")
+	DiffRemoved = DiffStatus("removed")
+	DiffChanged = DiffStatus("changed")
+)
+
+// ArtifactDiff is the difference of artifact To from artifact From
+type ArtifactDiff struct {
+	From      *Artifact
+	To        *Artifact
+	SizeDelta int64
+	Files     []*FileDiff // added, removed and changed files sorted by name
+	Unchanged int         // number of unchanged files
+	Meta      []*MetaDiff // added, removed and changed meta sorted by key
+}
+
+type FileDiff struct {
+	Name       string
+	Status     DiffStatus
+	FromSize   types.Size
+	ToSize     types.Size
+	SizeDelta  int64
+	FromSha256 string // empty if unknown or removed
+	ToSha256   string // empty if unknown or added
+}
+
+type MetaDiff struct {
+	Key       string
+	Status    DiffStatus
+	FromValue string
+	ToValue   string
+}
+
+// NewArtifactDiff compares artifacts files and meta.
+// The file checksums are given by manifests (see ManifestFiles),
+// so the file of same size is changed, if both checksums known and differ.
+// The hidden files and the artifact checksum file (named by checksum) are not compared.
+func NewArtifactDiff(from, to *Artifact, fromManifest, toManifest ManifestFiles) *ArtifactDiff {
+	diff := &ArtifactDiff{
+		From:      from,
+		To:        to,
+		SizeDelta: int64(to.Size) - int64(from.Size),
+		Files:     []*FileDiff{},
+		Meta:      []*MetaDiff{},
+	}
+
+	fromFiles, toFiles := diffFiles(from.Files), diffFiles(to.Files)
+	for name, f := range fromFiles {
+		if _, ok := toFiles[name]; !ok {
+			diff.Files = append(diff.Files, &FileDiff{Name: name, Status: DiffRemoved, FromSize: f.Size, SizeDelta: -int64(f.Size), FromSha256: fromManifest.Sha256(name)})
+		}
+	}
+	for name, t := range toFiles {
+		f, ok := fromFiles[name]
+		if !ok {
+			diff.Files = append(diff.Files, &FileDiff{Name: name, Status: DiffAdded, ToSize: t.Size, SizeDelta: int64(t.Size), ToSha256: toManifest.Sha256(name)})
+			continue
+		}
+		fd := &FileDiff{Name: name, Status: DiffChanged, FromSize: f.Size, ToSize: t.Size, SizeDelta: int64(t.Size) - int64(f.Size), FromSha256: fromManifest.Sha256(name), ToSha256: toManifest.Sha256(name)}
+		if fd.SizeDelta == 0 && (fd.FromSha256 == "" || fd.ToSha256 == "" || fd.FromSha256 == fd.ToSha256) {
+			diff.Unchanged++
+			continue
+		}
+		diff.Files = append(diff.Files, fd)
+	}
+	slices.SortFunc(diff.Files, func(a, b *FileDiff) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	fromMeta, toMeta := diffMeta(from.Meta), diffMeta(to.Meta)
+	for key, value := range fromMeta {
+		if _, ok := toMeta[key]; !ok {
+			diff.Meta = append(diff.Meta, &MetaDiff{Key: key, Status: DiffRemoved, FromValue: value})
+		}
+	}
+	for key, value := range toMeta {
+		fromValue, ok := fromMeta[key]
+		switch {
+		case !ok:
+			diff.Meta = append(diff.Meta, &MetaDiff{Key: key, Status: DiffAdded, ToValue: value})
+		case fromValue != value:
+			diff.Meta = append(diff.Meta, &MetaDiff{Key: key, Status: DiffChanged, FromValue: fromValue, ToValue: value})
+		}
+	}
+	slices.SortFunc(diff.Meta, func(a, b *MetaDiff) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return diff
+}
+
+// IsEmpty returns true, if artifacts have same files and meta
+func (diff *ArtifactDiff) IsEmpty() bool {
+	return len(diff.Files) == 0 && len(diff.Meta) == 0
+}
+
+// The diffFiles returns compared files by slash separated name
+func diffFiles(files ArtifactFiles) map[string]*ArtifactFile {
+	m := map[string]*ArtifactFile{}
+	for _, f := range files {
+		name := filepath.ToSlash(f.Name)
+		base := filepath.Base(name)
+		if base[0] == '_' || base[0] == '.' || strings.HasSuffix(base, ".sha256sum") {
+			continue
+		}
+		m[name] = f
+	}
+	return m
+}
+
+func diffMeta(metas ArtifactMetas) map[string]string {
+	m := map[string]string{}
+	for _, meta := range metas {
+		m[meta.Key] = meta.Value
+	}
+	return m
+}
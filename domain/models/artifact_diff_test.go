@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArtifactDiff(t *testing.T) {
+	assert := require.New(t)
+	from := &Artifact{RepoID: "nightly", ArtifactID: "1", Size: 100,
+		Files: ArtifactFiles{
+			{Name: "app.elf", Size: 50},
+			{Name: "app.map", Size: 20},
+			{Name: "readme.txt", Size: 10},
+			{Name: "notes.txt", Size: 10},
+			{Name: "_createdAt.txt", Size: 10},
+			{Name: "aaaa.sha256sum", Size: 100},
+		},
+		Meta: ArtifactMetas{
+			{Key: "VERSION", Value: "1.0"},
+			{Key: "BRANCH", Value: "main"},
+			{Key: "OLD", Value: "x"},
+		},
+	}
+	to := &Artifact{RepoID: "nightly", ArtifactID: "2", Size: 130,
+		Files: ArtifactFiles{
+			{Name: "app.elf", Size: 60},
+			{Name: "readme.txt", Size: 10},
+			{Name: "notes.txt", Size: 10},
+			{Name: "docs/index.html", Size: 20},
+			{Name: "_createdAt.txt", Size: 10},
+			{Name: "bbbb.sha256sum", Size: 100},
+		},
+		Meta: ArtifactMetas{
+			{Key: "VERSION", Value: "1.1"},
+			{Key: "BRANCH", Value: "main"},
+			{Key: "NEW", Value: "y"},
+		},
+	}
+	fromManifest := ManifestFiles{{Name: "readme.txt", Sha256: "r1"}, {Name: "notes.txt", Sha256: "n1"}, {Name: "app.map", Sha256: "m1"}}
+	toManifest := ManifestFiles{{Name: "readme.txt", Sha256: "r2"}, {Name: "notes.txt", Sha256: "n1"}, {Name: "docs/index.html", Sha256: "d2"}}
+
+	diff := NewArtifactDiff(from, to, fromManifest, toManifest)
+	assert.Equal(int64(30), diff.SizeDelta)
+	assert.Equal([]*FileDiff{
+		{Name: "app.elf", Status: DiffChanged, FromSize: 50, ToSize: 60, SizeDelta: 10},
+		{Name: "app.map", Status: DiffRemoved, FromSize: 20, SizeDelta: -20, FromSha256: "m1"},
+		{Name: "docs/index.html", Status: DiffAdded, ToSize: 20, SizeDelta: 20, ToSha256: "d2"},
+		{Name: "readme.txt", Status: DiffChanged, FromSize: 10, ToSize: 10, FromSha256: "r1", ToSha256: "r2"},
+	}, diff.Files)
+	assert.Equal(1, diff.Unchanged)
+	assert.Equal([]*MetaDiff{
+		{Key: "NEW", Status: DiffAdded, ToValue: "y"},
+		{Key: "OLD", Status: DiffRemoved, FromValue: "x"},
+		{Key: "VERSION", Status: DiffChanged, FromValue: "1.0", ToValue: "1.1"},
+	}, diff.Meta)
+	assert.False(diff.IsEmpty())
+
+	// Same artifact
+	diff = NewArtifactDiff(to, to, toManifest, toManifest)
+	assert.True(diff.IsEmpty())
+	assert.Equal(4, diff.Unchanged)
+}
@@ -16,6 +16,20 @@ type ArtifactFile struct {
 	Name       string           `gorm:"primaryKey;not null" validate:"required"`
 	Size       types.Size       `validate:"required,ge=0"`
 	State      vo.ArtifactState `validate:"min=0,max=1"` // OK(0) or Broken(1)
+	MimeType   string           // detected by content
+	Arch       string           // architecture of executable (ELF/PE)
+	BuildID    string           `gorm:"index"` // build-id of executable (ELF/PE)
+	Entries    int              // number of entries in archive (zip/tar)
+	BaseName   string           `gorm:"index"` // lower case base name for search (see Index)
+	Ext        string           `gorm:"index"` // lower case extension of base name for search (see Index)
+}
+
+// Index sets search fields of files derived from their names
+func (files ArtifactFiles) Index() {
+	for _, f := range files {
+		f.BaseName = strings.ToLower(filepath.Base(f.Name))
+		f.Ext = filepath.Ext(f.BaseName)
+	}
 }
 
 func (files ArtifactFiles) Sort(path string) {
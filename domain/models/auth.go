@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/lib"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The special principal names of Access
+const (
+	PrincipalAnonymous     = "anonymous" // not authenticated requests
+	PrincipalAuthenticated = "*"         // any authenticated user or token
+)
+
+// Auth defines local users and static api tokens.
+// It is declared globally (_auth) in the repos config:
+//
+//	_auth:
+//	  users:
+//	    alice: { password: "$2y$10$...", admin: true } # bcrypt hash, e.g. by htpasswd -nB alice
+//	    bob:   { password: "$2y$10$..." }
+//	  tokens:
+//	    ci: { token: "${CI_TOKEN}" }
+//	  access: # default access of repos without own access
+//	    "*": read
+//
+// The repos access is declared per repo:
+//
+//	access:
+//	  bob: write
+//	  ci: admin
+//	  anonymous: read
+type Auth struct {
+	Users  map[string]*AuthUser  `yaml:"users"`
+	Tokens map[string]*AuthToken `yaml:"tokens"`
+	Access Access                `yaml:"access"`
+}
+
+type AuthUser struct {
+	Password string `yaml:"password"` // bcrypt hash
+	Admin    bool   `yaml:"admin"`    // access to all repos and admin pages
+}
+
+type AuthToken struct {
+	Token string `yaml:"token"`
+	Admin bool   `yaml:"admin"` // access to all repos and admin pages
+}
+
+// Compile checks the auth is valid
+func (auth *Auth) Compile() error {
+	for _, name := range lib.SortedKeys(auth.Users) {
+		user := auth.Users[name]
+		if err := checkPrincipalName(name); err != nil {
+			return fmt.Errorf("user %v: %w", name, err)
+		}
+		if user == nil {
+			return fmt.Errorf("user %v: no password", name)
+		}
+		if _, err := bcrypt.Cost([]byte(user.Password)); err != nil {
+			return fmt.Errorf("user %v: password is not bcrypt hash: %w", name, err)
+		}
+	}
+	tokens := map[string]string{}
+	for _, name := range lib.SortedKeys(auth.Tokens) {
+		token := auth.Tokens[name]
+		if err := checkPrincipalName(name); err != nil {
+			return fmt.Errorf("token %v: %w", name, err)
+		}
+		if _, ok := auth.Users[name]; ok {
+			return fmt.Errorf("token %v: same name as user", name)
+		}
+		if token == nil || token.Token == "" {
+			return fmt.Errorf("token %v: empty token", name)
+		}
+		if other, ok := tokens[token.Token]; ok {
+			return fmt.Errorf("token %v: same token as %v", name, other)
+		}
+		tokens[token.Token] = name
+	}
+	return nil
+}
+
+func checkPrincipalName(name string) error {
+	if name == PrincipalAnonymous || name == PrincipalAuthenticated {
+		return fmt.Errorf("reserved name")
+	}
+	if name == "" {
+		return fmt.Errorf("empty name")
+	}
+	return nil
+}
+
+// Access maps principal names to the repo permission.
+// See PrincipalAnonymous and PrincipalAuthenticated for special names.
+// The nil access is public repo (read permission for everyone).
+type Access map[string]vo.Permission
+
+// Permission returns the permission of principal
+func (access Access) Permission(p *Principal) vo.Permission {
+	if p.Admin {
+		return vo.PermissionAdmin
+	}
+	if access == nil {
+		return vo.PermissionRead
+	}
+	if p.IsAnonymous() {
+		return access[PrincipalAnonymous]
+	}
+	return max(access[p.Name], access[PrincipalAuthenticated], access[PrincipalAnonymous])
+}
+
+// Principal is the authenticated user or token (or anonymous) making the request
+type Principal struct {
+	Name  string
+	Admin bool
+	Token *ApiToken // scoped api token, if authenticated by one
+}
+
+var Anonymous = &Principal{Name: PrincipalAnonymous}
+
+func (p *Principal) IsAnonymous() bool {
+	return p.Name == PrincipalAnonymous
+}
+
+// Can returns true, if principal has at least given permission to the repo
+// The scoped api token is limited by its scope only.
+func (p *Principal) Can(repo *Repo, perm vo.Permission) bool {
+	if p.Token != nil {
+		return p.Token.Permission(repo.RepoID) >= perm
+	}
+	return repo.Access.Permission(p) >= perm
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessPermission(t *testing.T) {
+	access := Access{
+		PrincipalAnonymous:     vo.PermissionRead,
+		PrincipalAuthenticated: vo.PermissionRead,
+		"bob":                  vo.PermissionWrite,
+	}
+	private := Access{"bob": vo.PermissionAdmin}
+
+	testCases := []struct {
+		name      string
+		access    Access
+		principal *Principal
+		expected  vo.Permission
+	}{
+		{"public anonymous", nil, Anonymous, vo.PermissionRead},
+		{"public user", nil, &Principal{Name: "alice"}, vo.PermissionRead},
+		{"public admin", nil, &Principal{Name: "alice", Admin: true}, vo.PermissionAdmin},
+		{"anonymous", access, Anonymous, vo.PermissionRead},
+		{"authenticated", access, &Principal{Name: "alice"}, vo.PermissionRead},
+		{"named", access, &Principal{Name: "bob"}, vo.PermissionWrite},
+		{"private anonymous", private, Anonymous, vo.PermissionNone},
+		{"private user", private, &Principal{Name: "alice"}, vo.PermissionNone},
+		{"private named", private, &Principal{Name: "bob"}, vo.PermissionAdmin},
+		{"private admin", private, &Principal{Name: "alice", Admin: true}, vo.PermissionAdmin},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.access.Permission(tc.principal))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/cloudcopper/swamp/lib/types"
+)
+
+// Limits defines download limits per client (user, token or ip).
+// It is declared globally (_limits) and per repo in the repos config:
+//
+//	_limits:
+//	  rate: 1          # downloads per second
+//	  burst: 10        # downloads at once
+//	  bandwidth: 10MB  # bytes per second
+//	  archives: 4      # archives generated at once by all repos
+//	  trusted_proxies: # proxies allowed to forward client ip (global only)
+//	    - 10.0.0.1
+//	    - 192.168.0.0/24
+//	firmware:
+//	  limits:
+//	    bandwidth: 1MB
+//	    archives: 1    # archives generated at once by the repo
+//
+// The zero value is no limit.
+// The global limits apply to the client across all repos,
+// the repo limits apply on top of them per repo.
+// The repo limits not set are inherited from global limits.
+// The client ip is taken from X-Forwarded-For/X-Real-IP of trusted proxies only,
+// otherwise it is the peer address.
+type Limits struct {
+	Rate           float64    `yaml:"rate" json:"rate,omitempty"`
+	Burst          int        `yaml:"burst" json:"burst,omitempty"`
+	Bandwidth      types.Size `yaml:"bandwidth" json:"bandwidth,omitempty"`
+	Archives       int        `yaml:"archives" json:"archives,omitempty"`
+	TrustedProxies []string   `yaml:"trusted_proxies" json:"trusted_proxies,omitempty"` // ips or cidrs
+	proxies        []netip.Prefix
+}
+
+// Compile checks the limits are valid
+func (l *Limits) Compile() error {
+	if l == nil {
+		return nil
+	}
+	if l.Rate < 0 || l.Burst < 0 || l.Bandwidth < 0 || l.Archives < 0 {
+		return fmt.Errorf("negative limit")
+	}
+	l.proxies = nil
+	for _, proxy := range l.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return fmt.Errorf("trusted proxy %v: %w", proxy, err)
+			}
+			l.proxies = append(l.proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %v: %w", proxy, err)
+		}
+		l.proxies = append(l.proxies, prefix.Masked())
+	}
+	return nil
+}
+
+// IsTrustedProxy returns true, if the ip is one of compiled trusted proxies
+func (l *Limits) IsTrustedProxy(ip string) bool {
+	if l == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range l.proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge returns limits with not set values taken from defaults
+func (l *Limits) Merge(defaults *Limits) *Limits {
+	switch {
+	case l == nil && defaults == nil:
+		return nil
+	case l == nil:
+		l = &Limits{}
+	case defaults == nil:
+		defaults = &Limits{}
+	}
+	v := *l
+	if v.Rate == 0 {
+		v.Rate = defaults.Rate
+	}
+	if v.Burst == 0 {
+		v.Burst = defaults.Burst
+	}
+	if v.Bandwidth == 0 {
+		v.Bandwidth = defaults.Bandwidth
+	}
+	if v.Archives == 0 {
+		v.Archives = defaults.Archives
+	}
+	// The trusted proxies are global only
+	v.TrustedProxies, v.proxies = nil, nil
+	return &v
+}
@@ -0,0 +1,23 @@
+package models
+
+import "github.com/cloudcopper/swamp/lib/types"
+
+// ManifestFiles is the verification manifest of artifact
+type ManifestFiles []*ManifestFile
+
+// ManifestFile is the file of artifact with its checksum given by artifact checksum file
+type ManifestFile struct {
+	Name   string
+	Size   types.Size
+	Sha256 string // hex sha256 of file
+}
+
+// Sha256 returns checksum of file by its slash separated name, or empty string if unknown
+func (manifest ManifestFiles) Sha256(name string) string {
+	for _, m := range manifest {
+		if m.Name == name {
+			return m.Sha256
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cloudcopper/swamp/domain/errors"
+	"github.com/cloudcopper/swamp/lib"
+)
+
+// MetaSchema defines constraints for artifact meta keys.
+// It is declared per repo in the repos config:
+//
+//	meta_schema:
+//	  GIT_COMMIT: { required: true, regex: "^[0-9a-f]{40}$" }
+//	  VERSION:    { required: true }
+//	  BUILD_TYPE: { enum: [release, debug] }
+//	  BUILD_ID:   { type: int }
+type MetaSchema map[string]*MetaRule
+
+type MetaRule struct {
+	Required bool           `yaml:"required" json:"required,omitempty"`
+	Type     string         `yaml:"type" json:"type,omitempty"` // string(default), int, float, bool
+	Regex    string         `yaml:"regex" json:"regex,omitempty"`
+	Enum     []string       `yaml:"enum" json:"enum,omitempty"`
+	re       *regexp.Regexp // compiled regex; nil until compiled
+}
+
+var metaRuleTypes = []string{"", "string", "int", "float", "bool"}
+
+// Compile checks the schema itself is valid.
+func (schema MetaSchema) Compile() error {
+	for _, key := range lib.SortedKeys(schema) {
+		rule := schema[key]
+		if rule == nil {
+			continue
+		}
+		if !slices.Contains(metaRuleTypes, rule.Type) {
+			return fmt.Errorf("meta schema key %v: unknown type %q", key, rule.Type)
+		}
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("meta schema key %v: %w", key, err)
+		}
+		rule.re = re
+	}
+	return nil
+}
+
+// Validate checks the meta against schema.
+// It returns error listing all violations or nil.
+func (schema MetaSchema) Validate(meta ArtifactMetas) error {
+	values := map[string]string{}
+	for _, m := range meta {
+		values[m.Key] = m.Value
+	}
+
+	violations := []string{}
+	for _, key := range lib.SortedKeys(schema) {
+		rule := schema[key]
+		if rule == nil {
+			continue
+		}
+		value, ok := values[key]
+		if !ok {
+			if rule.Required {
+				violations = append(violations, fmt.Sprintf("%v: required key is missing", key))
+			}
+			continue
+		}
+		if err := rule.check(value); err != nil {
+			violations = append(violations, fmt.Sprintf("%v: %v", key, err))
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return errors.ErrMetaSchemaViolation{Violations: violations}
+}
+
+func (rule *MetaRule) check(value string) error {
+	var err error
+	switch rule.Type {
+	case "int":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("value %q is not %v", value, rule.Type)
+	}
+
+	if rule.Regex != "" {
+		// The schema decoded from database is not compiled yet
+		if rule.re == nil {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return err
+			}
+			rule.re = re
+		}
+		if !rule.re.MatchString(value) {
+			return fmt.Errorf("value %q does not match %q", value, rule.Regex)
+		}
+	}
+
+	if len(rule.Enum) != 0 && !slices.Contains(rule.Enum, value) {
+		return fmt.Errorf("value %q is not one of [%v]", value, strings.Join(rule.Enum, ", "))
+	}
+
+	return nil
+}
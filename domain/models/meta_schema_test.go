@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/cloudcopper/swamp/domain/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaSchemaValidate(t *testing.T) {
+	schema := MetaSchema{
+		"GIT_COMMIT": {Required: true, Regex: "^[0-9a-f]{40}$"},
+		"VERSION":    {Required: true},
+		"BUILD_TYPE": {Enum: []string{"release", "debug"}},
+		"BUILD_ID":   {Type: "int"},
+	}
+	meta := func(kv ...string) ArtifactMetas {
+		a := ArtifactMetas{}
+		for x := 0; x+1 < len(kv); x += 2 {
+			a = append(a, &ArtifactMeta{Key: kv[x], Value: kv[x+1]})
+		}
+		return a
+	}
+	commit := "0123456789abcdef0123456789abcdef01234567"
+
+	testCases := []struct {
+		desc       string
+		meta       ArtifactMetas
+		violations []string
+	}{
+		{"conforming", meta("GIT_COMMIT", commit, "VERSION", "1.0.0", "BUILD_TYPE", "release", "BUILD_ID", "42"), nil},
+		{"extra keys allowed", meta("GIT_COMMIT", commit, "VERSION", "1.0.0", "USER", "nobody"), nil},
+		{"empty meta", nil, []string{"GIT_COMMIT: required key is missing", "VERSION: required key is missing"}},
+		{"bad regex", meta("GIT_COMMIT", "deadbeef", "VERSION", "1"), []string{`GIT_COMMIT: value "deadbeef" does not match "^[0-9a-f]{40}$"`}},
+		{"bad enum", meta("GIT_COMMIT", commit, "VERSION", "1", "BUILD_TYPE", "nightly"), []string{`BUILD_TYPE: value "nightly" is not one of [release, debug]`}},
+		{"bad type", meta("GIT_COMMIT", commit, "VERSION", "1", "BUILD_ID", "abc"), []string{`BUILD_ID: value "abc" is not int`}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert := require.New(t)
+			err := schema.Validate(tC.meta)
+			if tC.violations == nil {
+				assert.NoError(err)
+				return
+			}
+			var violation errors.ErrMetaSchemaViolation
+			assert.True(errors.As(err, &violation))
+			assert.Equal(tC.violations, violation.Violations)
+		})
+	}
+}
+
+func TestMetaSchemaCompile(t *testing.T) {
+	assert := require.New(t)
+	assert.NoError(MetaSchema{}.Compile())
+	assert.NoError(MetaSchema{"A": {Type: "bool", Regex: "^(true|false)$"}}.Compile())
+	assert.Error(MetaSchema{"A": {Type: "uuid"}}.Compile())
+	assert.Error(MetaSchema{"A": {Regex: "(unclosed"}}.Compile())
+
+	schema := MetaSchema{"A": {Regex: "^[0-9]+$"}}
+	assert.NoError(schema.Compile())
+	assert.NotNil(schema["A"].re)
+	assert.NoError(schema.Validate(ArtifactMetas{{Key: "A", Value: "42"}}))
+}
@@ -21,6 +21,11 @@ type Repo struct {
 	Size           types.Size     `gorm:"int64" validate:"min=0"`
 	ArtifactsCount int            `gorm:"int64" validate:"min=0"`
 	Meta           RepoMetas      `gorm:"foreignKey:RepoID;constraint:OnDelete:CASCADE;" validate:"-"`
+	MetaSchema     MetaSchema     `gorm:"serializer:json" yaml:"meta_schema" validate:"-"`
+	Webhooks       Webhooks       `gorm:"serializer:json" yaml:"webhooks" validate:"-"`
+	Access         Access         `gorm:"serializer:json" yaml:"access" validate:"-"`
+	Limits         *Limits        `gorm:"serializer:json" yaml:"limits" validate:"-"`
+	Upstream       *Upstream      `gorm:"serializer:json" yaml:"upstream" validate:"-"`
 	Artifacts      Artifacts      `gorm:"foreignKey:RepoID;constraint:OnDelete:CASCADE;" yaml:"-" validate:"-"`
 }
 
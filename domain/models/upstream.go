@@ -0,0 +1,57 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/lib/types"
+)
+
+// Upstream is the other swamp instance the repo pulls artifacts from.
+// It is declared per repo in the repos config:
+//
+//	firmware:
+//	  upstream:
+//	    url: https://swamp.example.com  # base url of upstream swamp
+//	    repo: firmware                  # upstream repo id; the repo id by default
+//	    token: ${UPSTREAM_TOKEN}        # optional bearer token
+//	    interval: 5m                    # how often upstream is checked
+//
+// The missing good artifacts are downloaded into repo storage,
+// verified by checksum file and registered as dangling artifacts.
+// The artifacts removed from repo (deleted, expired or broken) are not downloaded again,
+// while upstream still lists them.
+type Upstream struct {
+	URL      string         `yaml:"url" json:"url"`
+	Repo     RepoID         `yaml:"repo" json:"repo,omitempty"`
+	Token    string         `yaml:"token" json:"token,omitempty"`
+	Interval types.Duration `yaml:"interval" json:"interval,omitempty"`
+}
+
+// Compile checks the upstream is valid.
+// The not set upstream repo id is the repoID.
+func (u *Upstream) Compile(repoID RepoID) error {
+	if u == nil {
+		return nil
+	}
+	parsed, err := url.Parse(u.URL)
+	if err != nil {
+		return fmt.Errorf("upstream: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("upstream: url %q is not absolute http(s) url", u.URL)
+	}
+	u.URL = strings.TrimSuffix(u.URL, "/")
+	if u.Repo == EmptyRepoID {
+		u.Repo = repoID
+	}
+	if !lib.IsValidID(u.Repo) {
+		return fmt.Errorf("upstream: invalid repo id %q", u.Repo)
+	}
+	if u.Interval < 0 {
+		return fmt.Errorf("upstream: negative interval")
+	}
+	return nil
+}
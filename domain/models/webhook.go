@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// Webhooks are the http endpoints notified about artifact events.
+// They are declared globally (_webhooks) and per repo in the repos config:
+//
+//	webhooks:
+//	  - url: https://chat.example.com/hooks/swamp
+//	    secret: ${WEBHOOK_SECRET}
+//	    events: [artifact-created, artifact-broken]
+type Webhooks []*Webhook
+
+type Webhook struct {
+	URL    string   `yaml:"url" json:"url"`
+	Secret string   `yaml:"secret" json:"secret,omitempty"` // optional hmac-sha256 key of payload signature
+	Events []string `yaml:"events" json:"events,omitempty"` // empty means all events
+}
+
+// Compile checks the webhooks are valid.
+// The events are all known event names.
+func (hooks Webhooks) Compile(events []string) error {
+	for x, hook := range hooks {
+		if hook == nil {
+			return fmt.Errorf("webhook %v: empty", x)
+		}
+		u, err := url.Parse(hook.URL)
+		if err != nil {
+			return fmt.Errorf("webhook %v: %w", x, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook %v: url %q is not absolute http(s) url", x, hook.URL)
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(events, event) {
+				return fmt.Errorf("webhook %v: unknown event %q", x, event)
+			}
+		}
+	}
+	return nil
+}
+
+// Wants returns true if the webhook subscribed to the event
+func (hook *Webhook) Wants(event string) bool {
+	return len(hook.Events) == 0 || slices.Contains(hook.Events, event)
+}
@@ -0,0 +1,29 @@
+package models
+
+type WebhookDeliveryID = string
+
+type WebhookDeliveryState = string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryState = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryState = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryState = "failed"
+)
+
+// WebhookDelivery is the queued (and then historical) webhook request.
+// It keeps the payload, so it could be retried after restart.
+type WebhookDelivery struct {
+	DeliveryID WebhookDeliveryID    `gorm:"primaryKey;not null"`
+	URL        string               `gorm:"index;not null"`
+	Event      string               `gorm:"not null"`
+	RepoID     RepoID               `gorm:"index"`
+	ArtifactID ArtifactID           `gorm:"string"`
+	Payload    string               `gorm:"not null"`
+	State      WebhookDeliveryState `gorm:"index;not null"`
+	Attempts   int                  `gorm:"int64"`
+	CreatedAt  int64                `gorm:"index"`
+	NextAt     int64                `gorm:"index"` // unix time of next attempt
+	UpdatedAt  int64                `gorm:"int64"`
+	Status     int                  `gorm:"int64"` // last http status
+	Error      string               `gorm:"string"`
+}
@@ -0,0 +1,45 @@
+package vo
+
+import "fmt"
+
+// Permission is the access level to the repo.
+// Each next level includes previous ones.
+type Permission int
+
+const (
+	PermissionNone  Permission = 0
+	PermissionRead  Permission = 1 // browse and download artifacts
+	PermissionWrite Permission = 2 // change artifacts expiration
+	PermissionAdmin Permission = 3 // delete artifacts
+)
+
+var permissionNames = []string{"none", "read", "write", "admin"}
+
+func ParsePermission(s string) (Permission, error) {
+	for x, name := range permissionNames {
+		if s == name {
+			return Permission(x), nil
+		}
+	}
+	return PermissionNone, fmt.Errorf("unknown permission %q", s)
+}
+
+func (p Permission) String() string {
+	if p < PermissionNone || p > PermissionAdmin {
+		return fmt.Sprintf("Permission(%d)", int(p))
+	}
+	return permissionNames[p]
+}
+
+func (p Permission) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Permission) UnmarshalText(text []byte) error {
+	v, err := ParsePermission(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "github.com/cloudcopper/swamp/domain/models"
+
+type WebhookDeliveryRepository interface {
+	Create(model *models.WebhookDelivery) error
+	Update(model *models.WebhookDelivery) error
+	FindAll(flags ...interface{}) ([]*models.WebhookDelivery, error)
+	FindAllPending(now int64, flags ...interface{}) ([]*models.WebhookDelivery, error)
+	DeleteAllCompleted(before int64) error
+}
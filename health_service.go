@@ -0,0 +1,151 @@
+package swamp
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra/disk"
+	"github.com/cloudcopper/swamp/infra/metrics"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+)
+
+// The healthWatcher reports error, if filesystem watcher is degraded (see infra.WatcherService)
+type healthWatcher interface {
+	Health() error
+}
+
+// The healthScanner reports repos with storage scanned (see RepoService)
+type healthScanner interface {
+	Scanned(repoID models.RepoID) bool
+}
+
+// HealthService checks subsystems of application (see ports.HealthChecker):
+// - filesystem watcher
+// - catalog database
+// - storage and input directories of repos exist and writable
+// - initial storage scan complete (readiness only)
+type HealthService struct {
+	log            ports.Logger
+	fs             ports.FS
+	repoRepository domain.RepoRepository
+	watcher        healthWatcher
+	scanner        healthScanner
+	bus            metrics.EventBus
+	mutex          sync.Mutex
+	scanned        bool // initial storage scan complete
+}
+
+// NewHealthService creates health service.
+// The watcher, scanner and bus are optional, so their checks are skipped if nil.
+func NewHealthService(log ports.Logger, fs ports.FS, repoRepository domain.RepoRepository, watcher healthWatcher, scanner healthScanner, bus metrics.EventBus) *HealthService {
+	log = log.With(slog.String("entity", "HealthService"))
+	s := &HealthService{
+		log:            log,
+		fs:             fs,
+		repoRepository: repoRepository,
+		watcher:        watcher,
+		scanner:        scanner,
+		bus:            bus,
+	}
+	return s
+}
+
+// Health checks watcher, database and repos directories
+func (s *HealthService) Health() []ports.HealthCheck {
+	checks, _ := s.health()
+	return checks
+}
+
+// Ready checks health and the initial storage scan of all repos complete.
+// The scan is complete once storage of every repo scanned
+// and all found dangling artifacts processed.
+func (s *HealthService) Ready() []ports.HealthCheck {
+	checks, repos := s.health()
+	if repos == nil {
+		return checks
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.scanned || s.scanner == nil {
+		return append(checks, ports.HealthCheck{Name: "scan", Status: ports.HealthOk})
+	}
+
+	pending := false
+	for _, repo := range repos {
+		if !s.scanner.Scanned(repo.RepoID) {
+			checks = append(checks, ports.HealthCheck{Name: "scan", RepoID: repo.RepoID, Path: repo.Storage, Status: ports.HealthPending})
+			pending = true
+		}
+	}
+	if !pending && s.bus != nil {
+		queued := 0
+		for _, depth := range s.bus.Depths()[ports.TopicDanglingRepoArtifact] {
+			queued += depth
+		}
+		if queued != 0 {
+			checks = append(checks, ports.HealthCheck{Name: "scan", Status: ports.HealthPending, Error: fmt.Sprintf("%v dangling artifacts queued", queued)})
+			pending = true
+		}
+	}
+	if pending {
+		return checks
+	}
+
+	s.log.Info("initial storage scan complete")
+	s.scanned = true
+	return append(checks, ports.HealthCheck{Name: "scan", Status: ports.HealthOk})
+}
+
+// The health returns checks and all repos, or nil repos if database is not available
+func (s *HealthService) health() ([]ports.HealthCheck, []*models.Repo) {
+	checks := []ports.HealthCheck{}
+	check := func(name string, repoID models.RepoID, path string, err error) {
+		c := ports.HealthCheck{Name: name, RepoID: repoID, Path: path, Status: ports.HealthOk}
+		if err != nil {
+			c.Status, c.Error = ports.HealthDegraded, err.Error()
+		}
+		checks = append(checks, c)
+	}
+
+	if s.watcher != nil {
+		check("watcher", "", "", s.watcher.Health())
+	}
+
+	repos, err := s.repoRepository.FindAll()
+	check("database", "", "", err)
+	if err != nil {
+		return checks, nil
+	}
+	if repos == nil {
+		repos = []*models.Repo{}
+	}
+
+	for _, repo := range repos {
+		check("storage", repo.RepoID, repo.Storage, s.checkDir(repo.Storage))
+		// The repo without input is read-only repo (e.g. replicated from upstream)
+		if repo.Input != "" {
+			check("input", repo.RepoID, repo.Input, s.checkDir(repo.Input))
+		}
+	}
+	return checks, repos
+}
+
+// The checkDir returns error, if directory does not exist or is not writable
+func (s *HealthService) checkDir(dir string) error {
+	exist, err := afero.DirExists(s.fs, dir)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("directory not found")
+	}
+	if err := disk.Writable(s.fs, dir); err != nil {
+		return fmt.Errorf("directory not writable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package swamp
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+type testHealthRepos struct {
+	domain.RepoRepository
+	repos []*models.Repo
+	err   error
+}
+
+func (t *testHealthRepos) FindAll(flags ...interface{}) ([]*models.Repo, error) {
+	return t.repos, t.err
+}
+
+type testHealthWatcher struct{ err error }
+
+func (t *testHealthWatcher) Health() error { return t.err }
+
+type testHealthScanner map[models.RepoID]bool
+
+func (t testHealthScanner) Scanned(repoID models.RepoID) bool { return t[repoID] }
+
+type testHealthBus map[ports.Topic][]int
+
+func (t testHealthBus) Depths() map[ports.Topic][]int { return t }
+
+func TestHealthService(t *testing.T) {
+	assert := require.New(t)
+	fs := afero.NewMemMapFs()
+	assert.NoError(fs.MkdirAll("/storage/repo1", 0o755))
+	assert.NoError(fs.MkdirAll("/input/repo1", 0o755))
+	assert.NoError(fs.MkdirAll("/storage/repo2", 0o755))
+	assert.NoError(fs.MkdirAll("/storage/repo3", 0o755))
+	repos := &testHealthRepos{repos: []*models.Repo{
+		{RepoID: "repo1", Storage: "/storage/repo1", Input: "/input/repo1"},
+		{RepoID: "repo2", Storage: "/storage/repo2", Input: "/input/repo2"},
+		{RepoID: "repo3", Storage: "/storage/repo3"}, // read-only repo
+	}}
+	watcher := &testHealthWatcher{}
+	scanner := testHealthScanner{}
+	bus := testHealthBus{}
+	s := NewHealthService(slog.Default(), fs, repos, watcher, scanner, bus)
+
+	status := func(checks []ports.HealthCheck) map[string]string {
+		m := map[string]string{}
+		for _, c := range checks {
+			m[c.Name+":"+c.RepoID] = c.Status
+		}
+		return m
+	}
+
+	// Missing input of repo2, no input of repo3
+	assert.Equal(map[string]string{
+		"watcher:":      ports.HealthOk,
+		"database:":     ports.HealthOk,
+		"storage:repo1": ports.HealthOk,
+		"input:repo1":   ports.HealthOk,
+		"storage:repo2": ports.HealthOk,
+		"input:repo2":   ports.HealthDegraded,
+		"storage:repo3": ports.HealthOk,
+	}, status(s.Health()))
+	assert.NoError(fs.MkdirAll("/input/repo2", 0o755))
+
+	// Watcher error
+	watcher.err = fmt.Errorf("queue overflow")
+	assert.Equal(ports.HealthDegraded, status(s.Health())["watcher:"])
+	watcher.err = nil
+
+	// Scan pending until all repos scanned and dangling artifacts processed
+	scanner["repo1"] = true
+	assert.Equal(ports.HealthPending, status(s.Ready())["scan:repo2"])
+	scanner["repo2"] = true
+	scanner["repo3"] = true
+	bus[ports.TopicDanglingRepoArtifact] = []int{2}
+	assert.Equal(ports.HealthPending, status(s.Ready())["scan:"])
+	bus[ports.TopicDanglingRepoArtifact] = []int{0}
+	assert.Equal(ports.HealthOk, status(s.Ready())["scan:"])
+	// The complete scan is remembered
+	scanner["repo1"] = false
+	assert.Equal(ports.HealthOk, status(s.Ready())["scan:"])
+
+	// Not writable directories
+	s.fs = afero.NewReadOnlyFs(fs)
+	assert.Equal(ports.HealthDegraded, status(s.Health())["storage:repo1"])
+
+	// Database not available
+	repos.err = fmt.Errorf("database is locked")
+	checks := s.Ready()
+	assert.Equal(map[string]string{
+		"watcher:":  ports.HealthOk,
+		"database:": ports.HealthDegraded,
+	}, status(checks))
+	assert.Equal("database is locked", checks[1].Error)
+}
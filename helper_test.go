@@ -52,7 +52,7 @@ func testFakeApp(t *testing.T, fs afero.Fs, repos []*models.Repo, callback func(
 	db, closeDb, err := infra.NewDatabase(log, driver, source)
 	noErr(err)
 	defer closeDb()
-	noErr(db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFiles)))
+	noErr(db.AutoMigrate(new(models.Repo), new(models.RepoMeta), new(models.Artifact), new(models.ArtifactMeta), new(models.ArtifactFiles), new(models.ArtifactComponent)))
 	// Create repos repository
 	repoRepository, err := repository.NewRepoRepository(db, fs)
 	noErr(err)
@@ -65,6 +65,7 @@ func testFakeApp(t *testing.T, fs afero.Fs, repos []*models.Repo, callback func(
 		bus:             bus,
 		artifactStorage: artifactStorage,
 		repositories:    repository.NewRepositories(repoRepository, artifactRepository),
+		storageFs:       fs,
 	}
 	assert.True(artifactService != nil)
 
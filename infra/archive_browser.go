@@ -0,0 +1,280 @@
+package infra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// The archiveBrowserFormats are the archive formats by file name suffix
+var archiveBrowserFormats = []struct {
+	suffix string
+	walk   func(file ports.File, fn archiveWalkFunc) error
+}{
+	{".zip", walkZip},
+	{".jar", walkZip},
+	{".tar", walkTar(nil)},
+	{".tar.gz", walkTar(gzipReader)},
+	{".tgz", walkTar(gzipReader)},
+	{".tar.zst", walkTar(zstdReader)},
+	{".tar.xz", walkTar(xzReader)},
+	{".ipk", walkPackage},
+	{".deb", walkPackage},
+}
+
+// The archiveWalkFunc is called for every archive entry with function opening its content.
+// The walk stops, if it returns false or error.
+type archiveWalkFunc func(entry *ports.ArchiveEntry, open func() (io.Reader, error)) (bool, error)
+
+// IsBrowsableArchive returns true, if the file name has suffix of archive supported by ArchiveBrowser
+func IsBrowsableArchive(name string) bool {
+	return archiveWalk(name) != nil
+}
+
+func archiveWalk(name string) func(file ports.File, fn archiveWalkFunc) error {
+	name = strings.ToLower(name)
+	for _, format := range archiveBrowserFormats {
+		if strings.HasSuffix(name, format.suffix) {
+			return format.walk
+		}
+	}
+	return nil
+}
+
+// ArchiveBrowser lists and extracts entries of archive files without unpacking them.
+// The zip, jar, tar (optionally compressed by gzip, zstd or xz), ipk and deb archives are supported.
+// The listed entries and size of extracted entry are limited.
+type ArchiveBrowser struct {
+	maxEntries   int
+	maxEntrySize int64
+}
+
+func NewArchiveBrowser(maxEntries int, maxEntrySize types.Size) *ArchiveBrowser {
+	return &ArchiveBrowser{
+		maxEntries:   maxEntries,
+		maxEntrySize: int64(maxEntrySize),
+	}
+}
+
+// List returns entries of the archive file sorted by name.
+// The truncated is true, if the archive has more than max entries.
+// It returns ErrWrongArchiveFormat, if the file is not supported archive.
+func (b *ArchiveBrowser) List(file ports.File, name string) (entries []*ports.ArchiveEntry, truncated bool, err error) {
+	walk := archiveWalk(name)
+	if walk == nil {
+		return nil, false, ports.ErrWrongArchiveFormat
+	}
+	entries = []*ports.ArchiveEntry{}
+	err = walk(file, func(entry *ports.ArchiveEntry, _ func() (io.Reader, error)) (bool, error) {
+		if len(entries) >= b.maxEntries {
+			truncated = true
+			return false, nil
+		}
+		entries = append(entries, entry)
+		return true, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	slices.SortFunc(entries, func(a, b *ports.ArchiveEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return entries, truncated, nil
+}
+
+// Extract calls fn with the regular file entry of the archive file and its content.
+// It returns ErrArchiveEntryNotFound, if there is no such regular file,
+// and ErrArchiveEntryTooLarge, if the entry exceeds max entry size.
+func (b *ArchiveBrowser) Extract(file ports.File, name string, entryName string, fn func(entry *ports.ArchiveEntry, r io.Reader) error) error {
+	walk := archiveWalk(name)
+	if walk == nil {
+		return ports.ErrWrongArchiveFormat
+	}
+	entryName = cleanArchiveEntryName(entryName)
+	found := false
+	err := walk(file, func(entry *ports.ArchiveEntry, open func() (io.Reader, error)) (bool, error) {
+		if entry.Name != entryName || !entry.Mode.IsRegular() {
+			return true, nil
+		}
+		found = true
+		if int64(entry.Size) > b.maxEntrySize {
+			return false, ports.ErrArchiveEntryTooLarge
+		}
+		r, err := open()
+		if err != nil {
+			return false, err
+		}
+		// The declared size limits content of malformed archives
+		return false, fn(entry, io.LimitReader(r, int64(entry.Size)))
+	})
+	if err == nil && !found {
+		err = ports.ErrArchiveEntryNotFound
+	}
+	return err
+}
+
+// The cleanArchiveEntryName returns slash separated name without leading slash and dot segments,
+// so the entry can not refer outside of archive
+func cleanArchiveEntryName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
+
+func walkZip(file ports.File, fn archiveWalkFunc) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	z, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ports.ErrWrongArchiveFormat, err)
+	}
+	for _, f := range z.File {
+		entry := &ports.ArchiveEntry{Name: cleanArchiveEntryName(f.Name), Size: types.Size(f.UncompressedSize64), Mode: f.Mode(), ModTime: f.Modified}
+		if entry.Name == "" {
+			continue
+		}
+		var rc io.ReadCloser
+		cont, err := fn(entry, func() (io.Reader, error) {
+			r, err := f.Open()
+			rc = r
+			return r, err
+		})
+		if rc != nil {
+			rc.Close()
+		}
+		if err != nil || !cont {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(decompress func(r io.Reader) (io.ReadCloser, error)) func(file ports.File, fn archiveWalkFunc) error {
+	return func(file ports.File, fn archiveWalkFunc) error {
+		return walkTarReader(file, decompress, fn)
+	}
+}
+
+func walkTarReader(r io.Reader, decompress func(r io.Reader) (io.ReadCloser, error), fn archiveWalkFunc) error {
+	if decompress != nil {
+		rc, err := decompress(r)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ports.ErrWrongArchiveFormat, err)
+		}
+		defer rc.Close()
+		r = rc
+	}
+	t := tar.NewReader(r)
+	for {
+		h, err := t.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ports.ErrWrongArchiveFormat, err)
+		}
+		entry := &ports.ArchiveEntry{Name: cleanArchiveEntryName(h.Name), Size: types.Size(h.Size), Mode: h.FileInfo().Mode(), ModTime: h.ModTime}
+		if entry.Name == "" {
+			continue
+		}
+		cont, err := fn(entry, func() (io.Reader, error) { return t, nil })
+		if err != nil || !cont {
+			return err
+		}
+	}
+}
+
+// The walkPackage walks ipk or deb package.
+// It is ar archive of debian-binary, control and data tars,
+// or gzip compressed tar of those for older ipk.
+func walkPackage(file ports.File, fn archiveWalkFunc) error {
+	r := bufio.NewReader(file)
+	magic, err := r.Peek(len(arMagic))
+	if err == nil && string(magic) == arMagic {
+		return walkAr(r, fn)
+	}
+	return walkTarReader(r, gzipReader, fn)
+}
+
+const arMagic = "!<arch>\n"
+
+// The walkAr walks common (System V/GNU) ar archive without long names table
+func walkAr(r *bufio.Reader, fn archiveWalkFunc) error {
+	if _, err := r.Discard(len(arMagic)); err != nil {
+		return err
+	}
+	header := make([]byte, 60)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("%w: %w", ports.ErrWrongArchiveFormat, err)
+		}
+		if string(header[58:60]) != "`\n" {
+			return fmt.Errorf("%w: bad ar header", ports.ErrWrongArchiveFormat)
+		}
+		field := func(from, to int) string { return string(bytes.TrimSpace(header[from:to])) }
+		size, err := strconv.ParseInt(field(48, 58), 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("%w: bad ar size", ports.ErrWrongArchiveFormat)
+		}
+		mtime, _ := strconv.ParseInt(field(16, 28), 10, 64)
+		mode, _ := strconv.ParseUint(field(40, 48), 8, 32)
+		entry := &ports.ArchiveEntry{
+			Name:    cleanArchiveEntryName(strings.TrimSuffix(field(0, 16), "/")),
+			Size:    types.Size(size),
+			Mode:    fs.FileMode(mode) & fs.ModePerm,
+			ModTime: time.Unix(mtime, 0),
+		}
+		content := io.LimitReader(r, size)
+		if entry.Name != "" {
+			cont, err := fn(entry, func() (io.Reader, error) { return content, nil })
+			if err != nil || !cont {
+				return err
+			}
+		}
+		// Skip unread content and padding to even offset
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return err
+		}
+		if size%2 == 1 {
+			if _, err := r.Discard(1); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+		}
+	}
+}
+
+func gzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func zstdReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+func xzReader(r io.Reader) (io.ReadCloser, error) {
+	x, err := xz.NewReader(r)
+	return io.NopCloser(x), err
+}
@@ -0,0 +1,141 @@
+package infra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestZip(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	z := zip.NewWriter(buf)
+	for name, data := range files {
+		w, err := z.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, z.Close())
+	return buf.Bytes()
+}
+
+func writeTestTarGz(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	g := gzip.NewWriter(buf)
+	w := tar.NewWriter(g)
+	for name, data := range files {
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, g.Close())
+	return buf.Bytes()
+}
+
+func writeTestAr(files []string, data []string) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(arMagic)
+	for i, name := range files {
+		fmt.Fprintf(buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", name+"/", 0, 0, 0, 0644, len(data[i]))
+		buf.WriteString(data[i])
+		if len(data[i])%2 == 1 {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+func openTestFile(t *testing.T, name string, data []byte) ports.File {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, name, data, 0644))
+	file, err := fs.Open(name)
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func names(entries []*ports.ArchiveEntry) []string {
+	a := []string{}
+	for _, e := range entries {
+		a = append(a, e.Name)
+	}
+	return a
+}
+
+func extract(b *ArchiveBrowser, file ports.File, name, entry string) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	data := ""
+	err := b.Extract(file, name, entry, func(_ *ports.ArchiveEntry, r io.Reader) error {
+		d, err := io.ReadAll(r)
+		data = string(d)
+		return err
+	})
+	return data, err
+}
+
+func TestArchiveBrowser(t *testing.T) {
+	assert := require.New(t)
+	b := NewArchiveBrowser(10, 10)
+	files := map[string]string{"./usr/bin/app": "app", "readme.txt": "readme", "../etc/passwd": "passwd", "large.bin": "12345678901"}
+
+	for name, data := range map[string][]byte{
+		"fw.zip":    writeTestZip(t, files),
+		"fw.tar.gz": writeTestTarGz(t, files),
+		"fw.ipk":    writeTestTarGz(t, files),
+	} {
+		file := openTestFile(t, name, data)
+		entries, truncated, err := b.List(file, name)
+		assert.NoError(err, name)
+		assert.False(truncated)
+		assert.Equal([]string{"etc/passwd", "large.bin", "readme.txt", "usr/bin/app"}, names(entries), name)
+
+		data, err := extract(b, file, name, "/usr/bin/../bin/app")
+		assert.NoError(err, name)
+		assert.Equal("app", data)
+		data, err = extract(b, file, name, "../../etc/passwd")
+		assert.NoError(err, name)
+		assert.Equal("passwd", data)
+		_, err = extract(b, file, name, "missing")
+		assert.ErrorIs(err, ports.ErrArchiveEntryNotFound, name)
+		_, err = extract(b, file, name, "large.bin")
+		assert.ErrorIs(err, ports.ErrArchiveEntryTooLarge, name)
+	}
+
+	// The ipk of ar format
+	ipk := writeTestAr([]string{"debian-binary", "control.tar.gz", "data.tar.gz"}, []string{"2.0\n", "control", "data"})
+	file := openTestFile(t, "fw.ipk", ipk)
+	entries, _, err := b.List(file, "fw.ipk")
+	assert.NoError(err)
+	assert.Equal([]string{"control.tar.gz", "data.tar.gz", "debian-binary"}, names(entries))
+	data, err := extract(b, file, "fw.ipk", "control.tar.gz")
+	assert.NoError(err)
+	assert.Equal("control", data)
+
+	// Truncated listing
+	b = NewArchiveBrowser(2, 10)
+	file = openTestFile(t, "fw.zip", writeTestZip(t, files))
+	entries, truncated, err := b.List(file, "fw.zip")
+	assert.NoError(err)
+	assert.True(truncated)
+	assert.Len(entries, 2)
+
+	// Unsupported and malformed archives
+	_, _, err = b.List(file, "fw.bin")
+	assert.ErrorIs(err, ports.ErrWrongArchiveFormat)
+	file = openTestFile(t, "bad.tar.gz", []byte("not gzip"))
+	_, _, err = b.List(file, "bad.tar.gz")
+	assert.ErrorIs(err, ports.ErrWrongArchiveFormat)
+	assert.True(IsBrowsableArchive("FW.TAR.XZ"))
+	assert.False(IsBrowsableArchive("fw.gz"))
+}
@@ -0,0 +1,299 @@
+package infra
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+)
+
+// The archiveCacheSumSuffix is the suffix of file keeping sha256 of cached archive
+const archiveCacheSumSuffix = ".sha256"
+
+// The archiveCacheTmp is the infix of archives being generated
+const archiveCacheTmp = ".tmp-"
+
+// ArchiveCache keeps generated archives in directory,
+// so repeated downloads are served from disk instead of generated again.
+// The least recently used archives are removed, once total size exceeds max size.
+// The archive is cached once, even if requested by many clients at once.
+type ArchiveCache struct {
+	log     ports.Logger
+	fs      ports.FS
+	dir     string
+	maxSize int64
+	mutex   sync.Mutex
+	entries map[string]*archiveCacheEntry
+	pending map[string]chan struct{} // archives being generated
+	size    int64                    // total size of entries
+}
+
+type archiveCacheEntry struct {
+	size int64
+	sum  string // hex sha256 of archive
+	used time.Time
+}
+
+// NewArchiveCache creates archive cache in dir.
+// The dir is created private, as archives might be of not public repos.
+// The archives cached already by previous run are reused.
+func NewArchiveCache(log ports.Logger, fs ports.FS, dir string, maxSize types.Size) (*ArchiveCache, error) {
+	log = log.With(slog.String("entity", "ArchiveCache"), slog.String("dir", dir))
+	if err := fs.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	c := &ArchiveCache{
+		log:     log,
+		fs:      fs,
+		dir:     dir,
+		maxSize: int64(maxSize),
+		entries: map[string]*archiveCacheEntry{},
+		pending: map[string]chan struct{}{},
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.evict("")
+	log.Info("created", slog.Int("archives", len(c.entries)), slog.Any("size", types.Size(c.size)))
+	return c, nil
+}
+
+// The load adds archives of previous run and removes incomplete ones
+func (c *ArchiveCache) load() error {
+	infos, err := afero.ReadDir(c.fs, c.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasSuffix(name, archiveCacheSumSuffix) {
+			continue
+		}
+		sum, err := afero.ReadFile(c.fs, filepath.Join(c.dir, name+archiveCacheSumSuffix))
+		if strings.Contains(name, archiveCacheTmp) || err != nil || len(sum) != sha256.Size*2 {
+			c.remove(name)
+			continue
+		}
+		c.entries[name] = &archiveCacheEntry{size: info.Size(), sum: string(sum), used: info.ModTime()}
+		c.size += info.Size()
+	}
+	// Remove checksums of removed archives
+	for _, info := range infos {
+		name, ok := strings.CutSuffix(info.Name(), archiveCacheSumSuffix)
+		if _, found := c.entries[name]; ok && !found {
+			c.remove(name)
+		}
+	}
+	return nil
+}
+
+// Has returns true, if archive is cached
+func (c *ArchiveCache) Has(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.entries[key]
+	return ok
+}
+
+// Get opens cached archive given by key (the file name of archive in cache).
+// It returns opened archive and its hex sha256, or false, if not cached.
+func (c *ArchiveCache) Get(key string) (ports.File, string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, "", false
+	}
+	// Open under mutex, so the archive is not evicted meanwhile
+	file, err := c.fs.Open(filepath.Join(c.dir, key))
+	if err != nil {
+		c.log.Warn("unable open cached archive", slog.String("key", key), slog.Any("err", err))
+		return nil, "", false
+	}
+	entry.used = time.Now()
+	return file, entry.sum, true
+}
+
+// Open opens cached archive given by key (the file name of archive in cache).
+// The archive is written by generate function first, if not cached yet.
+// It returns opened archive and its hex sha256.
+func (c *ArchiveCache) Open(key string, generate func(w io.Writer) error) (ports.File, string, error) {
+	c.mutex.Lock()
+	for {
+		if entry, ok := c.entries[key]; ok {
+			entry.used = time.Now()
+			c.mutex.Unlock()
+			file, err := c.fs.Open(filepath.Join(c.dir, key))
+			return file, entry.sum, err
+		}
+		pending, ok := c.pending[key]
+		if !ok {
+			break
+		}
+		// Wait the archive generated by other request
+		c.mutex.Unlock()
+		<-pending
+		c.mutex.Lock()
+	}
+	done := make(chan struct{})
+	c.pending[key] = done
+	c.mutex.Unlock()
+
+	entry, err := c.generate(key, generate)
+	c.complete(key, done, entry)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := c.fs.Open(filepath.Join(c.dir, key))
+	return file, entry.sum, err
+}
+
+// Write writes archive given by key generated by generate function to w,
+// and caches it meanwhile, so the client does not wait the archive cached first.
+// The archive is not cached, if it is cached already or being cached by other request.
+// The failure to cache is logged only, as the archive is written to w anyway.
+func (c *ArchiveCache) Write(key string, w io.Writer, generate func(w io.Writer) error) error {
+	c.mutex.Lock()
+	_, cached := c.entries[key]
+	_, pending := c.pending[key]
+	if cached || pending {
+		c.mutex.Unlock()
+		return generate(w)
+	}
+	done := make(chan struct{})
+	c.pending[key] = done
+	c.mutex.Unlock()
+
+	var err error
+	generated := false
+	entry, cacheErr := c.generate(key, func(cache io.Writer) error {
+		tee := &archiveCacheTee{w: w, cache: cache}
+		generated, err = true, generate(tee)
+		if err != nil {
+			return err
+		}
+		return tee.err
+	})
+	c.complete(key, done, entry)
+	if cacheErr != nil && err == nil {
+		c.log.Warn("unable cache archive", slog.String("key", key), slog.Any("err", cacheErr))
+	}
+	if !generated { // the cache failed before generation
+		return generate(w)
+	}
+	return err
+}
+
+// The complete adds generated archive entry, if any, and wakes up requests waiting it
+func (c *ArchiveCache) complete(key string, done chan struct{}, entry *archiveCacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.pending, key)
+	close(done)
+	if entry == nil {
+		return
+	}
+	c.entries[key] = entry
+	c.size += entry.size
+	c.evict(key)
+}
+
+// The generate writes archive to temporary file and renames it once complete
+func (c *ArchiveCache) generate(key string, generate func(w io.Writer) error) (*archiveCacheEntry, error) {
+	log := c.log.With(slog.String("key", key))
+	start := time.Now()
+	tmp, err := afero.TempFile(c.fs, c.dir, key+archiveCacheTmp+"*")
+	if err != nil {
+		return nil, err
+	}
+	defer c.fs.Remove(tmp.Name()) // no-op once renamed
+
+	hash := sha256.New()
+	counter := &countWriter{}
+	if err := generate(io.MultiWriter(tmp, hash, counter)); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := afero.WriteFile(c.fs, filepath.Join(c.dir, key+archiveCacheSumSuffix), []byte(sum), 0o644); err != nil {
+		return nil, err
+	}
+	if err := c.fs.Rename(tmp.Name(), filepath.Join(c.dir, key)); err != nil {
+		return nil, err
+	}
+	log.Info("archive cached", slog.Any("size", types.Size(counter.n)), slog.Duration("duration", time.Since(start)))
+	return &archiveCacheEntry{size: counter.n, sum: sum, used: time.Now()}, nil
+}
+
+// The evict removes least recently used archives, but keep, until size fits max size.
+// Must be called with mutex locked.
+func (c *ArchiveCache) evict(keep string) {
+	if c.size <= c.maxSize {
+		return
+	}
+	keys := []string{}
+	for key := range c.entries {
+		if key != keep {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return c.entries[a].used.Compare(c.entries[b].used)
+	})
+	for _, key := range keys {
+		if c.size <= c.maxSize {
+			break
+		}
+		c.size -= c.entries[key].size
+		delete(c.entries, key)
+		c.remove(key)
+		c.log.Debug("archive evicted", slog.String("key", key))
+	}
+}
+
+func (c *ArchiveCache) remove(name string) {
+	for _, name := range []string{name, name + archiveCacheSumSuffix} {
+		if err := c.fs.Remove(filepath.Join(c.dir, name)); err != nil && !os.IsNotExist(err) {
+			c.log.Warn("unable remove archive", slog.String("file", name), slog.Any("err", err))
+		}
+	}
+}
+
+// The archiveCacheTee writes to w and to cache, till cache write fails
+type archiveCacheTee struct {
+	w     io.Writer
+	cache io.Writer
+	err   error // cache write error
+}
+
+func (t *archiveCacheTee) Write(p []byte) (int, error) {
+	if t.err == nil {
+		_, t.err = t.cache.Write(p)
+	}
+	return t.w.Write(p)
+}
+
+// The countWriter counts written bytes
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,160 @@
+package infra
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveCache(t *testing.T) {
+	assert := require.New(t)
+	fs := afero.NewMemMapFs()
+	c, err := NewArchiveCache(slog.Default(), fs, "/cache", 10)
+	assert.NoError(err)
+
+	calls := atomic.Int32{}
+	generate := func(data string) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			calls.Add(1)
+			_, err := w.Write([]byte(data))
+			return err
+		}
+	}
+	read := func(key string, data string) string {
+		file, sum, err := c.Open(key, generate(data))
+		assert.NoError(err)
+		defer file.Close()
+		b, err := io.ReadAll(file)
+		assert.NoError(err)
+		expected := sha256.Sum256(b)
+		assert.Equal(hex.EncodeToString(expected[:]), sum)
+		return string(b)
+	}
+
+	// Generated once
+	assert.False(c.Has("a.zip"))
+	assert.Equal("aaaa", read("a.zip", "aaaa"))
+	assert.Equal("aaaa", read("a.zip", "changed"))
+	assert.Equal(int32(1), calls.Load())
+	assert.True(c.Has("a.zip"))
+
+	// Generated once by concurrent requests
+	wg := sync.WaitGroup{}
+	for x := 0; x < 10; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read("b.zip", "bbbb")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(2), calls.Load())
+
+	// Failed generation is not cached
+	_, _, err = c.Open("c.zip", func(w io.Writer) error { return fmt.Errorf("no file") })
+	assert.Error(err)
+	assert.False(c.Has("c.zip"))
+	names, err := afero.Glob(fs, "/cache/c.zip*")
+	assert.NoError(err)
+	assert.Empty(names)
+
+	// Least recently used evicted
+	read("a.zip", "aaaa")
+	assert.Equal("cccc", read("c.zip", "cccc"))
+	assert.True(c.Has("a.zip"))
+	assert.False(c.Has("b.zip"))
+	assert.True(c.Has("c.zip"))
+	exists, err := afero.Exists(fs, "/cache/b.zip")
+	assert.NoError(err)
+	assert.False(exists)
+
+	// Reused by next run, incomplete archives removed
+	assert.NoError(afero.WriteFile(fs, "/cache/d.zip.tmp-123", []byte("dd"), 0o644))
+	assert.NoError(afero.WriteFile(fs, "/cache/e.zip", []byte("ee"), 0o644))
+	c, err = NewArchiveCache(slog.Default(), fs, "/cache", 10)
+	assert.NoError(err)
+	assert.True(c.Has("a.zip"))
+	assert.True(c.Has("c.zip"))
+	assert.False(c.Has("e.zip"))
+	names, err = afero.Glob(fs, "/cache/*")
+	assert.NoError(err)
+	assert.Equal([]string{"/cache/a.zip", "/cache/a.zip.sha256", "/cache/c.zip", "/cache/c.zip.sha256"}, names)
+	assert.Equal("aaaa", read("a.zip", "changed"))
+}
+
+// TestArchiveCacheWrite:
+//   - The archive is written to client, while cached
+//   - The concurrent request writes archive without caching
+//   - The archive is written to client, even if cache fails
+func TestArchiveCacheWrite(t *testing.T) {
+	assert := require.New(t)
+	fs := afero.NewMemMapFs()
+	c, err := NewArchiveCache(slog.Default(), fs, "/cache", 100)
+	assert.NoError(err)
+	info, err := fs.Stat("/cache")
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o700), info.Mode().Perm())
+
+	// The client gets first part, before archive complete
+	written, release := make(chan struct{}), make(chan struct{})
+	w := &bytes.Buffer{}
+	done := make(chan error)
+	go func() {
+		done <- c.Write("a.zip", w, func(w io.Writer) error {
+			w.Write([]byte("part1"))
+			close(written)
+			<-release
+			_, err := w.Write([]byte("part2"))
+			return err
+		})
+	}()
+	<-written
+	assert.Equal("part1", w.String())
+	assert.False(c.Has("a.zip"))
+	_, _, ok := c.Get("a.zip")
+	assert.False(ok)
+
+	// ...the concurrent request is not waiting
+	w2 := &bytes.Buffer{}
+	assert.NoError(c.Write("a.zip", w2, func(w io.Writer) error {
+		_, err := w.Write([]byte("other"))
+		return err
+	}))
+	assert.Equal("other", w2.String())
+
+	close(release)
+	assert.NoError(<-done)
+	assert.Equal("part1part2", w.String())
+	file, sum, ok := c.Get("a.zip")
+	assert.True(ok)
+	defer file.Close()
+	b, err := io.ReadAll(file)
+	assert.NoError(err)
+	assert.Equal("part1part2", string(b))
+	expected := sha256.Sum256(b)
+	assert.Equal(hex.EncodeToString(expected[:]), sum)
+
+	// Failed generation is not cached
+	assert.Error(c.Write("b.zip", io.Discard, func(w io.Writer) error { return fmt.Errorf("no file") }))
+	assert.False(c.Has("b.zip"))
+
+	// The failed cache does not fail client
+	c.fs = afero.NewReadOnlyFs(fs)
+	w = &bytes.Buffer{}
+	assert.NoError(c.Write("c.zip", w, func(w io.Writer) error {
+		_, err := w.Write([]byte("cccc"))
+		return err
+	}))
+	assert.Equal("cccc", w.String())
+	assert.False(c.Has("c.zip"))
+}
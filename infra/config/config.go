@@ -5,19 +5,25 @@ import (
 	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	tpl "github.com/cloudcopper/misc/env/template"
 	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
 	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/lib/types"
 	"github.com/cloudcopper/swamp/ports"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Repos map[string]*models.Repo
+	Repos    map[string]*models.Repo
+	Webhooks models.Webhooks // global webhooks of all repos
+	Auth     *models.Auth    // users and tokens; nil disables authentication
+	Limits   *models.Limits  // global download limits; nil is no limits
 }
 
 func (c *Config) String() string {
@@ -35,12 +41,62 @@ func (c *Config) String() string {
 		s += fmt.Sprintf("    storage: %v\n", repo.Storage)
 		s += fmt.Sprintf("    retention: %v\n", repo.Retention)
 		s += fmt.Sprintf("    broken: %v\n", repo.Broken)
+		if len(repo.MetaSchema) != 0 {
+			s += "    meta_schema:\n"
+			for _, key := range lib.SortedKeys(repo.MetaSchema) {
+				s += fmt.Sprintf("        %v: %+v\n", key, repo.MetaSchema[key])
+			}
+		}
+		s += webhooksString("    ", repo.Webhooks)
+		if repo.Access != nil {
+			s += "    access:\n"
+			for _, name := range lib.SortedKeys(repo.Access) {
+				s += fmt.Sprintf("        %q: %v\n", name, repo.Access[name])
+			}
+		}
+		if repo.Limits != nil {
+			s += fmt.Sprintf("    limits: %+v\n", *repo.Limits)
+		}
+		if repo.Upstream != nil {
+			s += fmt.Sprintf("    upstream:\n        url: %v\n        repo: %v\n        interval: %v\n", repo.Upstream.URL, repo.Upstream.Repo, repo.Upstream.Interval)
+		}
+	}
+	s += webhooksString("", c.Webhooks)
+	if c.Auth != nil {
+		s += fmt.Sprintf("auth:\n    users: %v\n    tokens: %v\n", lib.SortedKeys(c.Auth.Users), lib.SortedKeys(c.Auth.Tokens))
+	}
+	if c.Limits != nil {
+		s += fmt.Sprintf("limits: %+v\n", *c.Limits)
 	}
 	return strings.TrimSuffix(s, "\n")
 }
 
+// The webhooksString dumps webhooks without secrets
+func webhooksString(indent string, hooks models.Webhooks) string {
+	if len(hooks) == 0 {
+		return ""
+	}
+	s := indent + "webhooks:\n"
+	for _, hook := range hooks {
+		s += fmt.Sprintf("%v    - url: %v\n", indent, hook.URL)
+		if len(hook.Events) != 0 {
+			s += fmt.Sprintf("%v      events: %v\n", indent, hook.Events)
+		}
+	}
+	return s
+}
+
 const refRepoID = "${REPO_ID}"
 
+// The refWebhooks is the repos config key of global webhooks
+const refWebhooks = "_webhooks"
+
+// The refAuth is the repos config key of users and tokens
+const refAuth = "_auth"
+
+// The refLimits is the repos config key of global download limits
+const refLimits = "_limits"
+
 var (
 	Listen                = ":8080"
 	ReposConfigFileName   = "swamp_repos.yml"
@@ -51,6 +107,22 @@ var (
 	TimerBrokenStart      = 30 * time.Minute
 	TimerBrokenInterval   = 1 * time.Minute
 	TimerBrokenLimit      = 1
+	AdminToken            = "" // bearer token of management api; empty disables it
+	StateDatabase         = "" // sqlite file of persistent state (webhook deliveries); empty keeps state in memory
+	WebhookInterval       = 5 * time.Second
+	WebhookTimeout        = 10 * time.Second
+	WebhookBackoff        = 30 * time.Second // first retry delay, doubled by each next attempt
+	WebhookMaxBackoff     = 1 * time.Hour
+	WebhookMaxAttempts    = 10
+	WebhookHistory        = 7 * 24 * time.Hour                                   // how long completed deliveries are kept
+	StateDir              = filepath.Join(lib.First(os.UserCacheDir()), "swamp") // private directory of state kept on disk
+	ArchiveCacheDir       = filepath.Join(StateDir, "archives")                  // generated archives; empty disables cache
+	ArchiveCacheSize      = types.Size(1_000_000_000)                            // evict least recently used archives above
+	ArchiveMaxEntries     = 10000                                                // entries listed by archive file browsing
+	ArchiveEntryMaxSize   = types.Size(100_000_000)                              // largest entry extracted from archive file
+	PreviewMaxSize        = types.Size(1_000_000)                                // larger text files are previewed by tail
+	ReplicationInterval   = 5 * time.Minute                                      // default interval of upstream checks
+	ReplicationTimeout    = 1 * time.Hour                                        // upstream request timeout, including file download
 )
 
 func LoadConfig(log ports.Logger, f fs.ReadFileFS) (*Config, error) {
@@ -93,9 +165,48 @@ func loadReposConfig(log ports.Logger, f fs.ReadFileFS, fileName string) (*Confi
 	}
 
 	// unmrashal config
-	cfg := &Config{}
-	err = yaml.Unmarshal([]byte(s), &cfg.Repos)
-	return cfg, err
+	// The keys starting with _ are not repos,
+	// but either special (global webhooks) or yaml anchors holders
+	nodes := map[string]yaml.Node{}
+	if err := yaml.Unmarshal([]byte(s), &nodes); err != nil {
+		return nil, err
+	}
+	cfg := &Config{
+		Repos: map[string]*models.Repo{},
+	}
+	for k, node := range nodes {
+		switch {
+		case k == refWebhooks:
+			if err := node.Decode(&cfg.Webhooks); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+		case k == refAuth:
+			cfg.Auth = &models.Auth{}
+			if err := node.Decode(cfg.Auth); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+			if err := cfg.Auth.Compile(); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+		case k == refLimits:
+			cfg.Limits = &models.Limits{}
+			if err := node.Decode(cfg.Limits); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+			if err := cfg.Limits.Compile(); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+		case strings.HasPrefix(k, "_"):
+			continue
+		default:
+			repo := &models.Repo{}
+			if err := node.Decode(repo); err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+			cfg.Repos[k] = repo
+		}
+	}
+	return cfg, nil
 }
 
 // The processReposConfigs returns only meaningful repo configuration
@@ -105,6 +216,24 @@ func processReposConfigs(log ports.Logger, cfg *Config) *Config {
 		Repos: make(map[string]*models.Repo),
 	}
 
+	if err := cfg.Webhooks.Compile(ports.WebhookTopics); err != nil {
+		log.Error("skip - invalid global webhooks", slog.Any("err", err))
+	} else {
+		ret.Webhooks = cfg.Webhooks
+	}
+
+	// The repos without own access get default one, if authentication enabled
+	// Otherwise they are public
+	ret.Auth = cfg.Auth
+	ret.Limits = cfg.Limits
+	defaultAccess := models.Access(nil)
+	if cfg.Auth != nil {
+		defaultAccess = cfg.Auth.Access
+		if defaultAccess == nil {
+			defaultAccess = models.Access{models.PrincipalAuthenticated: vo.PermissionRead}
+		}
+	}
+
 	for k, v := range cfg.Repos {
 		log := log.With(slog.String("configID", k))
 
@@ -144,6 +273,35 @@ func processReposConfigs(log ports.Logger, cfg *Config) *Config {
 			log.Warn("repo has no input - read-only repo")
 		}
 
+		if err := v.MetaSchema.Compile(); err != nil {
+			log.Error("skip - invalid meta schema", slog.Any("err", err))
+			continue
+		}
+
+		if err := v.Webhooks.Compile(ports.WebhookTopics); err != nil {
+			log.Error("skip - invalid webhooks", slog.Any("err", err))
+			continue
+		}
+
+		if v.Access == nil {
+			v.Access = defaultAccess
+		}
+
+		if err := v.Limits.Compile(); err != nil {
+			log.Error("skip - invalid limits", slog.Any("err", err))
+			continue
+		}
+		if v.Limits != nil && len(v.Limits.TrustedProxies) != 0 {
+			log.Error("skip - trusted proxies are global limits only")
+			continue
+		}
+		v.Limits = v.Limits.Merge(cfg.Limits)
+
+		if err := v.Upstream.Compile(v.RepoID); err != nil {
+			log.Error("skip - invalid upstream", slog.Any("err", err))
+			continue
+		}
+
 		ret.Repos[k] = v
 	}
 
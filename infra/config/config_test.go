@@ -3,8 +3,12 @@ package config
 import (
 	"log/slog"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/lib/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -146,3 +150,187 @@ func TestRemoveSameRepos(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadReposConfigWebhooks(t *testing.T) {
+	assert := require.New(t)
+	f := fstest.MapFS{
+		"test_repos.yml": {Data: []byte(`
+_webhooks:
+  - url: https://chat.example.com/hook
+    secret: s3cret
+    events: [artifact-created]
+_defaults: &defaults
+  storage: /storage/${REPO_ID}
+  webhooks:
+    - url: http://deploy.example.com/hook
+repo1:
+  <<: *defaults
+  input: /input/repo1
+repo2:
+  <<: *defaults
+  input: /input/repo2
+  webhooks:
+    - url: ftp://deploy.example.com/hook
+`)},
+	}
+
+	cfg, err := loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.NoError(err)
+	assert.Len(cfg.Repos, 2)
+	assert.Equal(models.Webhooks{{URL: "https://chat.example.com/hook", Secret: "s3cret", Events: []string{"artifact-created"}}}, cfg.Webhooks)
+	assert.Equal(models.Webhooks{{URL: "http://deploy.example.com/hook"}}, cfg.Repos["repo1"].Webhooks)
+
+	cfg = processReposConfigs(slog.Default(), cfg)
+	assert.Len(cfg.Webhooks, 1)
+	assert.Contains(cfg.Repos, "repo1")
+	assert.Equal("/storage/repo1", cfg.Repos["repo1"].Storage)
+	assert.NotContains(cfg.Repos, "repo2", "invalid webhook url")
+}
+
+func TestLoadReposConfigAuth(t *testing.T) {
+	assert := require.New(t)
+	f := fstest.MapFS{
+		"test_repos.yml": {Data: []byte(`
+_auth:
+  users:
+    alice: { password: "$2a$04$gFFZaWeWmPq0GO23wMNwGumcjeE9fopmCpublmxXk9qdj2AOrTNrO", admin: true }
+  tokens:
+    ci: { token: t0ken }
+repo1:
+  storage: /storage/repo1
+  input: /input/repo1
+repo2:
+  storage: /storage/repo2
+  input: /input/repo2
+  access:
+    anonymous: read
+    ci: write
+`)},
+	}
+
+	cfg, err := loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.NoError(err)
+	assert.NotNil(cfg.Auth)
+	assert.True(cfg.Auth.Users["alice"].Admin)
+	assert.Equal("t0ken", cfg.Auth.Tokens["ci"].Token)
+
+	cfg = processReposConfigs(slog.Default(), cfg)
+	assert.Len(cfg.Repos, 2)
+	assert.Equal(models.Access{models.PrincipalAuthenticated: vo.PermissionRead}, cfg.Repos["repo1"].Access, "default access")
+	assert.Equal(models.Access{models.PrincipalAnonymous: vo.PermissionRead, "ci": vo.PermissionWrite}, cfg.Repos["repo2"].Access)
+
+	f["test_repos.yml"] = &fstest.MapFile{Data: []byte(`
+_auth:
+  users:
+    alice: { password: plain }
+`)}
+	_, err = loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.Error(err, "password is not bcrypt hash")
+}
+
+func TestLoadReposConfigLimits(t *testing.T) {
+	assert := require.New(t)
+	f := fstest.MapFS{
+		"test_repos.yml": {Data: []byte(`
+_limits:
+  rate: 2
+  bandwidth: 10MB
+  archives: 4
+repo1:
+  storage: /storage/repo1
+  input: /input/repo1
+repo2:
+  storage: /storage/repo2
+  input: /input/repo2
+  limits:
+    bandwidth: 1MiB
+    archives: 1
+repo3:
+  storage: /storage/repo3
+  input: /input/repo3
+  limits:
+    rate: -1
+`)},
+	}
+
+	cfg, err := loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.NoError(err)
+	assert.Equal(&models.Limits{Rate: 2, Bandwidth: 10 * 1000 * 1000, Archives: 4}, cfg.Limits)
+
+	cfg = processReposConfigs(slog.Default(), cfg)
+	assert.Equal(&models.Limits{Rate: 2, Bandwidth: 10 * 1000 * 1000, Archives: 4}, cfg.Limits)
+	assert.Equal(&models.Limits{Rate: 2, Bandwidth: 10 * 1000 * 1000, Archives: 4}, cfg.Repos["repo1"].Limits)
+	assert.Equal(&models.Limits{Rate: 2, Bandwidth: 1024 * 1024, Archives: 1}, cfg.Repos["repo2"].Limits)
+	assert.NotContains(cfg.Repos, "repo3", "invalid limits")
+}
+
+func TestLoadReposConfigTrustedProxies(t *testing.T) {
+	assert := require.New(t)
+	f := fstest.MapFS{
+		"test_repos.yml": {Data: []byte(`
+_limits:
+  rate: 2
+  trusted_proxies: [10.0.0.1, 192.168.0.0/24, "::1"]
+repo1:
+  storage: /storage/repo1
+  input: /input/repo1
+repo2:
+  storage: /storage/repo2
+  input: /input/repo2
+  limits:
+    trusted_proxies: [10.0.0.2]
+`)},
+	}
+
+	cfg, err := loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.NoError(err)
+	for ip, trusted := range map[string]bool{"10.0.0.1": true, "::ffff:10.0.0.1": true, "10.0.0.2": false, "192.168.0.7": true, "::1": true, "": false, "x": false} {
+		assert.Equal(trusted, cfg.Limits.IsTrustedProxy(ip), ip)
+	}
+
+	cfg = processReposConfigs(slog.Default(), cfg)
+	assert.Equal(&models.Limits{Rate: 2}, cfg.Repos["repo1"].Limits, "trusted proxies are not inherited")
+	assert.NotContains(cfg.Repos, "repo2", "trusted proxies are global only")
+
+	f["test_repos.yml"] = &fstest.MapFile{Data: []byte(`
+_limits:
+  trusted_proxies: [10.0.0.300]
+`)}
+	_, err = loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.Error(err, "invalid trusted proxy")
+}
+
+func TestLoadReposConfigUpstream(t *testing.T) {
+	assert := require.New(t)
+	f := fstest.MapFS{
+		"test_repos.yml": {Data: []byte(`
+repo1:
+  storage: /storage/repo1
+  input: /input/repo1
+  upstream:
+    url: https://swamp.example.com/
+    token: t0ken
+    interval: 1m
+repo2:
+  storage: /storage/repo2
+  input: /input/repo2
+  upstream:
+    url: http://swamp.example.com:8080
+    repo: other
+repo3:
+  storage: /storage/repo3
+  input: /input/repo3
+  upstream:
+    url: swamp.example.com
+`)},
+	}
+
+	cfg, err := loadReposConfig(slog.Default(), f, "test_repos.yml")
+	assert.NoError(err)
+
+	cfg = processReposConfigs(slog.Default(), cfg)
+	assert.Equal(&models.Upstream{URL: "https://swamp.example.com", Repo: "repo1", Token: "t0ken", Interval: types.Duration(time.Minute)}, cfg.Repos["repo1"].Upstream)
+	assert.Equal(&models.Upstream{URL: "http://swamp.example.com:8080", Repo: "other"}, cfg.Repos["repo2"].Upstream)
+	assert.NotContains(cfg.Repos, "repo3", "invalid upstream")
+	assert.NotContains(cfg.String(), "t0ken")
+}
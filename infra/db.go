@@ -11,10 +11,16 @@ import (
 )
 
 const (
-	DriverSqlite         = "sqlite"
-	SourceSqliteInMemory = "file::memory:?cache=shared&_pragma=foreign_keys(1)"
+	DriverSqlite              = "sqlite"
+	SourceSqliteInMemory      = "file::memory:?cache=shared&_pragma=foreign_keys(1)"
+	SourceSqliteStateInMemory = "file:state?mode=memory&cache=shared&_pragma=foreign_keys(1)"
 )
 
+// SourceSqliteFile returns source of sqlite database file
+func SourceSqliteFile(path string) string {
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
 func NewDatabase(log ports.Logger, driver, source string) (ports.DB, func(), error) {
 	sqlDB, err := sql.Open(driver, source)
 	if err != nil {
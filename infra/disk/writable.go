@@ -0,0 +1,21 @@
+package disk
+
+import (
+	"fmt"
+
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+)
+
+// Writable returns error, if the directory is not writable.
+// It does not create any files, as the directory might be watched.
+// The other than os filesystems are writable, unless read-only.
+func Writable(f ports.FS, dir string) error {
+	switch f.(type) {
+	case *afero.OsFs:
+		return writable(dir)
+	case *afero.ReadOnlyFs:
+		return fmt.Errorf("read-only file system")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package disk
+
+import (
+	"fmt"
+	"os"
+)
+
+// The writable checks the read-only attribute only,
+// as access(2) is not available
+func writable(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o200 == 0 {
+		return fmt.Errorf("read-only directory")
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package infra
 
 import (
+	"slices"
 	"sync"
 
 	"github.com/cloudcopper/swamp/ports"
@@ -8,14 +9,25 @@ import (
 )
 
 type EventBus struct {
-	bus *pubsub.PubSub[ports.Topic, ports.Event]
-	chs map[chan ports.Event]chan ports.Event
+	bus   *pubsub.PubSub[ports.Topic, ports.Event]
+	mutex sync.Mutex // protects chs and subs - subscribers might come from http handlers
+	chs   map[chan ports.Event]chan ports.Event
+	subs  map[chan ports.Event]*subscriber
+	seq   int
+}
+
+// The subscriber keeps subscription details for Depths
+type subscriber struct {
+	topic ports.Topic
+	seq   int
+	depth func() int
 }
 
 func NewEventBus() *EventBus {
 	bus := &EventBus{
-		bus: pubsub.New[ports.Topic, ports.Event](1),
-		chs: make(map[chan ports.Event]chan ports.Event),
+		bus:  pubsub.New[ports.Topic, ports.Event](1),
+		chs:  make(map[chan ports.Event]chan ports.Event),
+		subs: make(map[chan ports.Event]*subscriber),
 	}
 	return bus
 }
@@ -28,8 +40,11 @@ func (e *EventBus) Pub(topic ports.Topic, event ports.Event) {
 	e.bus.Pub(event, topic)
 }
 func (e *EventBus) Unsub(ch chan ports.Event) {
+	e.mutex.Lock()
 	inp := e.chs[ch]
 	delete(e.chs, ch)
+	delete(e.subs, ch)
+	e.mutex.Unlock()
 	e.bus.Unsub(inp)
 }
 
@@ -39,12 +54,22 @@ func (e *EventBus) Unsub(ch chan ports.Event) {
 func (e *EventBus) Sub(topic ports.Topic) chan ports.Event {
 	inp := e.bus.Sub(topic)
 	out := make(chan ports.Event, 1)
-	e.chs[out] = inp
 
 	mutex := &sync.Mutex{}
 	cond := sync.NewCond(mutex)
 	fifo := []ports.Event{}
 	abort := false
+	sending := 0 // event taken from fifo, but not yet passed to out
+
+	e.mutex.Lock()
+	e.chs[out] = inp
+	e.seq++
+	e.subs[out] = &subscriber{topic: topic, seq: e.seq, depth: func() int {
+		cond.L.Lock()
+		defer cond.L.Unlock()
+		return len(fifo) + sending + len(out)
+	}}
+	e.mutex.Unlock()
 
 	// Push events to fifo and signal
 	go func() {
@@ -69,9 +94,11 @@ func (e *EventBus) Sub(topic ports.Topic) chan ports.Event {
 			for len(fifo) > 0 {
 				e := fifo[0]
 				fifo = fifo[1:]
+				sending = 1
 				cond.L.Unlock()
 				out <- e
 				cond.L.Lock()
+				sending = 0
 			}
 			if abort {
 				return
@@ -82,3 +109,21 @@ func (e *EventBus) Sub(topic ports.Topic) chan ports.Event {
 
 	return out
 }
+
+// Depths returns the number of events queued (not yet received) by each subscriber of topics.
+// The subscribers of topic are ordered by subscription time.
+func (e *EventBus) Depths() map[ports.Topic][]int {
+	e.mutex.Lock()
+	subs := make([]*subscriber, 0, len(e.subs))
+	for _, sub := range e.subs {
+		subs = append(subs, sub)
+	}
+	e.mutex.Unlock()
+
+	slices.SortFunc(subs, func(a, b *subscriber) int { return a.seq - b.seq })
+	depths := map[ports.Topic][]int{}
+	for _, sub := range subs {
+		depths[sub.topic] = append(depths[sub.topic], sub.depth())
+	}
+	return depths
+}
@@ -1,10 +1,13 @@
 package infra
 
 import (
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/cloudcopper/swamp/ports"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEventBus(t *testing.T) {
@@ -23,3 +26,26 @@ func TestEventBus(t *testing.T) {
 	wg.Wait()
 	bus.Shutdown()
 }
+
+func TestEventBusDepths(t *testing.T) {
+	assert := require.New(t)
+	bus := NewEventBus()
+	defer bus.Shutdown()
+	ch1 := bus.Sub("topic1")
+	ch2 := bus.Sub("topic1")
+	ch3 := bus.Sub("topic2")
+
+	for x := 0; x < 3; x++ {
+		bus.Pub("topic1", ports.Event{"event"})
+	}
+	<-ch2
+	assert.Eventually(func() bool {
+		d := bus.Depths()
+		return slices.Equal(d["topic1"], []int{3, 2}) && slices.Equal(d["topic2"], []int{0})
+	}, time.Second, 10*time.Millisecond, "%v", bus.Depths())
+
+	bus.Unsub(ch1)
+	bus.Unsub(ch2)
+	bus.Unsub(ch3)
+	assert.Empty(bus.Depths())
+}
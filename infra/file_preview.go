@@ -0,0 +1,143 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+type PreviewKind = string
+
+const (
+	PreviewText     = PreviewKind("text")
+	PreviewJSON     = PreviewKind("json")
+	PreviewMarkdown = PreviewKind("markdown")
+	PreviewImage    = PreviewKind("image")
+	PreviewBinary   = PreviewKind("binary") // not previewable
+)
+
+var (
+	previewImageExts    = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".bmp", ".ico"}
+	previewMarkdownExts = []string{".md", ".markdown"}
+	previewJSONExts     = []string{".json"}
+	previewTextExts     = []string{".txt", ".log", ".csv", ".ini", ".conf", ".cfg", ".yml", ".yaml", ".xml", ".toml", ".sh", ".map", ".html", ".htm"}
+)
+
+// FilePreview is the content of file shown in browser
+type FilePreview struct {
+	Kind  PreviewKind
+	Lines []*PreviewLine // of text and json
+	HTML  template.HTML  // of markdown
+	Tail  bool           // only tail of text file is shown
+}
+
+type PreviewLine struct {
+	Number int
+	Text   string
+}
+
+// IsPreviewable returns true, if the file is likely previewable by name or mime type.
+// The file of other type is still previewed, if its content is text.
+func IsPreviewable(name string, mimeType string) bool {
+	return previewKindByName(name) != "" || strings.HasPrefix(mimeType, "text/") || strings.HasPrefix(mimeType, "image/")
+}
+
+func previewKindByName(name string) PreviewKind {
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case slices.Contains(previewImageExts, ext):
+		return PreviewImage
+	case slices.Contains(previewMarkdownExts, ext):
+		return PreviewMarkdown
+	case slices.Contains(previewJSONExts, ext):
+		return PreviewJSON
+	case slices.Contains(previewTextExts, ext):
+		return PreviewText
+	}
+	return ""
+}
+
+// FilePreviewer makes preview of artifact files.
+// The text files larger than max size are previewed by tail,
+// the markdown and json files larger than max size are previewed as text.
+type FilePreviewer struct {
+	maxSize int64
+}
+
+func NewFilePreviewer(maxSize types.Size) *FilePreviewer {
+	return &FilePreviewer{
+		maxSize: int64(maxSize),
+	}
+}
+
+// Preview returns preview of the file of given name and size.
+// The image files are not read, as shown by browser.
+func (p *FilePreviewer) Preview(file ports.File, name string, size types.Size, mimeType string) (*FilePreview, error) {
+	kind := previewKindByName(name)
+	if kind == PreviewImage || (kind == "" && strings.HasPrefix(mimeType, "image/")) {
+		return &FilePreview{Kind: PreviewImage}, nil
+	}
+
+	preview := &FilePreview{Kind: kind}
+	if int64(size) > p.maxSize {
+		if _, err := file.Seek(int64(size)-p.maxSize, io.SeekStart); err != nil {
+			return nil, err
+		}
+		preview.Kind, preview.Tail = PreviewText, true
+	}
+	data, err := io.ReadAll(io.LimitReader(file, p.maxSize))
+	if err != nil {
+		return nil, err
+	}
+	if preview.Tail {
+		// Show whole lines only
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	if !isText(data) {
+		return &FilePreview{Kind: PreviewBinary}, nil
+	}
+
+	switch preview.Kind {
+	case PreviewMarkdown:
+		preview.HTML = template.HTML(markdownHTML(string(data)))
+		return preview, nil
+	case PreviewJSON:
+		buf := &bytes.Buffer{}
+		if err := json.Indent(buf, data, "", "  "); err == nil {
+			data = buf.Bytes()
+		} else {
+			preview.Kind = PreviewText
+		}
+	default:
+		preview.Kind = PreviewText
+	}
+	preview.Lines = previewLines(data)
+	return preview, nil
+}
+
+// The isText returns true for valid utf-8 without nul characters
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+func previewLines(data []byte) []*PreviewLine {
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	lines := []*PreviewLine{}
+	if text == "" {
+		return lines
+	}
+	for i, line := range strings.Split(text, "\n") {
+		lines = append(lines, &PreviewLine{Number: i + 1, Text: line})
+	}
+	return lines
+}
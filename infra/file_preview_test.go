@@ -0,0 +1,71 @@
+package infra
+
+import (
+	"testing"
+
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePreviewer(t *testing.T) {
+	assert := require.New(t)
+	p := NewFilePreviewer(16)
+	preview := func(name string, data string) *FilePreview {
+		file := openTestFile(t, name, []byte(data))
+		preview, err := p.Preview(file, name, types.Size(len(data)), "")
+		assert.NoError(err)
+		return preview
+	}
+
+	v := preview("build.log", "one\r\ntwo\n")
+	assert.Equal(PreviewText, v.Kind)
+	assert.False(v.Tail)
+	assert.Equal([]*PreviewLine{{1, "one"}, {2, "two"}}, v.Lines)
+
+	v = preview("build.log", "line1\nline2\nline3\nline4\n")
+	assert.True(v.Tail)
+	assert.Equal([]*PreviewLine{{1, "line3"}, {2, "line4"}}, v.Lines)
+
+	v = preview("a.json", `{"a":[1]}`)
+	assert.Equal(PreviewJSON, v.Kind)
+	assert.Equal("{", v.Lines[0].Text)
+	assert.Equal(`  "a": [`, v.Lines[1].Text)
+	assert.Equal(PreviewText, preview("a.json", `{"a":`).Kind)
+
+	v = preview("README.md", "# Title\n<b>x</b>")
+	assert.Equal(PreviewMarkdown, v.Kind)
+	assert.Equal("<h1>Title</h1>\n<p>&lt;b&gt;x&lt;/b&gt;</p>\n", string(v.HTML))
+	assert.Equal(PreviewText, preview("README.md", "# Title\nlong long text\n").Kind)
+
+	assert.Equal(PreviewImage, preview("logo.SVG", "<svg></svg>").Kind)
+	assert.Equal(PreviewBinary, preview("app.elf", "\x7fELF\x00").Kind)
+	assert.Equal(PreviewText, preview("LICENSE", "MIT").Kind)
+
+	assert.True(IsPreviewable("notes", "text/plain; charset=utf-8"))
+	assert.True(IsPreviewable("a/b.yml", ""))
+	assert.False(IsPreviewable("app.elf", "application/x-executable"))
+}
+
+func TestMarkdownHTML(t *testing.T) {
+	assert := require.New(t)
+	for src, expected := range map[string]string{
+		"## Build ##":               "<h2>Build</h2>\n",
+		"a\nb\n\nc":                 "<p>a\nb</p>\n<p>c</p>\n",
+		"- a\n- **b**\n1. c":        "<ul>\n<li>a</li>\n<li><strong>b</strong></li>\n</ul>\n<ol>\n<li>c</li>\n</ol>\n",
+		"> *quote*":                 "<blockquote>\n<p><em>quote</em></p>\n</blockquote>\n",
+		"---":                       "<hr>\n",
+		"```sh\nmake <all>\n```":    "<pre><code class=\"language-sh\">make &lt;all&gt;</code></pre>\n",
+		"run `a*b*c` now":           "<p>run <code>a*b*c</code> now</p>\n",
+		"[docs](docs/a_b_.md)":      "<p><a href=\"docs/a_b_.md\" rel=\"nofollow\">docs</a></p>\n",
+		"[`x`](https://x.org/?a&b)": "<p><a href=\"https://x.org/?a&amp;b\" rel=\"nofollow\"><code>x</code></a></p>\n",
+		"![logo](logo.png)":         "<p><img src=\"logo.png\" alt=\"logo\"></p>\n",
+		"<https://x.org>":           "<p><a href=\"https://x.org\" rel=\"nofollow\">https://x.org</a></p>\n",
+		"[x](javascript:alert(1))":  "<p>x)</p>\n",
+		"[x](JavaScript:alert)":     "<p>x</p>\n",
+		"<script>alert(1)</script>": "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		"[a\"b](x\" onclick=\"y)":   "<p>[a&#34;b](x&#34; onclick=&#34;y)</p>\n",
+		"\x000\x00":                 "<p>0</p>\n",
+	} {
+		assert.Equal(expected, markdownHTML(src), src)
+	}
+}
@@ -24,10 +24,17 @@ func NewWebServer(log ports.Logger, addr string, handler http.Handler) (*WebServ
 		return nil, err
 	}
 
+	// The requests context is cancelled on shutdown,
+	// so long living requests (like event streams) are not blocking it
+	ctx, cancel := context.WithCancel(context.Background())
 	s := &WebServer{
 		log: log,
-		srv: &http.Server{Handler: handler},
+		srv: &http.Server{
+			Handler:     handler,
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		},
 	}
+	s.srv.RegisterOnShutdown(cancel)
 
 	s.closeWg.Add(1)
 	go func() {
@@ -0,0 +1,80 @@
+package infra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// InspectZip counts entries of zip archives
+type InspectZip struct {
+}
+
+func (*InspectZip) Inspect(f ports.FS, fileName string, info *ports.FileInspection) error {
+	file, err := f.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	z, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return ports.ErrWrongInspectFormat
+	}
+	info.Entries = len(z.File)
+	return nil
+}
+
+// InspectTar counts entries of tar and compressed by gzip tar archives
+type InspectTar struct {
+	gzip bool
+}
+
+func (i *InspectTar) Inspect(f ports.FS, fileName string, info *ports.FileInspection) error {
+	file, err := f.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if i.gzip {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return ports.ErrWrongInspectFormat
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	n, t := 0, tar.NewReader(r)
+	for {
+		_, err := t.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ports.ErrWrongInspectFormat
+		}
+		n++
+	}
+	info.Entries = n
+	return nil
+}
+
+func init() {
+	adapters.RegisterInspectAlgo(100300, "*.zip", &InspectZip{})
+	adapters.RegisterInspectAlgo(100301, "*.jar", &InspectZip{})
+	adapters.RegisterInspectAlgo(100310, "*.tar", &InspectTar{})
+	adapters.RegisterInspectAlgo(100320, "*.tar.gz", &InspectTar{gzip: true})
+	adapters.RegisterInspectAlgo(100321, "*.tgz", &InspectTar{gzip: true})
+}
@@ -0,0 +1,66 @@
+package infra
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// InspectElf detects architecture and GNU build-id of ELF files
+type InspectElf struct {
+}
+
+func (*InspectElf) Inspect(f ports.FS, fileName string, info *ports.FileInspection) error {
+	file, err := f.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	e, err := elf.NewFile(file)
+	var formatError *elf.FormatError
+	if errors.As(err, &formatError) {
+		return ports.ErrWrongInspectFormat
+	}
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	info.Arch = strings.ToLower(strings.TrimPrefix(e.Machine.String(), "EM_"))
+
+	section := e.Section(".note.gnu.build-id")
+	if section == nil {
+		return nil
+	}
+	data, err := section.Data()
+	if err != nil {
+		return err
+	}
+	info.BuildID = parseElfNote(e.ByteOrder, data)
+	return nil
+}
+
+// The parseElfNote returns hex encoded desc of note section
+// The note is namesz(4), descsz(4), type(4), name (4 aligned), desc
+func parseElfNote(order binary.ByteOrder, data []byte) string {
+	if len(data) < 12 {
+		return ""
+	}
+	namesz, descsz := order.Uint32(data[0:4]), order.Uint32(data[4:8])
+	start := 12 + (namesz+3)&^3
+	end := start + descsz
+	if end > uint32(len(data)) || start > end {
+		return ""
+	}
+	return hex.EncodeToString(data[start:end])
+}
+
+func init() {
+	adapters.RegisterInspectAlgo(100100, "*", &InspectElf{})
+}
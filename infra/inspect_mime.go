@@ -0,0 +1,30 @@
+package infra
+
+import (
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// InspectMime detects mime type by the file content sniffing
+type InspectMime struct {
+}
+
+func (*InspectMime) Inspect(f ports.FS, fileName string, info *ports.FileInspection) error {
+	file, err := f.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mime, err := mimetype.DetectReader(file)
+	if err != nil {
+		return err
+	}
+	info.MimeType = mime.String()
+	return nil
+}
+
+func init() {
+	adapters.RegisterInspectAlgo(100000, "*", &InspectMime{})
+}
@@ -0,0 +1,116 @@
+package infra
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// InspectPe detects architecture and CodeView (pdb) build-id of PE files
+type InspectPe struct {
+}
+
+var peMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "i386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "x86_64",
+	pe.IMAGE_FILE_MACHINE_ARM:   "arm",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "arm",
+	pe.IMAGE_FILE_MACHINE_ARM64: "aarch64",
+}
+
+func (*InspectPe) Inspect(f ports.FS, fileName string, info *ports.FileInspection) error {
+	file, err := f.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Check MZ signature first, as pe.NewFile has no distinct format error
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(file, magic); err != nil || string(magic) != "MZ" {
+		return ports.ErrWrongInspectFormat
+	}
+
+	p, err := pe.NewFile(file)
+	if err != nil {
+		return ports.ErrWrongInspectFormat
+	}
+	defer p.Close()
+
+	info.Arch = peMachines[p.Machine]
+	if info.Arch == "" {
+		info.Arch = fmt.Sprintf("pe-0x%x", p.Machine)
+	}
+	info.BuildID = peCodeViewID(p, file)
+	return nil
+}
+
+// The peCodeViewID returns pdb signature (GUID and age) as used by symbol servers,
+// or empty string if the file has no CodeView debug entry
+func peCodeViewID(p *pe.File, r io.ReaderAt) string {
+	var dir pe.DataDirectory
+	switch h := p.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if h.NumberOfRvaAndSizes <= pe.IMAGE_DIRECTORY_ENTRY_DEBUG {
+			return ""
+		}
+		dir = h.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_DEBUG]
+	case *pe.OptionalHeader64:
+		if h.NumberOfRvaAndSizes <= pe.IMAGE_DIRECTORY_ENTRY_DEBUG {
+			return ""
+		}
+		dir = h.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_DEBUG]
+	default:
+		return ""
+	}
+	if dir.VirtualAddress == 0 || dir.Size == 0 {
+		return ""
+	}
+
+	// Translate RVA of debug directory to file offset
+	offset := int64(-1)
+	for _, s := range p.Sections {
+		if dir.VirtualAddress >= s.VirtualAddress && dir.VirtualAddress < s.VirtualAddress+s.VirtualSize {
+			offset = int64(s.Offset) + int64(dir.VirtualAddress-s.VirtualAddress)
+			break
+		}
+	}
+	if offset < 0 {
+		return ""
+	}
+
+	// Each debug directory entry is 28 bytes
+	const entrySize, typeCodeView = 28, 2
+	for x := int64(0); x+entrySize <= int64(dir.Size); x += entrySize {
+		entry := make([]byte, entrySize)
+		if _, err := r.ReadAt(entry, offset+x); err != nil {
+			return ""
+		}
+		if binary.LittleEndian.Uint32(entry[12:16]) != typeCodeView {
+			continue
+		}
+		rawData := int64(binary.LittleEndian.Uint32(entry[24:28]))
+		cv := make([]byte, 24)
+		if _, err := r.ReadAt(cv, rawData); err != nil || string(cv[0:4]) != "RSDS" {
+			return ""
+		}
+		guid, age := cv[4:20], binary.LittleEndian.Uint32(cv[20:24])
+		id := fmt.Sprintf("%08x%04x%04x%x%x",
+			binary.LittleEndian.Uint32(guid[0:4]),
+			binary.LittleEndian.Uint16(guid[4:6]),
+			binary.LittleEndian.Uint16(guid[6:8]),
+			guid[8:16], age)
+		return strings.ToLower(id)
+	}
+
+	return ""
+}
+
+func init() {
+	adapters.RegisterInspectAlgo(100200, "*", &InspectPe{})
+}
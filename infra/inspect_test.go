@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectFile(t *testing.T) {
+	assert := require.New(t)
+	fs, log := afero.NewMemMapFs(), slog.Default()
+
+	// zip with three entries
+	buf := &bytes.Buffer{}
+	z := zip.NewWriter(buf)
+	for _, name := range []string{"a.txt", "b.txt", "dir/c.txt"} {
+		w, err := z.Create(name)
+		assert.NoError(err)
+		_, err = w.Write([]byte(name))
+		assert.NoError(err)
+	}
+	assert.NoError(z.Close())
+	assert.NoError(afero.WriteFile(fs, "/test/file.zip", buf.Bytes(), 0o644))
+
+	info := adapters.InspectFile(log, fs, "/test/file.zip")
+	assert.Equal("application/zip", info.MimeType)
+	assert.Equal(3, info.Entries)
+	assert.Empty(info.Arch)
+
+	// tar.gz with two entries
+	buf = &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		assert.NoError(tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(name)), Mode: 0o644}))
+		_, err := tw.Write([]byte(name))
+		assert.NoError(err)
+	}
+	assert.NoError(tw.Close())
+	assert.NoError(gz.Close())
+	assert.NoError(afero.WriteFile(fs, "/test/file.tar.gz", buf.Bytes(), 0o644))
+
+	info = adapters.InspectFile(log, fs, "/test/file.tar.gz")
+	assert.Equal("application/gzip", info.MimeType)
+	assert.Equal(2, info.Entries)
+
+	// plain text
+	assert.NoError(afero.WriteFile(fs, "/test/file.txt", []byte("hello\n"), 0o644))
+	info = adapters.InspectFile(log, fs, "/test/file.txt")
+	assert.Equal("text/plain; charset=utf-8", info.MimeType)
+	assert.Zero(info.Entries)
+	assert.Empty(info.Arch)
+	assert.Empty(info.BuildID)
+
+	// executable of the test itself
+	if runtime.GOOS != "linux" {
+		return
+	}
+	exe, err := os.Executable()
+	assert.NoError(err)
+	data, err := os.ReadFile(exe)
+	assert.NoError(err)
+	assert.NoError(afero.WriteFile(fs, "/test/exe", data, 0o755))
+	info = adapters.InspectFile(log, fs, "/test/exe")
+	assert.NotEmpty(info.Arch)
+}
+
+func TestParseElfNote(t *testing.T) {
+	assert := require.New(t)
+	// namesz=4, descsz=4, type=3, name="GNU\0", desc=deadbeef
+	note := []byte{4, 0, 0, 0, 4, 0, 0, 0, 3, 0, 0, 0, 'G', 'N', 'U', 0, 0xde, 0xad, 0xbe, 0xef}
+	assert.Equal("deadbeef", parseElfNote(binary.LittleEndian, note))
+	assert.Equal("", parseElfNote(binary.LittleEndian, note[:10]))
+	assert.Equal("", parseElfNote(binary.LittleEndian, note[:18]))
+}
@@ -0,0 +1,205 @@
+package infra
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"golang.org/x/time/rate"
+)
+
+// The limiterIdle is the time after which unused client limiters are forgotten
+const limiterIdle = 10 * time.Minute
+
+// The limiterChunk is the max size of single bandwidth limited write
+const limiterChunk = 32 * 1024
+
+// The limiterArchiveRetry is the delay suggested to clients,
+// when too many archives are generated
+const limiterArchiveRetry = 10 * time.Second
+
+// Limiter limits downloads of clients (request rate and bandwidth)
+// and the number of archives generated at once.
+// The client (user, token or ip) is limited by global limits across all repos
+// and additionally by the repo limits per repo.
+type Limiter struct {
+	global   *models.Limits
+	mutex    sync.Mutex
+	clients  map[limiterKey]*limiterClient
+	archives map[models.RepoID]int
+	total    int       // archives generated by all repos
+	pruned   time.Time // last time idle clients were removed
+}
+
+// The limiterKey identifies client limiter of repo.
+// The empty repoID is the global client limiter.
+type limiterKey struct {
+	repoID models.RepoID
+	client string
+}
+
+type limiterClient struct {
+	requests  *rate.Limiter
+	bandwidth *rate.Limiter
+	active    int // downloads in progress
+	seen      time.Time
+}
+
+// NewLimiter creates limiter.
+// The global limits cap every client across all repos
+// and the archives generated at once by all repos.
+func NewLimiter(global *models.Limits) *Limiter {
+	l := &Limiter{
+		global:   global,
+		clients:  map[limiterKey]*limiterClient{},
+		archives: map[models.RepoID]int{},
+		pruned:   time.Now(),
+	}
+	return l
+}
+
+// Download accounts download of the client from repo with given limits.
+// It returns writer limited by bandwidth and release function to be called once download complete.
+// It returns delay prior next allowed download instead, if client exceeds the rate.
+// The archive downloads also might be delayed, if too many archives are generated.
+func (l *Limiter) Download(ctx context.Context, repoID models.RepoID, limits *models.Limits, client string, w io.Writer, archive bool) (io.Writer, func(), time.Duration) {
+	if limits == nil {
+		limits = &models.Limits{}
+	}
+	global := l.global
+	if global == nil {
+		global = &models.Limits{}
+	}
+	now := time.Now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.prune(now)
+
+	clients := []*limiterClient{
+		l.client(limiterKey{client: client}, global, now),
+		l.client(limiterKey{repoID, client}, limits, now),
+	}
+
+	if archive {
+		if limits.Archives != 0 && l.archives[repoID] >= limits.Archives {
+			return nil, nil, limiterArchiveRetry
+		}
+		if global.Archives != 0 && l.total >= global.Archives {
+			return nil, nil, limiterArchiveRetry
+		}
+	}
+
+	// The download shall be allowed by global and repo rate
+	reservations := []*rate.Reservation{}
+	delay := time.Duration(0)
+	for _, c := range clients {
+		if c.requests == nil {
+			continue
+		}
+		r := c.requests.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		delay = max(delay, r.DelayFrom(now))
+	}
+	if delay > 0 {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+		return nil, nil, delay
+	}
+
+	if archive {
+		l.archives[repoID]++
+		l.total++
+	}
+
+	for _, c := range clients {
+		c.active++
+	}
+	release := sync.OnceFunc(func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		for _, c := range clients {
+			c.active--
+			c.seen = time.Now()
+		}
+		if archive {
+			l.archives[repoID]--
+			l.total--
+		}
+	})
+
+	for _, c := range clients {
+		if c.bandwidth != nil {
+			w = &limitedWriter{ctx: ctx, w: w, limiter: c.bandwidth}
+		}
+	}
+	return w, release, 0
+}
+
+// The client returns the client limiter by key, created by limits if not known yet
+func (l *Limiter) client(key limiterKey, limits *models.Limits, now time.Time) *limiterClient {
+	c, ok := l.clients[key]
+	if !ok {
+		c = newLimiterClient(limits)
+		l.clients[key] = c
+	}
+	c.seen = now
+	return c
+}
+
+func newLimiterClient(limits *models.Limits) *limiterClient {
+	c := &limiterClient{}
+	if limits.Rate > 0 {
+		burst := limits.Burst
+		if burst == 0 {
+			burst = int(math.Ceil(limits.Rate))
+		}
+		c.requests = rate.NewLimiter(rate.Limit(limits.Rate), burst)
+	}
+	if limits.Bandwidth > 0 {
+		c.bandwidth = rate.NewLimiter(rate.Limit(limits.Bandwidth), int(min(limits.Bandwidth, limiterChunk)))
+	}
+	return c
+}
+
+// The prune removes idle clients, what also resets their limits
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+	for key, c := range l.clients {
+		if c.active == 0 && now.Sub(c.seen) > limiterIdle {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// The limitedWriter writes in chunks not faster than limiter allows.
+// The limiter is shared by all downloads of the client.
+type limitedWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.limiter.Burst())
+		if err := w.limiter.WaitN(w.ctx, n); err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(p[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
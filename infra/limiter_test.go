@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterRate(t *testing.T) {
+	assert := require.New(t)
+	l := NewLimiter(nil)
+	limits := &models.Limits{Rate: 0.1, Burst: 2}
+	ctx := context.Background()
+
+	for x := 0; x < 2; x++ {
+		_, release, delay := l.Download(ctx, "repo", limits, "ip:1", &bytes.Buffer{}, false)
+		assert.Zero(delay)
+		release()
+	}
+	_, _, delay := l.Download(ctx, "repo", limits, "ip:1", &bytes.Buffer{}, false)
+	assert.Greater(delay, time.Duration(0))
+
+	// The other client and repo have own limits
+	_, _, delay = l.Download(ctx, "repo", limits, "ip:2", &bytes.Buffer{}, false)
+	assert.Zero(delay)
+	_, _, delay = l.Download(ctx, "other", limits, "ip:1", &bytes.Buffer{}, false)
+	assert.Zero(delay)
+	// No limits
+	for x := 0; x < 10; x++ {
+		_, _, delay = l.Download(ctx, "free", nil, "ip:1", &bytes.Buffer{}, false)
+		assert.Zero(delay)
+	}
+}
+
+func TestLimiterGlobal(t *testing.T) {
+	assert := require.New(t)
+	l := NewLimiter(&models.Limits{Rate: 0.1, Burst: 2})
+	ctx := context.Background()
+
+	// The global limits are shared by all repos of the client
+	for _, repoID := range []string{"repo1", "repo2"} {
+		_, release, delay := l.Download(ctx, repoID, nil, "ip:1", &bytes.Buffer{}, false)
+		assert.Zero(delay)
+		release()
+	}
+	_, _, delay := l.Download(ctx, "repo3", nil, "ip:1", &bytes.Buffer{}, false)
+	assert.Greater(delay, time.Duration(0))
+	_, _, delay = l.Download(ctx, "repo3", nil, "ip:2", &bytes.Buffer{}, false)
+	assert.Zero(delay)
+
+	// The repo limits apply on top of global limits
+	limits := &models.Limits{Rate: 0.1, Burst: 1}
+	_, _, delay = l.Download(ctx, "repo1", limits, "ip:3", &bytes.Buffer{}, false)
+	assert.Zero(delay)
+	_, _, delay = l.Download(ctx, "repo1", limits, "ip:3", &bytes.Buffer{}, false)
+	assert.Greater(delay, time.Duration(0))
+	// ...and rejected download does not consume global rate
+	_, _, delay = l.Download(ctx, "repo2", nil, "ip:3", &bytes.Buffer{}, false)
+	assert.Zero(delay)
+}
+
+func TestLimiterArchives(t *testing.T) {
+	assert := require.New(t)
+	l := NewLimiter(&models.Limits{Archives: 2})
+	ctx := context.Background()
+
+	_, release1, delay := l.Download(ctx, "repo1", &models.Limits{Archives: 1}, "ip:1", &bytes.Buffer{}, true)
+	assert.Zero(delay)
+	_, _, delay = l.Download(ctx, "repo1", &models.Limits{Archives: 1}, "ip:2", &bytes.Buffer{}, true)
+	assert.Equal(limiterArchiveRetry, delay, "repo cap")
+	_, _, delay = l.Download(ctx, "repo1", &models.Limits{Archives: 1}, "ip:2", &bytes.Buffer{}, false)
+	assert.Zero(delay, "single file is not archive")
+	_, release2, delay := l.Download(ctx, "repo2", nil, "ip:1", &bytes.Buffer{}, true)
+	assert.Zero(delay)
+	_, _, delay = l.Download(ctx, "repo3", nil, "ip:1", &bytes.Buffer{}, true)
+	assert.Equal(limiterArchiveRetry, delay, "global cap")
+
+	release1()
+	release1() // released once
+	_, _, delay = l.Download(ctx, "repo3", nil, "ip:1", &bytes.Buffer{}, true)
+	assert.Zero(delay)
+	release2()
+}
+
+func TestLimiterBandwidth(t *testing.T) {
+	assert := require.New(t)
+	l := NewLimiter(nil)
+	limits := &models.Limits{Bandwidth: 100 * 1024}
+	buf := &bytes.Buffer{}
+
+	w, release, delay := l.Download(context.Background(), "repo", limits, "ip:1", buf, false)
+	assert.Zero(delay)
+	defer release()
+	start := time.Now()
+	n, err := w.Write(make([]byte, 52*1024))
+	assert.NoError(err)
+	assert.Equal(52*1024, n)
+	assert.Equal(52*1024, buf.Len())
+	// The first burst (32KiB) is immediate, the rest 20KiB takes ~200ms
+	assert.Greater(time.Since(start), 150*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w, release, _ = l.Download(ctx, "repo", limits, "ip:1", buf, false)
+	defer release()
+	_, err = w.Write(make([]byte, 64*1024))
+	assert.Error(err, "canceled request")
+}
@@ -0,0 +1,170 @@
+package infra
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	markdownHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?$`)
+	markdownRule     = regexp.MustCompile(`^([-*_])(\s*[-*_]){2,}$`)
+	markdownListItem = regexp.MustCompile(`^([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	markdownCode     = regexp.MustCompile("`([^`]+)`")
+	markdownLink     = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)\)`)
+	markdownAutoLink = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	markdownStrong   = regexp.MustCompile(`(\*\*|__)([^*_]+)(\*\*|__)`)
+	markdownEm       = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	markdownKept     = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// The markdownHTML renders common subset of markdown (headings, paragraphs, lists, quotes, rules,
+// fenced code, code spans, emphasis, links and images) to html.
+// The html in source is escaped and links of unsafe schemes are dropped,
+// so the result is safe to show.
+func markdownHTML(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	b := &strings.Builder{}
+	paragraph := []string{}
+	list := ""
+	flush := func() {
+		if len(paragraph) != 0 {
+			b.WriteString("<p>" + markdownInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = paragraph[:0]
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			flush()
+			closeList()
+			continue
+		}
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			flush()
+			closeList()
+			fence, lang := line[:3], strings.TrimSpace(strings.Trim(line, "`~"))
+			code := []string{}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if lang != "" {
+				class = ` class="language-` + html.EscapeString(lang) + `"`
+			}
+			b.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+		if strings.HasPrefix(line, ">") {
+			flush()
+			closeList()
+			quote := []string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n" + markdownHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+			continue
+		}
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			closeList()
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + markdownInline(m[2]) + "</h" + level + ">\n")
+			continue
+		}
+		if markdownRule.MatchString(line) {
+			flush()
+			closeList()
+			b.WriteString("<hr>\n")
+			continue
+		}
+		if m := markdownListItem.FindStringSubmatch(line); m != nil {
+			flush()
+			tag := "ol"
+			if strings.ContainsAny(m[1], "-*+") {
+				tag = "ul"
+			}
+			if list != tag {
+				closeList()
+				list = tag
+				b.WriteString("<" + list + ">\n")
+			}
+			b.WriteString("<li>" + markdownInline(m[2]) + "</li>\n")
+			continue
+		}
+		closeList()
+		paragraph = append(paragraph, line)
+	}
+	flush()
+	closeList()
+	return b.String()
+}
+
+// The markdownInline renders inline markdown of the escaped text.
+// The rendered code spans and links are kept aside as placeholders,
+// so emphasis is not applied inside them.
+func markdownInline(s string) string {
+	kept := []string{}
+	keep := func(s string) string {
+		kept = append(kept, s)
+		return "\x00" + strconv.Itoa(len(kept)-1) + "\x00"
+	}
+
+	s = strings.ReplaceAll(s, "\x00", "")
+	s = markdownCode.ReplaceAllStringFunc(s, func(m string) string {
+		return keep("<code>" + html.EscapeString(strings.TrimSpace(m[1:len(m)-1])) + "</code>")
+	})
+	s = markdownAutoLink.ReplaceAllStringFunc(s, func(m string) string {
+		href := m[1 : len(m)-1]
+		return keep(`<a href="` + html.EscapeString(href) + `" rel="nofollow">` + html.EscapeString(href) + "</a>")
+	})
+	s = markdownLink.ReplaceAllStringFunc(s, func(m string) string {
+		a := markdownLink.FindStringSubmatch(m)
+		image, text, href := a[1] == "!", a[2], a[3]
+		if !isSafeMarkdownURL(href) {
+			return keep(markdownEmphasis(html.EscapeString(text)))
+		}
+		if image {
+			return keep(`<img src="` + html.EscapeString(href) + `" alt="` + html.EscapeString(text) + `">`)
+		}
+		return keep(`<a href="` + html.EscapeString(href) + `" rel="nofollow">` + markdownEmphasis(html.EscapeString(text)) + "</a>")
+	})
+	s = markdownEmphasis(html.EscapeString(s))
+
+	// The kept parts might refer earlier kept parts (e.g. code in link text)
+	for markdownKept.MatchString(s) {
+		s = markdownKept.ReplaceAllStringFunc(s, func(m string) string {
+			i, _ := strconv.Atoi(m[1 : len(m)-1])
+			return kept[i]
+		})
+	}
+	return s
+}
+
+func markdownEmphasis(s string) string {
+	s = markdownStrong.ReplaceAllString(s, "<strong>$2</strong>")
+	return markdownEm.ReplaceAllString(s, "<em>$1</em>")
+}
+
+// The isSafeMarkdownURL returns true for relative, http(s) and mailto urls
+func isSafeMarkdownURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
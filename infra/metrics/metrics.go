@@ -0,0 +1,180 @@
+// Package metrics defines the prometheus metrics of swamp.
+// The collectors are updated by services and http middleware,
+// and exposed at /metrics by registry returned from NewRegistry.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "swamp"
+
+// The ingestion results
+const (
+	IngestSuccess  = "success"
+	IngestFailure  = "failure"  // storage or database error
+	IngestRejected = "rejected" // meta schema violation
+	IngestInvalid  = "invalid"  // checksum verification failed
+)
+
+// The artifact removal reasons
+const (
+	RemovedExpired = "expired"
+	RemovedBroken  = "broken"
+)
+
+var (
+	IngestTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_total",
+		Help:      "Number of input artifacts processed by repo and result.",
+	}, []string{"repo", "result"})
+	IngestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ingest_duration_seconds",
+		Help:      "Time of input artifact processing by repo.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, []string{"repo"})
+	ChecksumFilesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checksum_files_total",
+		Help:      "Number of files checksum verified.",
+	})
+	ChecksumBytesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checksum_bytes_total",
+		Help:      "Number of bytes checksum verified.",
+	})
+	ChecksumDuration = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checksum_duration_seconds_total",
+		Help:      "Time spent on checksum verification.",
+	})
+	ArtifactsExpiredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "artifacts_expired_total",
+		Help:      "Number of artifacts marked expired by repo.",
+	}, []string{"repo"})
+	ArtifactsBrokenTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "artifacts_broken_total",
+		Help:      "Number of artifacts marked broken by repo.",
+	}, []string{"repo"})
+	ArtifactsRemovedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "artifacts_removed_total",
+		Help:      "Number of artifacts removed from storage by repo and reason.",
+	}, []string{"repo", "reason"})
+	HttpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by method, route and status code.",
+	}, []string{"method", "route", "code"})
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time of http requests by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	HttpResponseBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_response_bytes_total",
+		Help:      "Number of http response body bytes by route.",
+	}, []string{"route"})
+	DownloadBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_bytes_total",
+		Help:      "Number of downloaded bytes of files and archives by repo.",
+	}, []string{"repo"})
+)
+
+// Ingest accounts processed input artifact
+func Ingest(repoID models.RepoID, result string, d time.Duration) {
+	IngestTotal.WithLabelValues(repoID, result).Inc()
+	IngestDuration.WithLabelValues(repoID).Observe(d.Seconds())
+}
+
+// Checksum accounts checksum verified file
+func Checksum(size int64, d time.Duration) {
+	ChecksumFilesTotal.Inc()
+	ChecksumBytesTotal.Add(float64(size))
+	ChecksumDuration.Add(d.Seconds())
+}
+
+// EventBus is the event bus reporting queued events of subscribers
+type EventBus interface {
+	Depths() map[ports.Topic][]int
+}
+
+// NewRegistry returns registry of all swamp metrics, go runtime and process metrics.
+// The repos returns all repos for per repo artifacts count and size.
+func NewRegistry(repos func() ([]*models.Repo, error), bus EventBus) *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		IngestTotal,
+		IngestDuration,
+		ChecksumFilesTotal,
+		ChecksumBytesTotal,
+		ChecksumDuration,
+		ArtifactsExpiredTotal,
+		ArtifactsBrokenTotal,
+		ArtifactsRemovedTotal,
+		HttpRequestsTotal,
+		HttpRequestDuration,
+		HttpResponseBytesTotal,
+		DownloadBytesTotal,
+		&stateCollector{repos: repos, bus: bus},
+	)
+	return r
+}
+
+var (
+	repoArtifactsDesc = prometheus.NewDesc(namespace+"_repo_artifacts", "Number of artifacts in repo.", []string{"repo"}, nil)
+	repoSizeDesc      = prometheus.NewDesc(namespace+"_repo_size_bytes", "Size of artifacts in repo.", []string{"repo"}, nil)
+	eventsQueuedDesc  = prometheus.NewDesc(namespace+"_eventbus_queued_events", "Number of events queued per event bus subscriber.", []string{"topic", "subscriber"}, nil)
+	scrapeErrorDesc   = prometheus.NewDesc(namespace+"_scrape_error", "1 if repos could not be read at scrape time.", nil, nil)
+)
+
+// The stateCollector reports repos and event bus state at scrape time
+type stateCollector struct {
+	repos func() ([]*models.Repo, error)
+	bus   EventBus
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- repoArtifactsDesc
+	ch <- repoSizeDesc
+	ch <- eventsQueuedDesc
+	ch <- scrapeErrorDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	scrapeError := 0.0
+	if c.repos != nil {
+		repos, err := c.repos()
+		if err != nil {
+			scrapeError = 1
+		}
+		for _, repo := range repos {
+			ch <- prometheus.MustNewConstMetric(repoArtifactsDesc, prometheus.GaugeValue, float64(repo.ArtifactsCount), repo.RepoID)
+			ch <- prometheus.MustNewConstMetric(repoSizeDesc, prometheus.GaugeValue, float64(repo.Size), repo.RepoID)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, scrapeError)
+
+	if c.bus != nil {
+		for topic, depths := range c.bus.Depths() {
+			for x, depth := range depths {
+				ch <- prometheus.MustNewConstMetric(eventsQueuedDesc, prometheus.GaugeValue, float64(depth), topic, strconv.Itoa(x))
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testBus map[ports.Topic][]int
+
+func (b testBus) Depths() map[ports.Topic][]int {
+	return b
+}
+
+func TestRegistryState(t *testing.T) {
+	assert := require.New(t)
+	repos := []*models.Repo{
+		{RepoID: "repo1", Size: 1024, ArtifactsCount: 2},
+		{RepoID: "repo2"},
+	}
+	bus := testBus{"artifact-created": {3, 0}}
+	r := NewRegistry(func() ([]*models.Repo, error) { return repos, nil }, bus)
+
+	expected := `
+# HELP swamp_eventbus_queued_events Number of events queued per event bus subscriber.
+# TYPE swamp_eventbus_queued_events gauge
+swamp_eventbus_queued_events{subscriber="0",topic="artifact-created"} 3
+swamp_eventbus_queued_events{subscriber="1",topic="artifact-created"} 0
+# HELP swamp_repo_artifacts Number of artifacts in repo.
+# TYPE swamp_repo_artifacts gauge
+swamp_repo_artifacts{repo="repo1"} 2
+swamp_repo_artifacts{repo="repo2"} 0
+# HELP swamp_repo_size_bytes Size of artifacts in repo.
+# TYPE swamp_repo_size_bytes gauge
+swamp_repo_size_bytes{repo="repo1"} 1024
+swamp_repo_size_bytes{repo="repo2"} 0
+# HELP swamp_scrape_error 1 if repos could not be read at scrape time.
+# TYPE swamp_scrape_error gauge
+swamp_scrape_error 0
+`
+	err := testutil.GatherAndCompare(r, strings.NewReader(expected),
+		"swamp_eventbus_queued_events", "swamp_repo_artifacts", "swamp_repo_size_bytes", "swamp_scrape_error")
+	assert.NoError(err)
+
+	r = NewRegistry(func() ([]*models.Repo, error) { return nil, fmt.Errorf("no database") }, nil)
+	expected = `
+# HELP swamp_scrape_error 1 if repos could not be read at scrape time.
+# TYPE swamp_scrape_error gauge
+swamp_scrape_error 1
+`
+	err = testutil.GatherAndCompare(r, strings.NewReader(expected), "swamp_scrape_error")
+	assert.NoError(err)
+}
+
+func TestIngest(t *testing.T) {
+	assert := require.New(t)
+	Ingest("test-ingest", IngestSuccess, 0)
+	Ingest("test-ingest", IngestSuccess, 0)
+	Ingest("test-ingest", IngestInvalid, 0)
+	assert.Equal(2.0, testutil.ToFloat64(IngestTotal.WithLabelValues("test-ingest", IngestSuccess)))
+	assert.Equal(1.0, testutil.ToFloat64(IngestTotal.WithLabelValues("test-ingest", IngestInvalid)))
+}
@@ -22,6 +22,8 @@ func NewRender(f fs.FS, layout string) Render {
 				"hasField":  hasField,
 				"hasPrefix": hasPrefix,
 				"isHref":    isHref,
+				"isArchive": IsBrowsableArchive,
+				"isPreview": IsPreviewable,
 			},
 		},
 		IsDevelopment: func() bool {
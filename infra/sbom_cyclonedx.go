@@ -0,0 +1,77 @@
+package infra
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// SbomCycloneDX parses CycloneDX sbom files in json format
+type SbomCycloneDX struct {
+}
+
+type cycloneDXComponent struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Purl     string `json:"purl"`
+	Licenses []struct {
+		License struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	} `json:"licenses"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXDocument struct {
+	BomFormat  string               `json:"bomFormat"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+func (*SbomCycloneDX) ParseSbomFile(f ports.FS, sbomFileName string) ([]ports.SbomComponent, error) {
+	file, err := f.Open(sbomFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc := cycloneDXDocument{}
+	if err := json.NewDecoder(file).Decode(&doc); err != nil || doc.BomFormat != "CycloneDX" {
+		return nil, ports.ErrWrongSbomFormat
+	}
+
+	components := []ports.SbomComponent{}
+	var walk func(list []cycloneDXComponent)
+	walk = func(list []cycloneDXComponent) {
+		for _, c := range list {
+			licenses := []string{}
+			for _, l := range c.Licenses {
+				switch {
+				case l.Expression != "":
+					licenses = append(licenses, l.Expression)
+				case l.License.ID != "":
+					licenses = append(licenses, l.License.ID)
+				case l.License.Name != "":
+					licenses = append(licenses, l.License.Name)
+				}
+			}
+			components = append(components, ports.SbomComponent{
+				Name:    c.Name,
+				Version: c.Version,
+				Purl:    c.Purl,
+				License: strings.Join(licenses, " AND "),
+			})
+			// Nested components (sub-components) are listed flat
+			walk(c.Components)
+		}
+	}
+	walk(doc.Components)
+	return components, nil
+}
+
+func init() {
+	adapters.RegisterSbomAlgo(100100, "*.json", &SbomCycloneDX{})
+}
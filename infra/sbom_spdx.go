@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"encoding/json"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// SbomSpdx parses SPDX sbom files in json format
+type SbomSpdx struct {
+}
+
+type spdxDocument struct {
+	SpdxVersion string `json:"spdxVersion"`
+	Packages    []struct {
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		ExternalRefs     []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+func (*SbomSpdx) ParseSbomFile(f ports.FS, sbomFileName string) ([]ports.SbomComponent, error) {
+	file, err := f.Open(sbomFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc := spdxDocument{}
+	if err := json.NewDecoder(file).Decode(&doc); err != nil || doc.SpdxVersion == "" {
+		return nil, ports.ErrWrongSbomFormat
+	}
+
+	components := []ports.SbomComponent{}
+	for _, p := range doc.Packages {
+		c := ports.SbomComponent{
+			Name:    p.Name,
+			Version: p.VersionInfo,
+			License: spdxLicense(p.LicenseConcluded),
+		}
+		if c.License == "" {
+			c.License = spdxLicense(p.LicenseDeclared)
+		}
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				c.Purl = ref.ReferenceLocator
+				break
+			}
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+func spdxLicense(license string) string {
+	if license == "NOASSERTION" || license == "NONE" {
+		return ""
+	}
+	return license
+}
+
+func init() {
+	adapters.RegisterSbomAlgo(100000, "*.json", &SbomSpdx{})
+}
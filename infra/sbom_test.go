@@ -0,0 +1,57 @@
+package infra
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSbomFile(t *testing.T) {
+	assert := require.New(t)
+	fs, log := afero.NewMemMapFs(), slog.Default()
+
+	spdx := `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {"name": "openssl", "versionInfo": "3.0.7", "licenseConcluded": "Apache-2.0",
+     "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:generic/openssl@3.0.7"}]},
+    {"name": "zlib", "versionInfo": "1.3", "licenseConcluded": "NOASSERTION", "licenseDeclared": "Zlib"}
+  ]
+}`
+	assert.NoError(afero.WriteFile(fs, "/test/sbom.spdx.json", []byte(spdx), 0o644))
+	components, err := adapters.ParseSbomFile(log, fs, "/test/sbom.spdx.json")
+	assert.NoError(err)
+	assert.Equal([]ports.SbomComponent{
+		{Name: "openssl", Version: "3.0.7", Purl: "pkg:generic/openssl@3.0.7", License: "Apache-2.0"},
+		{Name: "zlib", Version: "1.3", License: "Zlib"},
+	}, components)
+
+	cdx := `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "components": [
+    {"name": "busybox", "version": "1.36.1", "purl": "pkg:generic/busybox@1.36.1",
+     "licenses": [{"license": {"id": "GPL-2.0-only"}}],
+     "components": [{"name": "libbb", "version": "1.36.1"}]}
+  ]
+}`
+	assert.NoError(afero.WriteFile(fs, "/test/bom.json", []byte(cdx), 0o644))
+	components, err = adapters.ParseSbomFile(log, fs, "/test/bom.json")
+	assert.NoError(err)
+	assert.Equal([]ports.SbomComponent{
+		{Name: "busybox", Version: "1.36.1", Purl: "pkg:generic/busybox@1.36.1", License: "GPL-2.0-only"},
+		{Name: "libbb", Version: "1.36.1"},
+	}, components)
+
+	// Not sbom json and not json at all
+	assert.NoError(afero.WriteFile(fs, "/test/other.json", []byte(`{"name": "value"}`), 0o644))
+	_, err = adapters.ParseSbomFile(log, fs, "/test/other.json")
+	assert.ErrorIs(err, ports.ErrWrongSbomFormat)
+	assert.NoError(afero.WriteFile(fs, "/test/file.txt", []byte("text"), 0o644))
+	_, err = adapters.ParseSbomFile(log, fs, "/test/file.txt")
+	assert.ErrorIs(err, ports.ErrWrongSbomFormat)
+}
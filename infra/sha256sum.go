@@ -8,9 +8,11 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/cloudcopper/swamp/adapters"
 	"github.com/cloudcopper/swamp/domain/errors"
+	"github.com/cloudcopper/swamp/infra/metrics"
 	"github.com/cloudcopper/swamp/lib"
 	"github.com/cloudcopper/swamp/ports"
 )
@@ -26,10 +28,13 @@ func (s *Sha256) Sum(f ports.FS, fileName string) ([]byte, error) {
 	}
 	defer file.Close()
 
+	start := time.Now()
 	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	n, err := io.Copy(hash, file)
+	if err != nil {
 		return nil, err
 	}
+	metrics.Checksum(n, time.Since(start))
 
 	// Use hex.EncodeToString to convert to string
 	return hash.Sum(nil), nil
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/cloudcopper/swamp/domain/errors"
 	"github.com/cloudcopper/swamp/lib"
@@ -13,6 +14,9 @@ import (
 	"github.com/spf13/afero"
 )
 
+// The watcherErrorWindow is the time watcher is degraded after watcher error
+const watcherErrorWindow = time.Minute
+
 type WatcherService struct {
 	id                  string
 	log                 ports.Logger
@@ -20,6 +24,11 @@ type WatcherService struct {
 	chTopicInputUpdated chan ports.Event
 	watcher             *fsnotify.Watcher
 	closeWg             sync.WaitGroup
+	mutex               sync.Mutex
+	failed              map[string]error // dirs unable to watch
+	lastErr             error            // last watcher error
+	lastErrAt           time.Time
+	closed              bool
 }
 
 func NewWatcherService(id string, log ports.Logger, bus ports.EventBus) (*WatcherService, error) {
@@ -35,6 +44,7 @@ func NewWatcherService(id string, log ports.Logger, bus ports.EventBus) (*Watche
 		bus:                 bus,
 		chTopicInputUpdated: bus.Sub(ports.TopicInputUpdated),
 		watcher:             watcher,
+		failed:              map[string]error{},
 	}
 	log.Info("created")
 
@@ -58,6 +68,9 @@ func (s *WatcherService) Close() {
 	}
 
 	s.log.Info("closing")
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
 	s.bus.Unsub(s.chTopicInputUpdated)
 	s.watcher.Close()
 	s.closeWg.Wait()
@@ -80,9 +93,33 @@ func (s *WatcherService) addDir(path string) error {
 	if err != nil {
 		log.Error("add dir failed!!!", slog.Any("err", err), slog.String("path", path))
 	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil {
+		s.failed[path] = err
+	} else {
+		delete(s.failed, path)
+	}
 	return err
 }
 
+// Health returns error, if watcher is closed, any dir is not watched
+// or watcher error occurred recently (e.g. events queue overflow)
+func (s *WatcherService) Health() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return fmt.Errorf("watcher closed")
+	}
+	for path, err := range s.failed {
+		return fmt.Errorf("unable to watch %v: %w", path, err)
+	}
+	if s.lastErr != nil && time.Since(s.lastErrAt) < watcherErrorWindow {
+		return s.lastErr
+	}
+	return nil
+}
+
 func (s *WatcherService) background() {
 	log, bus, fs := s.log, s.bus, afero.NewOsFs()
 	topicFileModified := fmt.Sprintf("%v-file-modified", s.id)
@@ -100,6 +137,9 @@ func (s *WatcherService) background() {
 		case err, ok := <-s.watcher.Errors:
 			if err != nil {
 				log.Error("watcher error", slog.Any("err", err))
+				s.mutex.Lock()
+				s.lastErr, s.lastErrAt = err, time.Now()
+				s.mutex.Unlock()
 			}
 			if !ok {
 				return
@@ -117,3 +117,26 @@ func appendFile(name, content string) error {
 	_, err = f.WriteString(content)
 	return err
 }
+
+func TestWatcherServiceHealth(t *testing.T) {
+	assert := testifyAssert.New(t)
+	dir, err := filepath.Abs("testdata/tmp/TestWatcherServiceHealth")
+	assert.NoError(err)
+	_ = os.RemoveAll(dir)
+
+	bus := NewEventBus()
+	defer bus.Shutdown()
+	s, err := NewWatcherService("TestWatcherServiceHealth", slog.Default(), bus)
+	assert.NoError(err)
+	assert.NoError(s.Health())
+
+	// Missing dir is not watched
+	assert.Error(s.addDir(dir))
+	assert.Error(s.Health())
+	assert.NoError(os.MkdirAll(dir, os.ModePerm))
+	assert.NoError(s.addDir(dir))
+	assert.NoError(s.Health())
+
+	s.Close()
+	assert.Error(s.Health())
+}
@@ -1,5 +1,20 @@
 package lib
 
+import (
+	"cmp"
+	"slices"
+)
+
 func First[T any](first T, rest ...interface{}) T {
 	return first
 }
+
+// SortedKeys returns sorted keys of map
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -1,9 +1,46 @@
 package types
 
-import "github.com/dustin/go-humanize"
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"gopkg.in/yaml.v3"
+)
 
 type Size int64
 
 func (s Size) String() string {
 	return humanize.Bytes(uint64(s))
 }
+
+func ParseSize(s string) (Size, error) {
+	v, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, err
+	}
+	return Size(v), nil
+}
+
+func (s *Size) UnmarshalYAML(value *yaml.Node) error {
+	var str string
+	if err := value.Decode(&str); err != nil {
+		return err
+	}
+
+	v, err := ParseSize(str)
+	if err != nil {
+		return fmt.Errorf("invalid size: %v", err)
+	}
+	*s = v
+	return nil
+}
+
+// Set parses size given as command line flag (see flag.Value)
+func (s *Size) Set(str string) error {
+	v, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
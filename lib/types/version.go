@@ -0,0 +1,158 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CompareVersions compares two version strings
+// and returns -1, 0 or +1 like cmp.Compare.
+// Versions are split to numeric and alphabetic segments,
+// so "3.0.10" > "3.0.8", "1.1.1k" > "1.1.1" and
+// the segments after '-' or '~' starting with letter are pre-releases,
+// so "1.0.0-rc1" < "1.0.0".
+func CompareVersions(a, b string) int {
+	ta, tb := versionTokens(a), versionTokens(b)
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		switch {
+		case i >= len(ta):
+			if tb[i].pre {
+				return 1
+			}
+			return -1
+		case i >= len(tb):
+			if ta[i].pre {
+				return -1
+			}
+			return 1
+		}
+		if c := ta[i].compare(tb[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+type versionToken struct {
+	value   string
+	numeric bool
+	pre     bool
+}
+
+func (t versionToken) compare(o versionToken) int {
+	switch {
+	case t.numeric && o.numeric:
+		a, b := strings.TrimLeft(t.value, "0"), strings.TrimLeft(o.value, "0")
+		if len(a) != len(b) {
+			return compareInt(len(a), len(b))
+		}
+		return strings.Compare(a, b)
+	case t.numeric:
+		return 1
+	case o.numeric:
+		return -1
+	}
+	return strings.Compare(t.value, o.value)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func versionTokens(version string) []versionToken {
+	version = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	tokens := []versionToken{}
+	sep := rune(0)
+	for i := 0; i < len(version); {
+		r := rune(version[i])
+		if !unicode.IsDigit(r) && !unicode.IsLetter(r) {
+			sep = r
+			i++
+			continue
+		}
+		numeric := unicode.IsDigit(r)
+		j := i
+		for j < len(version) && unicode.IsDigit(rune(version[j])) == numeric && (numeric || unicode.IsLetter(rune(version[j]))) {
+			j++
+		}
+		tokens = append(tokens, versionToken{
+			value:   version[i:j],
+			numeric: numeric,
+			pre:     !numeric && (sep == '-' || sep == '~'),
+		})
+		sep, i = 0, j
+	}
+	return tokens
+}
+
+// VersionConstraint is a list of conditions all must match the version.
+// It is parsed from string like ">=1.2, <2.0" or "<3.0.8".
+// The supported operators are =, !=, <, <=, >, >=.
+// The version without operator means =.
+type VersionConstraint []versionCondition
+
+type versionCondition struct {
+	op      string
+	version string
+}
+
+var versionOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+func ParseVersionConstraint(str string) (VersionConstraint, error) {
+	vc := VersionConstraint{}
+	for _, s := range strings.Split(str, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		op := "="
+		for _, o := range versionOperators {
+			if strings.HasPrefix(s, o) {
+				op, s = o, strings.TrimSpace(s[len(o):])
+				break
+			}
+		}
+		if op == "==" {
+			op = "="
+		}
+		if s == "" {
+			return nil, fmt.Errorf("version constraint %q: missing version", str)
+		}
+		vc = append(vc, versionCondition{op, s})
+	}
+	return vc, nil
+}
+
+// Match returns true if the version satisfies all conditions.
+// Empty constraint matches any version.
+func (vc VersionConstraint) Match(version string) bool {
+	for _, c := range vc {
+		r := CompareVersions(version, c.version)
+		ok := false
+		switch c.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
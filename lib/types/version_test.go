@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		val  int
+	}{
+		{"1.0", "1.0", 0},
+		{"v1.0", "1.0", 0},
+		{"1.0", "1.0.0", -1},
+		{"3.0.8", "3.0.10", -1},
+		{"3.0.10", "3.0.8", 1},
+		{"1.1.1k", "1.1.1", 1},
+		{"1.1.1k", "1.1.1l", -1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0-rc1", "1.0.0-rc2", -1},
+		{"1.0.0", "1.0.0-1", -1},
+		{"2.0", "10.0", -1},
+		{"007", "7", 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.a+" vs "+tC.b, func(t *testing.T) {
+			assert := require.New(t)
+			assert.Equal(tC.val, CompareVersions(tC.a, tC.b))
+			assert.Equal(-tC.val, CompareVersions(tC.b, tC.a))
+		})
+	}
+}
+
+func TestVersionConstraint(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		version    string
+		match      bool
+	}{
+		{"", "1.0", true},
+		{"<3.0.8", "3.0.7", true},
+		{"<3.0.8", "3.0.8", false},
+		{"<3.0.8", "1.1.1k", true},
+		{"<=3.0.8", "3.0.8", true},
+		{">=1.2, <2", "1.5", true},
+		{">=1.2, <2", "2.0", false},
+		{"1.2", "1.2", true},
+		{"=1.2", "1.3", false},
+		{"!=1.2", "1.3", true},
+		{"> 1.2", "1.2.1", true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.constraint+" "+tC.version, func(t *testing.T) {
+			assert := require.New(t)
+			vc, err := ParseVersionConstraint(tC.constraint)
+			assert.NoError(err)
+			assert.Equal(tC.match, vc.Match(tC.version))
+		})
+	}
+
+	_, err := ParseVersionConstraint(">=")
+	require.Error(t, err)
+}
@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/lib/types"
+)
+
+const ErrWrongArchiveFormat = lib.Error("wrong archive format")
+const ErrArchiveEntryNotFound = lib.Error("archive entry not found")
+const ErrArchiveEntryTooLarge = lib.Error("archive entry too large")
+
+// ArchiveEntry is the entry of archive file (e.g. zip, tar)
+type ArchiveEntry struct {
+	Name    string // slash separated name without leading slash and dot segments
+	Size    types.Size
+	Mode    fs.FileMode
+	ModTime time.Time
+}
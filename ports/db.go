@@ -7,5 +7,10 @@ type DB = *gorm.DB
 type WithRelationship bool
 type Limit int
 type LimitArtifacts int
+type WithBuildID string
+type WithFileName string // extension, glob or substring (see ArtifactRepository.FindAllFiles)
+type WithComponentName string
+type WithRepoID string
+type WithRepoIDs []string // any of repo ids
 
 var ErrRecordNotFound = gorm.ErrRecordNotFound
@@ -14,6 +14,14 @@ const (
 	TopicArtifactUpdated      Topic = "artifact-updated"
 	TopicInputUpdated         Topic = "input-updated"
 	TopicInputFileModified    Topic = "input-file-modified"
+	TopicInputFileRemoved     Topic = "input-file-removed"
 	TopicDanglingRepoArtifact Topic = "dangling-repo-artifact"
-	TopicBrokenRepoArtifact   Topic = "broken-repo-artifact"
+	// The replicated artifact is dangling artifact, which is new to the repo
+	TopicReplicatedRepoArtifact Topic = "replicated-repo-artifact"
+	TopicBrokenRepoArtifact     Topic = "broken-repo-artifact"
+	TopicRejectedRepoArtifact   Topic = "rejected-repo-artifact"
+	TopicArtifactRemoved        Topic = "artifact-removed"
+	TopicArtifactCreated        Topic = "artifact-created"
+	TopicArtifactExpired        Topic = "artifact-expired"
+	TopicArtifactBroken         Topic = "artifact-broken"
 )
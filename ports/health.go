@@ -0,0 +1,24 @@
+package ports
+
+// The health check statuses
+const (
+	HealthOk       = "ok"
+	HealthDegraded = "degraded"
+	HealthPending  = "pending" // not ready yet (e.g. initial storage scan)
+)
+
+// HealthCheck is the result of single subsystem check
+type HealthCheck struct {
+	Name   string `json:"name"`
+	RepoID string `json:"repo_id,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthChecker checks subsystems health (is the service working)
+// and readiness (is the service ready to serve).
+type HealthChecker interface {
+	Health() []HealthCheck
+	Ready() []HealthCheck
+}
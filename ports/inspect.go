@@ -0,0 +1,19 @@
+package ports
+
+import "github.com/cloudcopper/swamp/lib"
+
+const ErrWrongInspectFormat = lib.Error("wrong inspect format")
+
+// FileInspection holds details detected from file content
+type FileInspection struct {
+	MimeType string
+	Arch     string
+	BuildID  string
+	Entries  int
+}
+
+type InspectAlgo interface {
+	// Inspect updates info by details of the file content.
+	// Shall return ErrWrongInspectFormat, if the file format is not supported.
+	Inspect(fs FS, fileName string, info *FileInspection) error
+}
@@ -0,0 +1,18 @@
+package ports
+
+import "github.com/cloudcopper/swamp/lib"
+
+const ErrWrongSbomFormat = lib.Error("wrong sbom format")
+
+type SbomComponent struct {
+	Name    string
+	Version string
+	Purl    string
+	License string
+}
+
+type SbomAlgo interface {
+	// ParseSbomFile returns components listed in the sbom file.
+	// Shall return ErrWrongSbomFormat, if the file format is not supported.
+	ParseSbomFile(fs FS, sbomFileName string) ([]SbomComponent, error)
+}
@@ -0,0 +1,16 @@
+package ports
+
+// WebhookTopics are the bus topics delivered to webhooks.
+// The topic is also the webhook event name.
+var WebhookTopics = []Topic{
+	TopicArtifactCreated,
+	TopicArtifactExpired,
+	TopicArtifactBroken,
+	TopicArtifactRemoved,
+}
+
+const (
+	WebhookEventHeader     = "X-Swamp-Event"
+	WebhookDeliveryHeader  = "X-Swamp-Delivery"
+	WebhookSignatureHeader = "X-Swamp-Signature" // sha256=<hex hmac-sha256 of body>
+)
@@ -0,0 +1,362 @@
+package swamp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters"
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra/config"
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+)
+
+// replicationPerPage is the page size of upstream artifacts list
+const replicationPerPage = 100
+
+// replicationStaging is the hidden directory in repo storage, where artifacts are downloaded to.
+// It is skipped by storage scan, as artifact id can not start with dot.
+const replicationStaging = ".replication"
+
+// replicationRemoved is the directory in replication staging directory,
+// where the artifacts removed from repo are recorded, so they are not replicated again.
+const replicationRemoved = ".removed"
+
+// ReplicationService pulls artifacts of repos with upstream (see models.Upstream)
+// from other swamp instance by its /api/v1.
+// Each missing good upstream artifact is downloaded to staging directory of repo storage,
+// verified against its checksum file, moved to repo storage
+// and signaled as replicated artifact to be registered by artifact service.
+// The artifacts already expired by repo retention are not replicated.
+// The artifacts removed from repo are recorded and not replicated again,
+// till upstream stops listing them.
+type ReplicationService struct {
+	log          ports.Logger
+	bus          ports.EventBus
+	fs           ports.FS
+	repositories domain.Repositories
+	client       *http.Client
+	next         map[models.RepoID]time.Time // next check of repo upstream
+	chRemoved    chan ports.Event
+	ctx          context.Context
+	cancel       context.CancelFunc
+	closeWg      sync.WaitGroup
+}
+
+func NewReplicationService(log ports.Logger, bus ports.EventBus, fs ports.FS, repositories domain.Repositories) *ReplicationService {
+	log = log.With(slog.String("entity", "ReplicationService"))
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &ReplicationService{
+		log:          log,
+		bus:          bus,
+		fs:           fs,
+		repositories: repositories,
+		client:       &http.Client{Timeout: config.ReplicationTimeout},
+		next:         map[models.RepoID]time.Time{},
+		chRemoved:    bus.Sub(ports.TopicArtifactRemoved),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	s.closeWg.Add(1)
+	go func() {
+		defer s.closeWg.Done()
+		log.Info("process started")
+		defer log.Warn("process complete")
+		s.background()
+	}()
+
+	return s
+}
+
+func (s *ReplicationService) Close() {
+	s.log.Info("closing")
+	s.bus.Unsub(s.chRemoved)
+	s.cancel()
+	s.closeWg.Wait()
+}
+
+func (s *ReplicationService) background() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case event, ok := <-s.chRemoved:
+			if !ok {
+				return
+			}
+			s.recordRemoved(event[0], event[1])
+		case <-timer.C:
+			timer.Reset(s.replicateRepos(time.Now()))
+		}
+	}
+}
+
+// The replicateRepos checks upstream of every repo due to check.
+// It returns the delay till next check.
+func (s *ReplicationService) replicateRepos(now time.Time) time.Duration {
+	repos, err := s.repositories.Repo().FindAll()
+	if err != nil {
+		s.log.Error("unable to find repos", slog.Any("err", err))
+		return config.ReplicationInterval
+	}
+
+	delay := config.ReplicationInterval
+	for _, repo := range repos {
+		if repo.Upstream == nil {
+			continue
+		}
+		interval := time.Duration(repo.Upstream.Interval)
+		if interval == 0 {
+			interval = config.ReplicationInterval
+		}
+		if next, ok := s.next[repo.RepoID]; !ok || !next.After(now) {
+			s.replicateRepo(repo)
+			s.next[repo.RepoID] = now.Add(interval)
+		}
+		delay = min(delay, s.next[repo.RepoID].Sub(now))
+	}
+	return delay
+}
+
+// The replicateRepo downloads missing good artifacts of repo upstream
+func (s *ReplicationService) replicateRepo(repo *models.Repo) {
+	log := s.log.With(slog.Any("repoID", repo.RepoID), slog.String("upstream", repo.Upstream.URL), slog.Any("upstreamRepoID", repo.Upstream.Repo))
+	log.Debug("check upstream")
+
+	artifacts, err := s.upstreamArtifacts(repo.Upstream)
+	if err != nil {
+		log.Error("unable to list upstream artifacts", slog.Any("err", err))
+		return
+	}
+
+	removed := s.removedArtifacts(repo, artifacts)
+	now := time.Now().UTC().Unix()
+	for _, a := range artifacts {
+		if s.ctx.Err() != nil {
+			return
+		}
+		log := log.With(slog.Any("artifactID", a.ArtifactID))
+		if !lib.IsValidID(a.ArtifactID) {
+			log.Warn("skip - invalid artifact id")
+			continue
+		}
+		if repo.Retention != 0 && a.CreatedAt+int64(repo.Retention/1000000000) < now {
+			continue
+		}
+		if removed[a.ArtifactID] {
+			continue
+		}
+		if exist, _ := afero.Exists(s.fs, filepath.Join(repo.Storage, a.ArtifactID)); exist {
+			continue
+		}
+		if _, err := s.repositories.Artifact().FindByID(repo.RepoID, a.ArtifactID); err == nil {
+			continue
+		} else if !errors.Is(err, ports.ErrRecordNotFound) {
+			log.Error("unable to find artifact", slog.Any("err", err))
+			continue
+		}
+
+		if err := s.replicateArtifact(repo, a.ArtifactID); err != nil {
+			log.Error("unable to replicate artifact", slog.Any("err", err))
+			continue
+		}
+		log.Info("artifact replicated")
+		s.bus.Pub(ports.TopicReplicatedRepoArtifact, ports.Event{repo.RepoID, a.ArtifactID})
+	}
+}
+
+// The recordRemoved records artifact removed from repo with upstream,
+// so it is not replicated again
+func (s *ReplicationService) recordRemoved(repoID models.RepoID, artifactID models.ArtifactID) {
+	log := s.log.With(slog.Any("repoID", repoID), slog.Any("artifactID", artifactID))
+	repo, err := s.repositories.Repo().FindByID(repoID)
+	if err != nil {
+		log.Error("unable to find repo", slog.Any("err", err))
+		return
+	}
+	if repo.Upstream == nil || !lib.IsValidID(artifactID) {
+		return
+	}
+	dir := filepath.Join(repo.Storage, replicationStaging, replicationRemoved)
+	if err := s.fs.MkdirAll(dir, os.ModePerm); err != nil {
+		log.Error("unable to record removed artifact", slog.Any("err", err))
+		return
+	}
+	if err := lib.CreateFile(s.fs, filepath.Join(dir, artifactID), fmt.Sprintf("%v", time.Now().UTC().Unix())); err != nil && !errors.Is(err, os.ErrExist) {
+		log.Error("unable to record removed artifact", slog.Any("err", err))
+	}
+}
+
+// The removedArtifacts returns recorded removed artifacts of repo.
+// The records of artifacts not listed by upstream anymore are dropped.
+func (s *ReplicationService) removedArtifacts(repo *models.Repo, artifacts []*viewmodels.ApiArtifact) map[models.ArtifactID]bool {
+	dir := filepath.Join(repo.Storage, replicationStaging, replicationRemoved)
+	infos, err := afero.ReadDir(s.fs, dir)
+	if err != nil {
+		return nil
+	}
+	listed := map[models.ArtifactID]bool{}
+	for _, a := range artifacts {
+		listed[a.ArtifactID] = true
+	}
+	removed := map[models.ArtifactID]bool{}
+	for _, info := range infos {
+		artifactID := info.Name()
+		if listed[artifactID] {
+			removed[artifactID] = true
+			continue
+		}
+		if err := s.fs.Remove(filepath.Join(dir, artifactID)); err != nil {
+			s.log.Error("unable to drop removed artifact record", slog.Any("repoID", repo.RepoID), slog.Any("artifactID", artifactID), slog.Any("err", err))
+		}
+	}
+	return removed
+}
+
+// The upstreamArtifacts returns all good artifacts of upstream repo
+func (s *ReplicationService) upstreamArtifacts(upstream *models.Upstream) ([]*viewmodels.ApiArtifact, error) {
+	artifacts := []*viewmodels.ApiArtifact{}
+	for page := 1; ; page++ {
+		data := viewmodels.ApiPage[*viewmodels.ApiArtifact]{}
+		query := fmt.Sprintf("/artifacts?state=ok&per_page=%v&page=%v", replicationPerPage, page)
+		if err := s.getJSON(upstream, query, &data); err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, data.Items...)
+		if len(data.Items) == 0 || page*replicationPerPage >= data.Total {
+			return artifacts, nil
+		}
+	}
+}
+
+// The replicateArtifact downloads good files of upstream artifact to staging directory,
+// verifies them by checksum file and moves them to repo storage
+func (s *ReplicationService) replicateArtifact(repo *models.Repo, artifactID models.ArtifactID) error {
+	artifact := &viewmodels.ApiArtifact{}
+	if err := s.getJSON(repo.Upstream, "/artifacts/"+url.PathEscape(artifactID), artifact); err != nil {
+		return err
+	}
+
+	staging := filepath.Join(repo.Storage, replicationStaging, artifactID)
+	if err := s.fs.RemoveAll(staging); err != nil {
+		return err
+	}
+	defer s.fs.RemoveAll(staging)
+
+	checksumFile := ""
+	for _, file := range artifact.Files {
+		if file.State != "ok" {
+			return fmt.Errorf("upstream file %v is %v", file.Name, file.State)
+		}
+		if !filepath.IsLocal(file.Name) {
+			return fmt.Errorf("upstream file %v is not local", file.Name)
+		}
+		name := filepath.Join(staging, filepath.FromSlash(file.Name))
+		if err := s.download(repo.Upstream, artifactID, file.Name, name); err != nil {
+			return err
+		}
+		if adapters.IsChecksumFile(name) {
+			checksumFile = name
+		}
+	}
+	if checksumFile == "" {
+		return fmt.Errorf("upstream artifact has no checksum file")
+	}
+
+	checksum, files, err := adapters.CheckChecksum(s.log, s.fs, checksumFile)
+	if err != nil {
+		return err
+	}
+	if len(files.Bad) != 0 {
+		return fmt.Errorf("checksum mismatch of %v", files.Bad)
+	}
+	if string(checksum) != artifact.Checksum {
+		return fmt.Errorf("checksum %v does not match upstream checksum %v", checksum, artifact.Checksum)
+	}
+
+	// The _createdAt.txt is not listed, if it is not part of checksum file.
+	// Keep upstream creation time, so retention is counted same way.
+	file := filepath.Join(staging, "_createdAt.txt")
+	if lib.NoSuchFile(s.fs, file) {
+		if err := lib.CreateFile(s.fs, file, fmt.Sprintf("%v", artifact.CreatedAt)); err != nil {
+			return err
+		}
+	}
+
+	return s.fs.Rename(staging, filepath.Join(repo.Storage, artifactID))
+}
+
+// The download writes upstream artifact file to name
+func (s *ReplicationService) download(upstream *models.Upstream, artifactID models.ArtifactID, fileName, name string) error {
+	segments := strings.Split(fileName, "/")
+	for x, segment := range segments {
+		segments[x] = url.PathEscape(segment)
+	}
+	resp, err := s.get(upstream, "/artifacts/"+url.PathEscape(artifactID)+"/files/"+strings.Join(segments, "/"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := s.fs.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := s.fs.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (s *ReplicationService) getJSON(upstream *models.Upstream, path string, v any) error {
+	resp, err := s.get(upstream, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// The get requests path of upstream repo /api/v1.
+// The response with non 2xx status is an error.
+func (s *ReplicationService) get(upstream *models.Upstream, path string) (*http.Response, error) {
+	u := upstream.URL + "/api/v1/repos/" + url.PathEscape(upstream.Repo) + path
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if upstream.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+upstream.Token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %v: %v", u, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,190 @@
+package swamp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/adapters/repository"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// testUpstreamArtifact returns api artifact and its files content
+// with checksum file of the files
+func testUpstreamArtifact(artifactID string, createdAt time.Time, files map[string]string) (*viewmodels.ApiArtifact, map[string]string) {
+	sums := ""
+	for _, name := range lib.SortedKeys(files) {
+		sum := sha256.Sum256([]byte(files[name]))
+		sums += fmt.Sprintf("%v  %v\n", hex.EncodeToString(sum[:]), name)
+	}
+	sum := sha256.Sum256([]byte(sums))
+	checksum := hex.EncodeToString(sum[:])
+	content := map[string]string{checksum + ".sha256sum": sums}
+	for name, data := range files {
+		content[name] = data
+	}
+
+	a := &viewmodels.ApiArtifact{RepoID: "upstream", ArtifactID: artifactID, State: "ok", CreatedAt: createdAt.Unix(), Checksum: checksum}
+	for name := range content {
+		a.Files = append(a.Files, &viewmodels.ApiFile{Name: name, State: "ok"})
+	}
+	return a, content
+}
+
+// TestReplicationService:
+//   - Creates repo with upstream
+//   - Upstream has tampered, old, good artifacts and good artifact without _createdAt.txt
+//   - Only good artifacts are replicated and signaled as replicated artifacts
+//   - The _createdAt.txt keeps upstream creation time
+//   - The good artifacts are the last ones, so others are already processed once they signaled
+//   - The artifact removed from repo is recorded and not replicated again
+//   - The record of artifact not listed by upstream is dropped
+func TestReplicationService(t *testing.T) {
+	assert := require.New(t)
+	now := time.Now()
+
+	artifacts := []*viewmodels.ApiArtifact{}
+	contents := map[string]map[string]string{}
+	for _, it := range []struct {
+		artifactID    string
+		createdAt     time.Time
+		createdAtFile bool
+	}{
+		{"tampered", now, true},
+		{"old", now.Add(-2 * time.Hour), true},
+		{"good", now.Add(-time.Minute), true},
+		{"ingested", now.Add(-time.Second), false},
+	} {
+		files := map[string]string{"firmware.bin": "firmware"}
+		if it.createdAtFile {
+			files["_createdAt.txt"] = fmt.Sprint(it.createdAt.Unix())
+		}
+		a, content := testUpstreamArtifact(it.artifactID, it.createdAt, files)
+		artifacts = append(artifacts, a)
+		contents[it.artifactID] = content
+	}
+	contents["tampered"]["firmware.bin"] = "malware"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path, ok := strings.CutPrefix(r.URL.Path, "/api/v1/repos/upstream/artifacts")
+		switch {
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+		case path == "":
+			assert.Equal("ok", r.URL.Query().Get("state"))
+			json.NewEncoder(w).Encode(viewmodels.ApiPage[*viewmodels.ApiArtifact]{Page: 1, PerPage: replicationPerPage, Total: len(artifacts), Items: artifacts})
+		default:
+			artifactID, file, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/files/")
+			for _, a := range artifacts {
+				if a.ArtifactID != artifactID {
+					continue
+				}
+				if file == "" {
+					json.NewEncoder(w).Encode(a)
+					return
+				}
+				w.Write([]byte(contents[artifactID][file]))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{"/input/repo1", "/storage/repo1"} {
+		assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+	}
+	repos := []*models.Repo{
+		{
+			RepoID:    "repo1",
+			Name:      "Repo1",
+			Input:     "/input/repo1",
+			Storage:   "/storage/repo1",
+			Retention: types.Duration(time.Hour),
+			Upstream:  &models.Upstream{URL: server.URL, Repo: "upstream", Token: "t0ken"},
+		},
+	}
+
+	testFakeApp(t, fs, repos, func(app *testFakeAppInternals) {
+		log := slog.Default()
+		bus := infra.NewEventBus()
+		defer bus.Shutdown()
+		ch := bus.Sub(ports.TopicReplicatedRepoArtifact)
+		defer bus.Unsub(ch)
+
+		s := NewReplicationService(log, bus, fs, repository.NewRepositories(app.rr, app.ar))
+		defer s.Close()
+
+		for _, artifactID := range []string{"good", "ingested"} {
+			select {
+			case event := <-ch:
+				assert.Equal(ports.Event{"repo1", artifactID}, event)
+			case <-time.After(5 * time.Second):
+				assert.FailNow("no replicated artifact")
+			}
+		}
+
+		for _, artifactID := range []string{"good", "ingested"} {
+			for name, data := range contents[artifactID] {
+				content, err := afero.ReadFile(fs, "/storage/repo1/"+artifactID+"/"+name)
+				assert.NoError(err)
+				assert.Equal(data, string(content))
+			}
+		}
+		content, err := afero.ReadFile(fs, "/storage/repo1/ingested/_createdAt.txt")
+		assert.NoError(err)
+		assert.Equal(fmt.Sprint(now.Add(-time.Second).Unix()), string(content))
+		for _, name := range []string{"/storage/repo1/old", "/storage/repo1/tampered", "/storage/repo1/.replication/tampered"} {
+			exist, err := afero.Exists(fs, name)
+			assert.NoError(err)
+			assert.False(exist, name)
+		}
+
+		// Remove replicated artifact
+		assert.NoError(fs.RemoveAll("/storage/repo1/good"))
+		bus.Pub(ports.TopicArtifactRemoved, ports.Event{"repo1", "good"})
+		assert.Eventually(func() bool {
+			exist, _ := afero.Exists(fs, "/storage/repo1/.replication/.removed/good")
+			return exist
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NoError(lib.CreateFile(fs, "/storage/repo1/.replication/.removed/gone", "0"))
+
+		repo, err := app.rr.FindByID("repo1")
+		assert.NoError(err)
+		s.replicateRepo(repo)
+		select {
+		case event := <-ch:
+			assert.Fail("removed artifact replicated", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+		for name, expected := range map[string]bool{
+			"/storage/repo1/good":                       false,
+			"/storage/repo1/.replication/.removed/good": true,
+			"/storage/repo1/.replication/.removed/gone": false,
+		} {
+			exist, err := afero.Exists(fs, name)
+			assert.NoError(err)
+			assert.Equal(expected, exist, name)
+		}
+	})
+}
@@ -3,12 +3,14 @@ package swamp
 import (
 	"log/slog"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/cloudcopper/swamp/adapters"
 	"github.com/cloudcopper/swamp/domain"
 	"github.com/cloudcopper/swamp/domain/models"
 	"github.com/cloudcopper/swamp/infra/disk"
+	"github.com/cloudcopper/swamp/lib"
 	"github.com/cloudcopper/swamp/ports"
 	"github.com/spf13/afero"
 )
@@ -20,6 +22,8 @@ type RepoService struct {
 	repoRepository     domain.RepoRepository
 	chTopicRepoUpdated chan ports.Event
 	closeWg            sync.WaitGroup
+	mutex              sync.Mutex
+	scanned            map[models.RepoID]bool // repos storage scanned at least once
 }
 
 // NewRepoService create repo service:
@@ -32,6 +36,7 @@ func NewRepoService(log ports.Logger, bus ports.EventBus, walk disk.FilepathWalk
 		walk:               walk,
 		repoRepository:     repoRepository,
 		chTopicRepoUpdated: bus.Sub(ports.TopicRepoUpdated),
+		scanned:            map[models.RepoID]bool{},
 	}
 
 	s.closeWg.Add(1)
@@ -51,6 +56,13 @@ func (s *RepoService) Close() {
 	s.closeWg.Wait()
 }
 
+// Scanned returns true, once storage of the repo scanned for dangling artifacts
+func (s *RepoService) Scanned(repoID models.RepoID) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.scanned[repoID]
+}
+
 func (s *RepoService) background() {
 	for {
 		select {
@@ -72,6 +84,9 @@ func (s *RepoService) checkRepoById(repoID models.RepoID) {
 		return
 	}
 	s.checkRepoStorage(repo)
+	s.mutex.Lock()
+	s.scanned[repoID] = true
+	s.mutex.Unlock()
 }
 
 func (s *RepoService) checkRepoStorage(repo *models.Repo) {
@@ -94,6 +109,10 @@ func (s *RepoService) checkRepoStorage(repo *models.Repo) {
 			log.Error("walk error", slog.String("name", name), slog.Any("err", err))
 			return true, nil
 		}
+		// The hidden directories are not artifacts (e.g. replication staging)
+		if name != storage && strings.HasPrefix(filepath.Base(name), ".") && lib.First(afero.DirExists(fs, name)) {
+			return true, filepath.SkipDir
+		}
 		if !adapters.IsChecksumFile(name) {
 			return true, nil
 		}
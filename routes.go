@@ -0,0 +1,103 @@
+package swamp
+
+import (
+	"io/fs"
+
+	"github.com/cloudcopper/swamp/adapters/http"
+	"github.com/cloudcopper/swamp/adapters/http/controllers"
+	"github.com/cloudcopper/swamp/domain/vo"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+// Controllers holds all http controllers of the application
+type Controllers struct {
+	FrontPage *controllers.FrontPageController
+	Repo      *controllers.RepoController
+	Artifact  *controllers.ArtifactController
+	AboutPage *controllers.AboutPageController
+	Search    *controllers.SearchController
+	Api       *controllers.ApiController
+	Docs      *controllers.DocsController
+	Manage    *controllers.ManageController
+	Events    *controllers.EventsController
+	Webhooks  *controllers.WebhooksController
+	Auth      *controllers.AuthController
+	Tokens    *controllers.TokensController
+	Metrics   *controllers.MetricsController
+	Health    *controllers.HealthController
+	Compare   *controllers.CompareController
+	Feed      *controllers.FeedController
+	Badge     *controllers.BadgeController
+}
+
+// AddRoutes registers all application routes in the router.
+// Every route shall be described in the OpenAPI document (see controllers.OpenApiFileName),
+// what is verified by tests.
+// The routes with {repoID} are guarded by repo permissions,
+// while listings are filtered by controllers.
+func AddRoutes(router ports.Router, fs fs.FS, c *Controllers) {
+	router.Use(c.Auth.Authenticate)
+	read := router.With(c.Auth.RequireRepo(vo.PermissionRead))
+	write := router.With(c.Auth.RequireRepo(vo.PermissionWrite))
+	admin := router.With(c.Auth.RequireRepo(vo.PermissionAdmin))
+
+	router.Get("/", c.FrontPage.Index)
+	router.Get("/about", c.AboutPage.Index)
+	router.Get("/login", c.Auth.Login)
+	router.Get("/search", c.Search.Index)
+	read.Get("/repo/{repoID}/artifact/{artifactID}/file/*", c.Artifact.DownloadSingleFile)
+	// WARN The archive routes below match artifact ids without dots only
+	// Please see https://github.com/go-chi/chi/issues/758 and related
+	// The ids with dots (e.g. 1.2.3.tar.gz) are routed to c.Artifact.Get,
+	// which calls proper handler base on suffix
+	read.Get("/repo/{repoID}/artifact/{artifactID}.zip", c.Artifact.DownloadArchive)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar", c.Artifact.DownloadArchive)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.gz", c.Artifact.DownloadArchive)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.zst", c.Artifact.DownloadArchive)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.xz", c.Artifact.DownloadArchive)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.zip.sha256", c.Artifact.ArchiveChecksum)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.sha256", c.Artifact.ArchiveChecksum)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.gz.sha256", c.Artifact.ArchiveChecksum)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.zst.sha256", c.Artifact.ArchiveChecksum)
+	read.Get("/repo/{repoID}/artifact/{artifactID}.tar.xz.sha256", c.Artifact.ArchiveChecksum)
+	read.Get("/repo/{repoID}/artifact/{artifactID}/SHA256SUMS", c.Artifact.Manifest)
+	read.Get("/repo/{repoID}/artifact/{artifactID}/verify.sh", c.Artifact.VerifyScript)
+	read.Get("/repo/{repoID}/artifact/{artifactID}/compare", c.Compare.Page)
+	read.Get("/repo/{repoID}/artifact/{artifactID}", c.Artifact.Get)
+	read.Get("/repo/{repoID}/feed.atom", c.Feed.Repo)
+	read.Get("/repo/{repoID}/badge/{kind}.svg", c.Badge.Get)
+	read.Get("/repo/{repoID}/latest", c.Artifact.Latest)
+	read.Get("/repo/{repoID}/latest/file/*", c.Artifact.LatestFile)
+	read.Get("/repo/{repoID}", c.Repo.Get)
+	router.Get("/feed.atom", c.Feed.All)
+	router.Get("/events", c.Events.Stream)
+	router.With(c.Auth.RequireAdminIfEnabled).Get("/metrics", c.Metrics.Index)
+	router.Get("/healthz", c.Health.Health)
+	router.Get("/readyz", c.Health.Ready)
+	// JSON API
+	router.Get("/api/docs", c.Docs.Index)
+	router.Get("/api/v1/openapi.json", c.Docs.OpenApi)
+	router.Get("/api/v1/repos", c.Api.Repos)
+	read.Get("/api/v1/repos/{repoID}", c.Api.Repo)
+	read.Get("/api/v1/repos/{repoID}/artifacts", c.Api.Artifacts)
+	read.Get("/api/v1/repos/{repoID}/latest", c.Api.Latest)
+	read.Get("/api/v1/repos/{repoID}/artifacts/{artifactID}", c.Api.Artifact)
+	read.Get("/api/v1/repos/{repoID}/artifacts/{artifactID}/files/*", c.Api.DownloadFile)
+	read.Get("/api/v1/repos/{repoID}/artifacts/{artifactID}/manifest", c.Api.Manifest)
+	read.Get("/api/v1/repos/{repoID}/artifacts/{artifactID}/compare", c.Compare.Api)
+	// Management API
+	admin.Delete("/api/v1/repos/{repoID}/artifacts/{artifactID}", c.Manage.Delete)
+	write.Post("/api/v1/repos/{repoID}/artifacts/{artifactID}/expire", c.Manage.Expire)
+	write.Put("/api/v1/repos/{repoID}/artifacts/{artifactID}/retention", c.Manage.Retention)
+	router.HandleFunc("/api/v1/*", c.Api.NotFound)
+	// Admin pages
+	router.With(c.Auth.RequireAdmin).Get("/admin/webhooks", c.Webhooks.Index)
+	router.With(c.Auth.RequireAdmin).Get("/admin/tokens", c.Tokens.Index)
+	router.With(c.Auth.RequireAdmin).Post("/admin/tokens", c.Tokens.Create)
+	router.With(c.Auth.RequireAdmin).Post("/admin/tokens/{tokenID}/revoke", c.Tokens.Revoke)
+	// Static file handler
+	fileServer := http.FileServer(http.FS(fs))
+	router.Handle("/static/*", fileServer)
+	// 404 handler
+	router.NotFound(c.FrontPage.NotFound)
+}
@@ -0,0 +1,66 @@
+package swamp
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cloudcopper/swamp/adapters/http/controllers"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TestOpenApiRoutes verifies the OpenAPI document describes
+// exactly the routes registered by AddRoutes
+func TestOpenApiRoutes(t *testing.T) {
+	assert := require.New(t)
+
+	data, err := fs.ReadFile(appFS, controllers.OpenApiFileName)
+	assert.NoError(err)
+	doc := struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}{}
+	assert.NoError(yaml.Unmarshal(data, &doc))
+
+	// The document must be convertible to json
+	var raw interface{}
+	assert.NoError(yaml.Unmarshal(data, &raw))
+	_, err = json.Marshal(raw)
+	assert.NoError(err)
+
+	// The handlers are never called, so zero controllers are fine
+	router := chi.NewRouter()
+	AddRoutes(router, appFS, &Controllers{})
+
+	// Catch-all routes are not part of the document
+	skip := []string{"/static/*", "/api/v1/*"}
+	routes := map[string][]string{}
+	err = chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if slices.Contains(skip, route) {
+			return nil
+		}
+		path := strings.ReplaceAll(route, "/*", "/{path}")
+		routes[path] = append(routes[path], strings.ToLower(method))
+		return nil
+	})
+	assert.NoError(err)
+
+	for path, methods := range routes {
+		for _, method := range methods {
+			_, ok := doc.Paths[path][method]
+			assert.True(ok, "route %v %v is not documented", method, path)
+		}
+	}
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			if method == "parameters" {
+				continue
+			}
+			assert.True(slices.Contains(routes[path], method), "documented %v %v is not routed", method, path)
+		}
+	}
+}
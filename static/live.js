@@ -0,0 +1,42 @@
+// swampLive subscribes to the swamp event stream and refreshes
+// elements marked by data-live attribute (and id) without page reload.
+// The fresh content is taken from the same page fetched again,
+// so the server templates stay the only place rendering it.
+// The repo narrows the events to single repo (optional).
+function swampLive(repo) {
+    if (!window.EventSource) {
+        return;
+    }
+    var url = "/events?topic=artifact-updated&topic=artifact-removed&topic=repo-updated";
+    if (repo) {
+        url += "&repo=" + encodeURIComponent(repo);
+    }
+
+    var timer = null;
+    var refresh = function() {
+        timer = null;
+        fetch(window.location.href, {headers: {"Accept": "text/html"}})
+            .then(function(resp) { return resp.ok ? resp.text() : Promise.reject(resp.status); })
+            .then(function(html) {
+                var doc = new DOMParser().parseFromString(html, "text/html");
+                document.querySelectorAll("[data-live][id]").forEach(function(el) {
+                    var fresh = doc.getElementById(el.id);
+                    if (fresh) {
+                        el.innerHTML = fresh.innerHTML;
+                    }
+                });
+            })
+            .catch(function(err) { console.log("swamp live refresh failed", err); });
+    };
+    // Many events come at once (startup, expiration), so refresh once for them all
+    var schedule = function() {
+        if (timer === null) {
+            timer = setTimeout(refresh, 500);
+        }
+    };
+
+    var source = new EventSource(url);
+    ["artifact-updated", "artifact-removed", "repo-updated"].forEach(function(topic) {
+        source.addEventListener(topic, schedule);
+    });
+}
@@ -0,0 +1,153 @@
+package swamp
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/repository"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/errors"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra/config"
+	"github.com/cloudcopper/swamp/lib"
+	"github.com/cloudcopper/swamp/lib/types"
+	"github.com/cloudcopper/swamp/ports"
+)
+
+const tokenCommandUsage = `usage: token <command> [flags]
+
+Manage scoped api tokens in the state database (see -state flag).
+
+commands:
+  create -name <name> [-repo <repoID>] [-scope read|write|delete] [-expires <duration>]
+  list
+  revoke <tokenID>
+`
+
+// TokenCommand executes token subcommand (create, list or revoke) given by args.
+// The tokens are kept in the persistent state database given by config.StateDatabase,
+// so running server accepts created tokens immediately.
+func TokenCommand(log ports.Logger, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, tokenCommandUsage)
+		return lib.NewErrorCode(fmt.Errorf("no token command"), errors.RetTokenCommandError)
+	}
+	if config.StateDatabase == "" {
+		return lib.NewErrorCode(fmt.Errorf("no state database (see -state flag)"), errors.RetTokenCommandError)
+	}
+
+	stateDb, closeStateDb, err := openStateDatabase(log)
+	if err != nil {
+		return err
+	}
+	defer closeStateDb()
+	tokens, err := repository.NewApiTokenRepository(stateDb)
+	if err != nil {
+		log.Error("unable create api token repository", slog.Any("err", err))
+		return lib.NewErrorCode(err, errors.RetCreateApiTokenRepositoryError)
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "create":
+		err = tokenCreate(tokens, args, out)
+	case "list":
+		err = tokenList(tokens, out)
+	case "revoke":
+		err = tokenRevoke(tokens, args, out)
+	default:
+		fmt.Fprint(out, tokenCommandUsage)
+		err = fmt.Errorf("unknown token command %q", command)
+	}
+	if err != nil {
+		return lib.NewErrorCode(err, errors.RetTokenCommandError)
+	}
+	return nil
+}
+
+func tokenCreate(tokens domain.ApiTokenRepository, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("token create", flag.ContinueOnError)
+	flags.SetOutput(out)
+	name := flags.String("name", "", "token name (e.g. ci)")
+	repoID := flags.String("repo", "", "repo ID (empty for all repos)")
+	scopeName := flags.String("scope", "read", "token scope (read, write or delete)")
+	expires := flags.String("expires", "", "token lifetime (e.g. 90d, empty for never)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	scope, err := models.ParseApiTokenScope(*scopeName)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	expiresAt := int64(0)
+	if *expires != "" {
+		d, err := types.ParseDuration(*expires)
+		if err != nil {
+			return fmt.Errorf("invalid expires %q: %w", *expires, err)
+		}
+		if d != 0 {
+			expiresAt = now.Add(time.Duration(d)).Unix()
+		}
+	}
+	token, secret, err := models.NewApiToken(*name, *repoID, scope, now.Unix(), expiresAt)
+	if err != nil {
+		return err
+	}
+	if err := tokens.Create(token); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "token %v created (copy it now, as it is not shown again):\n%v\n", token.TokenID, secret)
+	return nil
+}
+
+func tokenList(tokens domain.ApiTokenRepository, out io.Writer) error {
+	all, err := tokens.FindAll()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Unix()
+	unix := func(t int64, zero string) string {
+		if t == 0 {
+			return zero
+		}
+		return time.Unix(t, 0).UTC().Format(time.RFC3339)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTOKEN\tREPO\tSCOPE\tCREATED\tEXPIRES\tLAST USED\tSTATE")
+	for _, token := range all {
+		state := "valid"
+		switch {
+		case token.RevokedAt != 0:
+			state = "revoked"
+		case !token.IsValid(now):
+			state = "expired"
+		}
+		repoID := token.RepoID
+		if repoID == "" {
+			repoID = "*"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v...\t%v\t%v\t%v\t%v\t%v\t%v\n", token.TokenID, token.Name, token.Hint, repoID, token.ScopeName(),
+			unix(token.CreatedAt, "-"), unix(token.ExpiresAt, "never"), unix(token.LastUsedAt, "never"), state)
+	}
+	return w.Flush()
+}
+
+func tokenRevoke(tokens domain.ApiTokenRepository, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("token revoke requires single token ID")
+	}
+	tokenID := args[0]
+	if err := tokens.Revoke(tokenID, time.Now().UTC().Unix()); err != nil {
+		return fmt.Errorf("token %v: %w", tokenID, err)
+	}
+	fmt.Fprintf(out, "token %v revoked\n", tokenID)
+	return nil
+}
@@ -0,0 +1,314 @@
+package swamp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/domain"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra/config"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/oklog/ulid/v2"
+)
+
+// webhookBatch is the max number of deliveries sent per one run
+const webhookBatch = 10
+
+// WebhookService listening eventbus for artifact events (see ports.WebhookTopics)
+// and delivers them to global and repo webhooks.
+// Each delivery is queued in delivery repository first,
+// so it is retried with exponential back-off until it succeed
+// or reach config.WebhookMaxAttempts (even after restart with persistent state database).
+type WebhookService struct {
+	log          ports.Logger
+	bus          ports.EventBus
+	repositories domain.Repositories
+	deliveries   domain.WebhookDeliveryRepository
+	webhooks     models.Webhooks
+	client       *http.Client
+	chTopics     []chan ports.Event
+	wake         chan struct{}
+	ctx          context.Context
+	cancel       context.CancelFunc
+	closeWg      sync.WaitGroup
+}
+
+// NewWebhookService creates webhook service.
+// The webhooks are the global webhooks of all repos.
+func NewWebhookService(log ports.Logger, bus ports.EventBus, repositories domain.Repositories, deliveries domain.WebhookDeliveryRepository, webhooks models.Webhooks) *WebhookService {
+	log = log.With(slog.String("entity", "WebhookService"))
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &WebhookService{
+		log:          log,
+		bus:          bus,
+		repositories: repositories,
+		deliveries:   deliveries,
+		webhooks:     webhooks,
+		client:       &http.Client{Timeout: config.WebhookTimeout},
+		wake:         make(chan struct{}, 1),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	for _, topic := range ports.WebhookTopics {
+		ch := bus.Sub(topic)
+		s.chTopics = append(s.chTopics, ch)
+		s.closeWg.Add(1)
+		go func() {
+			defer s.closeWg.Done()
+			for event := range ch {
+				s.enqueue(topic, event)
+			}
+		}()
+	}
+
+	s.closeWg.Add(1)
+	go func() {
+		defer s.closeWg.Done()
+		log.Info("process started")
+		defer log.Warn("process complete")
+		s.background()
+	}()
+
+	return s
+}
+
+func (s *WebhookService) Close() {
+	s.log.Info("closing")
+	for _, ch := range s.chTopics {
+		s.bus.Unsub(ch)
+	}
+	s.cancel()
+	s.closeWg.Wait()
+}
+
+func (s *WebhookService) background() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+			now := time.Now().UTC()
+			before := now.Add(-config.WebhookHistory).Unix()
+			if err := s.deliveries.DeleteAllCompleted(before); err != nil {
+				s.log.Error("unable to remove completed deliveries", slog.Any("err", err))
+			}
+			timer.Reset(config.WebhookInterval)
+		}
+		s.deliverPending()
+	}
+}
+
+// The enqueue creates delivery of the event for every interested webhook
+func (s *WebhookService) enqueue(topic ports.Topic, event ports.Event) {
+	if len(event) < 2 {
+		return
+	}
+	repoID, artifactID := event[0], event[1]
+	log := s.log.With(slog.Any("event", topic), slog.Any("repoID", repoID), slog.Any("artifactID", artifactID))
+
+	webhooks := s.findWebhooks(repoID)
+	if len(webhooks) == 0 {
+		return
+	}
+
+	// The removed artifact has no details anymore
+	var artifact *viewmodels.ApiArtifact
+	if topic != ports.TopicArtifactRemoved {
+		model, err := s.repositories.Artifact().FindByID(repoID, artifactID, ports.WithRelationship(true))
+		if err != nil {
+			log.Warn("unable to find artifact", slog.Any("err", err))
+		} else {
+			artifact = viewmodels.NewApiArtifact(viewmodels.NewArtifact(model))
+		}
+	}
+
+	now := time.Now().UTC().Unix()
+	urls := []string{}
+	for _, hook := range webhooks {
+		// Deliver event once per url, even if configured globally and in repo
+		if !hook.Wants(topic) || slices.Contains(urls, hook.URL) {
+			continue
+		}
+		urls = append(urls, hook.URL)
+
+		deliveryID := ulid.Make().String()
+		payload, err := json.Marshal(viewmodels.ApiWebhookPayload{
+			Event:      topic,
+			DeliveryID: deliveryID,
+			Timestamp:  now,
+			RepoID:     repoID,
+			ArtifactID: artifactID,
+			Artifact:   artifact,
+		})
+		if err != nil {
+			log.Error("unable to marshal webhook payload", slog.Any("err", err))
+			return
+		}
+		delivery := &models.WebhookDelivery{
+			DeliveryID: deliveryID,
+			URL:        hook.URL,
+			Event:      topic,
+			RepoID:     repoID,
+			ArtifactID: artifactID,
+			Payload:    string(payload),
+			State:      models.WebhookDeliveryPending,
+			CreatedAt:  now,
+			NextAt:     now,
+			UpdatedAt:  now,
+		}
+		if err := s.deliveries.Create(delivery); err != nil {
+			log.Error("unable to create webhook delivery", slog.Any("url", hook.URL), slog.Any("err", err))
+			continue
+		}
+		log.Debug("webhook delivery queued", slog.Any("deliveryID", deliveryID), slog.Any("url", hook.URL))
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// The findWebhooks returns the repo webhooks followed by global webhooks
+func (s *WebhookService) findWebhooks(repoID models.RepoID) models.Webhooks {
+	webhooks := models.Webhooks{}
+	repo, err := s.repositories.Repo().FindByID(repoID)
+	if err != nil {
+		s.log.Warn("unable to find repo", slog.Any("repoID", repoID), slog.Any("err", err))
+	} else {
+		webhooks = append(webhooks, repo.Webhooks...)
+	}
+	return append(webhooks, s.webhooks...)
+}
+
+// The deliverPending sends all due pending deliveries
+func (s *WebhookService) deliverPending() {
+	for s.ctx.Err() == nil {
+		now := time.Now().UTC().Unix()
+		deliveries, err := s.deliveries.FindAllPending(now, ports.Limit(webhookBatch))
+		if err != nil {
+			s.log.Error("unable to fetch pending deliveries", slog.Any("err", err))
+			return
+		}
+		for _, delivery := range deliveries {
+			s.deliver(delivery)
+		}
+		if len(deliveries) < webhookBatch {
+			return
+		}
+	}
+}
+
+// The deliver makes single delivery attempt and updates its state
+func (s *WebhookService) deliver(delivery *models.WebhookDelivery) {
+	log := s.log.With(slog.Any("deliveryID", delivery.DeliveryID), slog.Any("url", delivery.URL), slog.Any("event", delivery.Event))
+
+	// The secret is not stored with delivery,
+	// so it is looked up in the current configuration
+	var hook *models.Webhook
+	for _, h := range s.findWebhooks(delivery.RepoID) {
+		if h.URL == delivery.URL {
+			hook = h
+			break
+		}
+	}
+
+	status, err := 0, error(nil)
+	if hook == nil {
+		err = fmt.Errorf("webhook is no longer configured")
+		delivery.Attempts = config.WebhookMaxAttempts
+	} else {
+		status, err = s.post(hook, delivery)
+		if s.ctx.Err() != nil {
+			// Closing - keep delivery as is for next run
+			return
+		}
+		delivery.Attempts++
+	}
+
+	now := time.Now().UTC()
+	delivery.Status = status
+	delivery.Error = ""
+	delivery.UpdatedAt = now.Unix()
+	switch {
+	case err == nil:
+		log.Info("webhook delivered", slog.Any("status", status), slog.Any("attempts", delivery.Attempts))
+		delivery.State = models.WebhookDeliveryDelivered
+	case delivery.Attempts >= config.WebhookMaxAttempts:
+		log.Error("webhook delivery failed", slog.Any("status", status), slog.Any("attempts", delivery.Attempts), slog.Any("err", err))
+		delivery.State = models.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		backoff := webhookBackoff(delivery.Attempts)
+		log.Warn("webhook delivery will be retried", slog.Any("status", status), slog.Any("attempts", delivery.Attempts), slog.Any("backoff", backoff), slog.Any("err", err))
+		delivery.NextAt = now.Add(backoff).Unix()
+		delivery.Error = err.Error()
+	}
+	if err := s.deliveries.Update(delivery); err != nil {
+		log.Error("unable to update webhook delivery", slog.Any("err", err))
+	}
+}
+
+// The post sends delivery payload to the webhook.
+// The payload is signed by webhook secret, if any.
+// It returns http status and error for non 2xx status.
+func (s *WebhookService) post(hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "swamp-webhook")
+	req.Header.Set(ports.WebhookEventHeader, delivery.Event)
+	req.Header.Set(ports.WebhookDeliveryHeader, delivery.DeliveryID)
+	if hook.Secret != "" {
+		req.Header.Set(ports.WebhookSignatureHeader, webhookSignature(hook.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// The webhookSignature returns hex encoded hmac-sha256 of body as "sha256=<hex>"
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// The webhookBackoff returns delay prior next attempt.
+// It doubles config.WebhookBackoff for every failed attempt up to config.WebhookMaxBackoff.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := config.WebhookBackoff
+	for x := 1; x < attempts && backoff < config.WebhookMaxBackoff; x++ {
+		backoff *= 2
+	}
+	return min(backoff, config.WebhookMaxBackoff)
+}
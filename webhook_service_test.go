@@ -0,0 +1,137 @@
+package swamp
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudcopper/swamp/adapters/http/viewmodels"
+	"github.com/cloudcopper/swamp/adapters/repository"
+	"github.com/cloudcopper/swamp/domain/models"
+	"github.com/cloudcopper/swamp/infra"
+	"github.com/cloudcopper/swamp/infra/config"
+	"github.com/cloudcopper/swamp/ports"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	assert := require.New(t)
+	signature := webhookSignature("key", []byte("The quick brown fox jumps over the lazy dog"))
+	assert.Equal("sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", signature)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	defer func(backoff, maxBackoff time.Duration) {
+		config.WebhookBackoff, config.WebhookMaxBackoff = backoff, maxBackoff
+	}(config.WebhookBackoff, config.WebhookMaxBackoff)
+	config.WebhookBackoff, config.WebhookMaxBackoff = 30*time.Second, time.Hour
+
+	testCases := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, 1 * time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.backoff.String(), func(t *testing.T) {
+			assert := require.New(t)
+			assert.Equal(tC.backoff, webhookBackoff(tC.attempts))
+		})
+	}
+}
+
+// TestWebhookServiceDelivery:
+//   - Creates repo with webhook
+//   - Publish artifact removed event
+//   - Webhook fails first time and succeed on retry
+func TestWebhookServiceDelivery(t *testing.T) {
+	assert := require.New(t)
+	defer func(interval, backoff time.Duration) {
+		config.WebhookInterval, config.WebhookBackoff = interval, backoff
+	}(config.WebhookInterval, config.WebhookBackoff)
+	config.WebhookInterval, config.WebhookBackoff = 10*time.Millisecond, 0
+
+	mutex := sync.Mutex{}
+	requests := []*http.Request{}
+	bodies := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{"/input/repo1", "/storage/repo1"} {
+		assert.NoError(fs.MkdirAll(dir, os.ModePerm))
+	}
+	repos := []*models.Repo{
+		{
+			RepoID:   "repo1",
+			Name:     "Repo1",
+			Input:    "/input/repo1",
+			Storage:  "/storage/repo1",
+			Webhooks: models.Webhooks{{URL: server.URL, Secret: "s3cret", Events: []string{ports.TopicArtifactRemoved}}},
+		},
+	}
+
+	testFakeApp(t, fs, repos, func(app *testFakeAppInternals) {
+		log := slog.Default()
+		bus := infra.NewEventBus()
+		defer bus.Shutdown()
+		stateDb, closeStateDb, err := infra.NewDatabase(log, infra.DriverSqlite, infra.SourceSqliteStateInMemory)
+		assert.NoError(err)
+		defer closeStateDb()
+		assert.NoError(stateDb.AutoMigrate(new(models.WebhookDelivery)))
+		deliveries, err := repository.NewWebhookDeliveryRepository(stateDb)
+		assert.NoError(err)
+
+		s := NewWebhookService(log, bus, repository.NewRepositories(app.rr, app.ar), deliveries, nil)
+		defer s.Close()
+
+		bus.Pub(ports.TopicArtifactExpired, ports.Event{"repo1", "artifact1"}) // not subscribed
+		bus.Pub(ports.TopicArtifactRemoved, ports.Event{"repo1", "artifact2"})
+
+		assert.Eventually(func() bool {
+			all, err := deliveries.FindAll()
+			return err == nil && len(all) == 1 && all[0].State == models.WebhookDeliveryDelivered
+		}, 5*time.Second, 10*time.Millisecond)
+
+		all, err := deliveries.FindAll()
+		assert.NoError(err)
+		assert.Equal(2, all[0].Attempts)
+		assert.Equal(http.StatusNoContent, all[0].Status)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Len(requests, 2)
+		r, body := requests[1], bodies[1]
+		assert.Equal(ports.TopicArtifactRemoved, r.Header.Get(ports.WebhookEventHeader))
+		assert.Equal(all[0].DeliveryID, r.Header.Get(ports.WebhookDeliveryHeader))
+		assert.Equal(webhookSignature("s3cret", body), r.Header.Get(ports.WebhookSignatureHeader))
+		payload := viewmodels.ApiWebhookPayload{}
+		assert.NoError(json.Unmarshal(body, &payload))
+		assert.Equal("repo1", payload.RepoID)
+		assert.Equal("artifact2", payload.ArtifactID)
+		assert.Nil(payload.Artifact)
+	})
+}